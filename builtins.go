@@ -1,5 +1,7 @@
 package tengo
 
+import "fmt"
+
 var builtinFuncs = []*BuiltinFunction{
 	{
 		Name:  "len",
@@ -117,6 +119,38 @@ var builtinFuncs = []*BuiltinFunction{
 		Name:  "range",
 		Value: builtinRange,
 	},
+	{
+		Name:  "bigint",
+		Value: builtinBigInt,
+	},
+	{
+		Name:  "is_bigint",
+		Value: builtinIsBigInt,
+	},
+	{
+		Name:  "decimal",
+		Value: builtinDecimal,
+	},
+	{
+		Name:  "is_decimal",
+		Value: builtinIsDecimal,
+	},
+	{
+		Name:  "set",
+		Value: builtinSet,
+	},
+	{
+		Name:  "is_set",
+		Value: builtinIsSet,
+	},
+	{
+		Name:  "is_immutable_set",
+		Value: builtinIsImmutableSet,
+	},
+	{
+		Name:  "round",
+		Value: builtinRound,
+	},
 }
 
 // GetAllBuiltinFunctions returns all builtin function objects.
@@ -124,6 +158,17 @@ func GetAllBuiltinFunctions() []*BuiltinFunction {
 	return append([]*BuiltinFunction{}, builtinFuncs...)
 }
 
+// builtinIndex returns the index of the builtin function with the given name
+// or -1 if there is no such function.
+func builtinIndex(name string) int {
+	for idx, fn := range builtinFuncs {
+		if fn.Name == name {
+			return idx
+		}
+	}
+	return -1
+}
+
 func builtinTypeName(args ...Object) (Object, error) {
 	if len(args) != 1 {
 		return nil, ErrWrongNumArguments
@@ -151,6 +196,26 @@ func builtinIsInt(args ...Object) (Object, error) {
 	return FalseValue, nil
 }
 
+func builtinIsDecimal(args ...Object) (Object, error) {
+	if len(args) != 1 {
+		return nil, ErrWrongNumArguments
+	}
+	if _, ok := args[0].(*Decimal); ok {
+		return TrueValue, nil
+	}
+	return FalseValue, nil
+}
+
+func builtinIsBigInt(args ...Object) (Object, error) {
+	if len(args) != 1 {
+		return nil, ErrWrongNumArguments
+	}
+	if _, ok := args[0].(*BigInt); ok {
+		return TrueValue, nil
+	}
+	return FalseValue, nil
+}
+
 func builtinIsFloat(args ...Object) (Object, error) {
 	if len(args) != 1 {
 		return nil, ErrWrongNumArguments
@@ -211,6 +276,26 @@ func builtinIsImmutableArray(args ...Object) (Object, error) {
 	return FalseValue, nil
 }
 
+func builtinIsSet(args ...Object) (Object, error) {
+	if len(args) != 1 {
+		return nil, ErrWrongNumArguments
+	}
+	if _, ok := args[0].(*Set); ok {
+		return TrueValue, nil
+	}
+	return FalseValue, nil
+}
+
+func builtinIsImmutableSet(args ...Object) (Object, error) {
+	if len(args) != 1 {
+		return nil, ErrWrongNumArguments
+	}
+	if _, ok := args[0].(*ImmutableSet); ok {
+		return TrueValue, nil
+	}
+	return FalseValue, nil
+}
+
 func builtinIsMap(args ...Object) (Object, error) {
 	if len(args) != 1 {
 		return nil, ErrWrongNumArguments
@@ -300,10 +385,16 @@ func builtinLen(args ...Object) (Object, error) {
 		return &Int{Value: int64(len(arg.Value))}, nil
 	case *ImmutableMap:
 		return &Int{Value: int64(len(arg.Value))}, nil
+	case *Set:
+		return &Int{Value: int64(len(arg.Value))}, nil
+	case *ImmutableSet:
+		return &Int{Value: int64(len(arg.Value))}, nil
+	case *Proxy:
+		return &Int{Value: int64(arg.Len())}, nil
 	default:
 		return nil, ErrInvalidArgumentType{
 			Name:     "first",
-			Expected: "array/string/bytes/map",
+			Expected: "array/string/bytes/map/set",
 			Found:    arg.TypeName(),
 		}
 	}
@@ -444,6 +535,118 @@ func builtinInt(args ...Object) (Object, error) {
 	return UndefinedValue, nil
 }
 
+func builtinBigInt(args ...Object) (Object, error) {
+	argsLen := len(args)
+	if !(argsLen == 1 || argsLen == 2) {
+		return nil, ErrWrongNumArguments
+	}
+	if _, ok := args[0].(*BigInt); ok {
+		return args[0], nil
+	}
+	if s, ok := args[0].(*String); ok && len(s.Value) > MaxBigIntBits {
+		// every digit adds at least one bit
+		return nil, ErrBigIntLimit
+	}
+	v, ok := ToBigInt(args[0])
+	if ok {
+		if v.BitLen() > MaxBigIntBits {
+			return nil, ErrBigIntLimit
+		}
+		return &BigInt{Value: v}, nil
+	}
+	if argsLen == 2 {
+		return args[1], nil
+	}
+	return UndefinedValue, nil
+}
+
+func builtinDecimal(args ...Object) (Object, error) {
+	argsLen := len(args)
+	if !(argsLen == 1 || argsLen == 2) {
+		return nil, ErrWrongNumArguments
+	}
+	if _, ok := args[0].(*Decimal); ok {
+		return args[0], nil
+	}
+	if s, ok := args[0].(*String); ok {
+		v, err := ParseDecimal(s.Value)
+		if err == nil {
+			return v, nil
+		}
+		if err == ErrDecimalLimit {
+			return nil, err
+		}
+	} else if v, ok := ToDecimal(args[0]); ok {
+		return checkDecimal(v)
+	}
+	if argsLen == 2 {
+		return args[1], nil
+	}
+	return UndefinedValue, nil
+}
+
+// builtinRound rounds a number to a decimal with the given number of digits
+// after the decimal point.
+// usage: round(x[, places[, mode]])
+func builtinRound(args ...Object) (Object, error) {
+	argsLen := len(args)
+	if argsLen < 1 || argsLen > 3 {
+		return nil, ErrWrongNumArguments
+	}
+	v, ok := decimalOperand(args[0])
+	if !ok {
+		return nil, ErrInvalidArgumentType{
+			Name:     "first",
+			Expected: "decimal/int/bigint/float",
+			Found:    args[0].TypeName(),
+		}
+	}
+	var places int64
+	if argsLen > 1 {
+		arg1, ok := args[1].(*Int)
+		if !ok {
+			return nil, ErrInvalidArgumentType{
+				Name:     "second",
+				Expected: "int",
+				Found:    args[1].TypeName(),
+			}
+		}
+		places = arg1.Value
+		if places > int64(MaxDecimalDigits) {
+			return nil, ErrDecimalLimit
+		}
+	}
+	mode := RoundHalfEven
+	if argsLen > 2 {
+		arg2, ok := args[2].(*String)
+		if !ok {
+			return nil, ErrInvalidArgumentType{
+				Name:     "third",
+				Expected: "string",
+				Found:    args[2].TypeName(),
+			}
+		}
+		mode, ok = roundingModes[arg2.Value]
+		if !ok {
+			return nil, fmt.Errorf("unknown rounding mode: %s", arg2.Value)
+		}
+	}
+	if places < 0 {
+		places = 0
+	}
+	return v.Round(int32(places), mode), nil
+}
+
+// builtinSet returns a set of the arguments.
+// usage: s := set(1, 2, 3) or s := set(arr...)
+func builtinSet(args ...Object) (Object, error) {
+	s, err := NewSet(args...)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
 func builtinFloat(args ...Object) (Object, error) {
 	argsLen := len(args)
 	if !(argsLen == 1 || argsLen == 2) {
@@ -561,10 +764,13 @@ func builtinDelete(args ...Object) (Object, error) {
 			Expected: "string",
 			Found:    args[1].TypeName(),
 		}
+	case *Set:
+		arg.Remove(args[1])
+		return UndefinedValue, nil
 	default:
 		return nil, ErrInvalidArgumentType{
 			Name:     "first",
-			Expected: "map",
+			Expected: "map/set",
 			Found:    arg.TypeName(),
 		}
 	}
@@ -34,7 +34,7 @@ func Test_builtinDelete(t *testing.T) {
 			&tengo.String{}}}, wantErr: true,
 			wantedErr: tengo.ErrInvalidArgumentType{
 				Name:     "first",
-				Expected: "map",
+				Expected: "map/set",
 				Found:    "string"},
 		},
 		{name: "no-args",
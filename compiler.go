@@ -19,11 +19,14 @@ type compilationScope struct {
 	Instructions []byte
 	SymbolInit   map[string]bool
 	SourceMap    map[int]parser.Pos
+	Generator    bool
 }
 
 // loop represents a loop construct that the compiler uses to track the current
 // loop.
 type loop struct {
+	Label     string
+	Scope     int
 	Continues []int
 	Breaks    []int
 }
@@ -53,9 +56,12 @@ type Compiler struct {
 	scopeIndex      int
 	modules         ModuleGetter
 	compiledModules map[string]*CompiledFunction
+	moduleCache     *moduleCache // shared by the compilers of an Engine
+	importer        *Compiler    // compiler that imports a shared module
 	allowFileImport bool
 	loops           []*loop
 	loopIndex       int
+	optionalJumps   [][]int // jump positions of the open optional chains
 	trace           io.Writer
 	indent          int
 }
@@ -137,7 +143,8 @@ func (c *Compiler) Compile(node parser.Node) error {
 			return err
 		}
 	case *parser.BinaryExpr:
-		if node.Token == token.LAnd || node.Token == token.LOr {
+		if node.Token == token.LAnd || node.Token == token.LOr ||
+			node.Token == token.Coalesce {
 			return c.compileLogical(node)
 		}
 
@@ -159,6 +166,8 @@ func (c *Compiler) Compile(node parser.Node) error {
 			c.emit(node, parser.OpBinaryOp, int(token.Quo))
 		case token.Rem:
 			c.emit(node, parser.OpBinaryOp, int(token.Rem))
+		case token.Pow:
+			c.emit(node, parser.OpBinaryOp, int(token.Pow))
 		case token.Greater:
 			c.emit(node, parser.OpBinaryOp, int(token.Greater))
 		case token.GreaterEq:
@@ -171,6 +180,11 @@ func (c *Compiler) Compile(node parser.Node) error {
 			c.emit(node, parser.OpEqual)
 		case token.NotEqual:
 			c.emit(node, parser.OpNotEqual)
+		case token.In:
+			c.emit(node, parser.OpContains)
+		case token.NotIn:
+			c.emit(node, parser.OpContains)
+			c.emit(node, parser.OpLNot)
 		case token.And:
 			c.emit(node, parser.OpBinaryOp, int(token.And))
 		case token.Or:
@@ -205,6 +219,26 @@ func (c *Compiler) Compile(node parser.Node) error {
 		}
 		c.emit(node, parser.OpConstant,
 			c.addConstant(&String{Value: node.Value}))
+	case *parser.InterpStringLit:
+		// the literal is compiled into the concatenation of its string parts
+		// and the string() conversions of the embedded expressions.
+		if err := c.Compile(node.Strings[0]); err != nil {
+			return err
+		}
+		for i, expr := range node.Exprs {
+			c.emit(expr, parser.OpGetBuiltin, builtinIndex("string"))
+			if err := c.Compile(expr); err != nil {
+				return err
+			}
+			c.emit(expr, parser.OpCall, 1, 0)
+			c.emit(expr, parser.OpBinaryOp, int(token.Add))
+			if str := node.Strings[i+1]; str.Value != "" {
+				if err := c.Compile(str); err != nil {
+					return err
+				}
+				c.emit(str, parser.OpBinaryOp, int(token.Add))
+			}
+		}
 	case *parser.CharLit:
 		c.emit(node, parser.OpConstant,
 			c.addConstant(&Char{Value: node.Value}))
@@ -269,19 +303,41 @@ func (c *Compiler) Compile(node parser.Node) error {
 			c.changeOperand(jumpPos1, curPos)
 		}
 	case *parser.ForStmt:
-		return c.compileForStmt(node)
+		return c.compileForStmt(node, "")
 	case *parser.ForInStmt:
-		return c.compileForInStmt(node)
+		return c.compileForInStmt(node, "")
+	case *parser.LabeledStmt:
+		label := node.Label.Name
+		if c.findLoop(label) != nil {
+			return c.errorf(node, "label '%s' already defined", label)
+		}
+		switch stmt := node.Stmt.(type) {
+		case *parser.ForStmt:
+			return c.compileForStmt(stmt, label)
+		case *parser.ForInStmt:
+			return c.compileForInStmt(stmt, label)
+		}
+		return c.errorf(node, "label '%s' not followed by a loop", label)
 	case *parser.BranchStmt:
+		var label string
+		if node.Label != nil {
+			label = node.Label.Name
+		}
 		if node.Token == token.Break {
-			curLoop := c.currentLoop()
+			curLoop := c.findLoop(label)
+			if curLoop == nil && label != "" {
+				return c.errorf(node, "unknown label '%s'", label)
+			}
 			if curLoop == nil {
 				return c.errorf(node, "break not allowed outside loop")
 			}
 			pos := c.emit(node, parser.OpJump, 0)
 			curLoop.Breaks = append(curLoop.Breaks, pos)
 		} else if node.Token == token.Continue {
-			curLoop := c.currentLoop()
+			curLoop := c.findLoop(label)
+			if curLoop == nil && label != "" {
+				return c.errorf(node, "unknown label '%s'", label)
+			}
 			if curLoop == nil {
 				return c.errorf(node, "continue not allowed outside loop")
 			}
@@ -311,11 +367,20 @@ func (c *Compiler) Compile(node parser.Node) error {
 		if err != nil {
 			return err
 		}
+	case *parser.ConstStmt:
+		if err := c.compileConst(node); err != nil {
+			return err
+		}
 	case *parser.Ident:
 		symbol, _, ok := c.symbolTable.Resolve(node.Name, false)
 		if !ok {
 			return c.errorf(node, "unresolved reference '%s'", node.Name)
 		}
+		if symbol.Value != nil {
+			// folded constant
+			c.emitValue(node, symbol.Value)
+			break
+		}
 
 		switch symbol.Scope {
 		case ScopeGlobal:
@@ -328,14 +393,53 @@ func (c *Compiler) Compile(node parser.Node) error {
 			c.emit(node, parser.OpGetFree, symbol.Index)
 		}
 	case *parser.ArrayLit:
+		// with spread elements, the runs of other elements are built into
+		// arrays and all the parts are concatenated in order.
+		var numParts, numElements int
 		for _, elem := range node.Elements {
-			if err := c.Compile(elem); err != nil {
+			spread, ok := elem.(*parser.SpreadExpr)
+			if !ok {
+				if err := c.Compile(elem); err != nil {
+					return err
+				}
+				numElements++
+				continue
+			}
+			if numElements > 0 {
+				c.emit(node, parser.OpArray, numElements)
+				numParts, numElements = numParts+1, 0
+			}
+			if err := c.Compile(spread.Expr); err != nil {
 				return err
 			}
+			numParts++
+		}
+		if numParts == 0 {
+			c.emit(node, parser.OpArray, numElements)
+			break
 		}
-		c.emit(node, parser.OpArray, len(node.Elements))
+		if numElements > 0 {
+			c.emit(node, parser.OpArray, numElements)
+			numParts++
+		}
+		c.emit(node, parser.OpArrayConcat, numParts)
 	case *parser.MapLit:
+		// with spread elements, the runs of other elements are built into
+		// maps and all the parts are merged in order.
+		var numParts, numElements int
 		for _, elt := range node.Elements {
+			if elt.Ellipsis.IsValid() {
+				if numElements > 0 {
+					c.emit(node, parser.OpMap, numElements*2)
+					numParts, numElements = numParts+1, 0
+				}
+				if err := c.Compile(elt.Value); err != nil {
+					return err
+				}
+				numParts++
+				continue
+			}
+
 			// key
 			if len(elt.Key) > MaxStringLen {
 				return c.error(node, ErrStringLimit)
@@ -347,29 +451,65 @@ func (c *Compiler) Compile(node parser.Node) error {
 			if err := c.Compile(elt.Value); err != nil {
 				return err
 			}
+			numElements++
 		}
-		c.emit(node, parser.OpMap, len(node.Elements)*2)
+		if numParts == 0 {
+			c.emit(node, parser.OpMap, numElements*2)
+			break
+		}
+		if numElements > 0 {
+			c.emit(node, parser.OpMap, numElements*2)
+			numParts++
+		}
+		c.emit(node, parser.OpMapMerge, numParts)
 
+	case *parser.OptionalChainExpr:
+		c.optionalJumps = append(c.optionalJumps, nil)
+		if err := c.Compile(node.Expr); err != nil {
+			return err
+		}
+		n := len(c.optionalJumps) - 1
+		for _, pos := range c.optionalJumps[n] {
+			c.changeOperand(pos, len(c.currentInstructions()))
+		}
+		c.optionalJumps = c.optionalJumps[:n]
 	case *parser.SelectorExpr: // selector on RHS side
 		if err := c.Compile(node.Expr); err != nil {
 			return err
 		}
+		if node.Optional {
+			c.emitOptionalJump(node, parser.OpOptionalJump)
+		}
 		if err := c.Compile(node.Sel); err != nil {
 			return err
 		}
-		c.emit(node, parser.OpIndex)
+		if node.Optional {
+			c.emitOptionalJump(node, parser.OpOptionalIndex)
+		} else {
+			c.emit(node, parser.OpIndex)
+		}
 	case *parser.IndexExpr:
 		if err := c.Compile(node.Expr); err != nil {
 			return err
 		}
+		if node.Optional {
+			c.emitOptionalJump(node, parser.OpOptionalJump)
+		}
 		if err := c.Compile(node.Index); err != nil {
 			return err
 		}
-		c.emit(node, parser.OpIndex)
+		if node.Optional {
+			c.emitOptionalJump(node, parser.OpOptionalIndex)
+		} else {
+			c.emit(node, parser.OpIndex)
+		}
 	case *parser.SliceExpr:
 		if err := c.Compile(node.Expr); err != nil {
 			return err
 		}
+		if node.Optional {
+			c.emitOptionalJump(node, parser.OpOptionalJump)
+		}
 		if node.Low != nil {
 			if err := c.Compile(node.Low); err != nil {
 				return err
@@ -384,7 +524,14 @@ func (c *Compiler) Compile(node parser.Node) error {
 		} else {
 			c.emit(node, parser.OpNull)
 		}
-		c.emit(node, parser.OpSliceIndex)
+		if node.Step != nil {
+			if err := c.Compile(node.Step); err != nil {
+				return err
+			}
+			c.emit(node, parser.OpSliceStep)
+		} else {
+			c.emit(node, parser.OpSliceIndex)
+		}
 	case *parser.FuncLit:
 		c.enterScope()
 
@@ -395,6 +542,28 @@ func (c *Compiler) Compile(node parser.Node) error {
 			s.LocalAssigned = true
 		}
 
+		// default values are evaluated at call time, only for the
+		// parameters that were not passed by the caller.
+		for i := range node.Type.Params.List {
+			def := node.Type.Params.Default(i)
+			if def == nil {
+				continue
+			}
+			pos := c.emit(def, parser.OpDefaultJump, i, 0)
+			if err := c.Compile(def); err != nil {
+				return err
+			}
+			c.emit(def, parser.OpDefineLocal, i)
+			c.changeOperand(pos, i, len(c.currentInstructions()))
+		}
+
+		// a function containing yield is a generator: once the arguments
+		// are bound, the call returns the suspended frame to the caller.
+		if hasYield(node.Body) {
+			c.scopes[c.scopeIndex].Generator = true
+			c.emit(node, parser.OpGenerator)
+		}
+
 		if err := c.Compile(node.Body); err != nil {
 			return err
 		}
@@ -459,10 +628,16 @@ func (c *Compiler) Compile(node parser.Node) error {
 			}
 		}
 
+		paramNames := make([]string, len(node.Type.Params.List))
+		for i, p := range node.Type.Params.List {
+			paramNames[i] = p.Name
+		}
 		compiledFunction := &CompiledFunction{
 			Instructions:  instructions,
 			NumLocals:     numLocals,
 			NumParameters: len(node.Type.Params.List),
+			NumDefaults:   node.Type.Params.NumDefaults(),
+			ParamNames:    paramNames,
 			VarArgs:       node.Type.Params.VarArgs,
 			SourceMap:     sourceMap,
 		}
@@ -472,6 +647,45 @@ func (c *Compiler) Compile(node parser.Node) error {
 		} else {
 			c.emit(node, parser.OpConstant, c.addConstant(compiledFunction))
 		}
+	case *parser.DeferStmt:
+		if c.symbolTable.Parent(true) == nil {
+			// outside the function
+			return c.errorf(node, "defer not allowed outside function")
+		}
+		if len(node.Call.KeywordArgs) > 0 {
+			return c.errorf(node,
+				"keyword arguments not allowed in defer")
+		}
+
+		// the function and its arguments are evaluated now, the call
+		// itself is made when the function returns.
+		if err := c.Compile(node.Call.Func); err != nil {
+			return err
+		}
+		for _, arg := range node.Call.Args {
+			if err := c.Compile(arg); err != nil {
+				return err
+			}
+		}
+		ellipsis := 0
+		if node.Call.Ellipsis.IsValid() {
+			ellipsis = 1
+		}
+		c.emit(node, parser.OpDefer, len(node.Call.Args), ellipsis)
+	case *parser.YieldStmt:
+		if c.symbolTable.Parent(true) == nil {
+			// outside the function
+			return c.errorf(node, "yield not allowed outside function")
+		}
+		if !c.scopes[c.scopeIndex].Generator {
+			// hasYield did not find this yield statement
+			return c.errorf(node, "yield not allowed here")
+		}
+
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(node, parser.OpYield)
 	case *parser.ReturnStmt:
 		if c.symbolTable.Parent(true) == nil {
 			// outside the function
@@ -480,6 +694,8 @@ func (c *Compiler) Compile(node parser.Node) error {
 
 		if node.Result == nil {
 			c.emit(node, parser.OpReturn, 0)
+		} else if c.scopes[c.scopeIndex].Generator {
+			return c.errorf(node, "return with value not allowed in generator")
 		} else {
 			if err := c.Compile(node.Result); err != nil {
 				return err
@@ -490,11 +706,32 @@ func (c *Compiler) Compile(node parser.Node) error {
 		if err := c.Compile(node.Func); err != nil {
 			return err
 		}
+		if node.Optional {
+			c.emitOptionalJump(node, parser.OpOptionalJump)
+		}
 		for _, arg := range node.Args {
 			if err := c.Compile(arg); err != nil {
 				return err
 			}
 		}
+		if len(node.KeywordArgs) > 0 {
+			names := make(map[string]bool, len(node.KeywordArgs))
+			for _, kwarg := range node.KeywordArgs {
+				if names[kwarg.Name.Name] {
+					return c.errorf(kwarg,
+						"duplicate keyword argument '%s'", kwarg.Name.Name)
+				}
+				names[kwarg.Name.Name] = true
+				c.emit(kwarg, parser.OpConstant,
+					c.addConstant(&String{Value: kwarg.Name.Name}))
+				if err := c.Compile(kwarg.Value); err != nil {
+					return err
+				}
+			}
+			c.emit(node, parser.OpCallKw,
+				len(node.Args), len(node.KeywordArgs))
+			break
+		}
 		ellipsis := 0
 		if node.Ellipsis.IsValid() {
 			ellipsis = 1
@@ -556,7 +793,7 @@ func (c *Compiler) Compile(node parser.Node) error {
 		}
 
 		// export statement is simply ignore when compiling non-module code
-		if c.parent == nil {
+		if c.parent == nil && c.importer == nil {
 			break
 		}
 		if err := c.Compile(node.Result); err != nil {
@@ -658,6 +895,118 @@ func (c *Compiler) GetImportFileExt() []string {
 	return c.importFileExt
 }
 
+// compileSliceAssign compiles the assignment of value to the elements of a
+// slice expression.
+func (c *Compiler) compileSliceAssign(
+	node parser.Node,
+	slice *parser.SliceExpr,
+	value parser.Expr,
+) error {
+	for _, expr := range []parser.Expr{
+		value, slice.Expr, slice.Low, slice.High, slice.Step,
+	} {
+		if expr == nil {
+			c.emit(node, parser.OpNull)
+			continue
+		}
+		if err := c.Compile(expr); err != nil {
+			return err
+		}
+	}
+	c.emit(node, parser.OpSetSlice)
+	return nil
+}
+
+func (c *Compiler) compileConst(node *parser.ConstStmt) error {
+	ident := node.Name.Name
+	_, depth, exists := c.symbolTable.Resolve(ident, false)
+	if depth == 0 && exists {
+		return c.errorf(node, "'%s' redeclared in this block", ident)
+	}
+
+	// a function can refer to itself, so it's defined before compiling.
+	var symbol *Symbol
+	_, isFunc := node.Value.(*parser.FuncLit)
+	if isFunc {
+		symbol = c.symbolTable.DefineConst(ident, nil)
+	}
+	value := c.constValue(node.Value)
+	if err := c.Compile(node.Value); err != nil {
+		return err
+	}
+	if !isFunc {
+		symbol = c.symbolTable.DefineConst(ident, value)
+	}
+
+	if symbol.Scope == ScopeGlobal {
+		c.emit(node, parser.OpSetGlobal, symbol.Index)
+	} else {
+		if symbol.LocalAssigned {
+			c.emit(node, parser.OpSetLocal, symbol.Index)
+		} else {
+			c.emit(node, parser.OpDefineLocal, symbol.Index)
+		}
+		symbol.LocalAssigned = true
+	}
+	return nil
+}
+
+// constValue returns the value of a constant expression if it is a literal,
+// or nil otherwise.
+func (c *Compiler) constValue(expr parser.Expr) Object {
+	switch expr := expr.(type) {
+	case *parser.IntLit:
+		return &Int{Value: expr.Value}
+	case *parser.FloatLit:
+		return &Float{Value: expr.Value}
+	case *parser.StringLit:
+		if len(expr.Value) > MaxStringLen {
+			return nil
+		}
+		return &String{Value: expr.Value}
+	case *parser.CharLit:
+		return &Char{Value: expr.Value}
+	case *parser.BoolLit:
+		if expr.Value {
+			return TrueValue
+		}
+		return FalseValue
+	case *parser.UndefinedLit:
+		return UndefinedValue
+	case *parser.ParenExpr:
+		return c.constValue(expr.Expr)
+	case *parser.UnaryExpr:
+		if expr.Token != token.Sub {
+			return nil
+		}
+		switch x := expr.Expr.(type) {
+		case *parser.IntLit:
+			return &Int{Value: -x.Value}
+		case *parser.FloatLit:
+			return &Float{Value: -x.Value}
+		}
+	case *parser.Ident:
+		if symbol, _, ok := c.symbolTable.Resolve(expr.Name, false); ok {
+			return symbol.Value
+		}
+	}
+	return nil
+}
+
+// emitValue emits the instruction that pushes a literal value.
+func (c *Compiler) emitValue(node parser.Node, o Object) {
+	switch o {
+	case TrueValue:
+		c.emit(node, parser.OpTrue)
+	case FalseValue:
+		c.emit(node, parser.OpFalse)
+	case UndefinedValue:
+		c.emit(node, parser.OpNull)
+	default:
+		c.emit(node, parser.OpConstant, c.addConstant(o))
+	}
+}
+
 func (c *Compiler) compileAssign(
 	node parser.Node,
 	lhs, rhs []parser.Expr,
@@ -668,6 +1017,21 @@ func (c *Compiler) compileAssign(
 		return c.errorf(node, "tuple assignment not allowed")
 	}
 
+	if _, ok := lhs[0].(*parser.OptionalChainExpr); ok {
+		return c.errorf(node, "optional chaining not allowed in assignment")
+	}
+	if slice, ok := lhs[0].(*parser.SliceExpr); ok {
+		if slice.Optional {
+			return c.errorf(node,
+				"optional chaining not allowed in assignment")
+		}
+		if op != token.Assign {
+			return c.errorf(node, "operator '%s' not allowed with slice",
+				op.String())
+		}
+		return c.compileSliceAssign(node, slice, rhs[0])
+	}
+
 	// resolve and compile left-hand side
 	ident, selectors := resolveAssignLHS(lhs[0])
 	numSel := len(selectors)
@@ -690,6 +1054,9 @@ func (c *Compiler) compileAssign(
 		if !exists {
 			return c.errorf(node, "unresolved reference '%s'", ident)
 		}
+		if symbol.Constant && (numSel == 0 || symbol.Value != nil) {
+			return c.errorf(node, "cannot assign to constant '%s'", ident)
+		}
 	}
 
 	// +=, -=, *=, /=
@@ -733,6 +1100,8 @@ func (c *Compiler) compileAssign(
 		c.emit(node, parser.OpBinaryOp, int(token.Shl))
 	case token.ShrAssign:
 		c.emit(node, parser.OpBinaryOp, int(token.Shr))
+	case token.PowAssign:
+		c.emit(node, parser.OpBinaryOp, int(token.Pow))
 	}
 
 	// compile selector expressions (right to left)
@@ -783,9 +1152,12 @@ func (c *Compiler) compileLogical(node *parser.BinaryExpr) error {
 
 	// jump position
 	var jumpPos int
-	if node.Token == token.LAnd {
+	switch node.Token {
+	case token.LAnd:
 		jumpPos = c.emit(node, parser.OpAndJump, 0)
-	} else {
+	case token.Coalesce:
+		jumpPos = c.emit(node, parser.OpCoalesceJump, 0)
+	default:
 		jumpPos = c.emit(node, parser.OpOrJump, 0)
 	}
 
@@ -798,7 +1170,7 @@ func (c *Compiler) compileLogical(node *parser.BinaryExpr) error {
 	return nil
 }
 
-func (c *Compiler) compileForStmt(stmt *parser.ForStmt) error {
+func (c *Compiler) compileForStmt(stmt *parser.ForStmt, label string) error {
 	c.symbolTable = c.symbolTable.Fork(true)
 	defer func() {
 		c.symbolTable = c.symbolTable.Parent(false)
@@ -825,7 +1197,7 @@ func (c *Compiler) compileForStmt(stmt *parser.ForStmt) error {
 	}
 
 	// enter loop
-	loop := c.enterLoop()
+	loop := c.enterLoop(label)
 
 	// body statement
 	if err := c.Compile(stmt.Body); err != nil {
@@ -864,7 +1236,10 @@ func (c *Compiler) compileForStmt(stmt *parser.ForStmt) error {
 	return nil
 }
 
-func (c *Compiler) compileForInStmt(stmt *parser.ForInStmt) error {
+func (c *Compiler) compileForInStmt(
+	stmt *parser.ForInStmt,
+	label string,
+) error {
 	c.symbolTable = c.symbolTable.Fork(true)
 	defer func() {
 		c.symbolTable = c.symbolTable.Parent(false)
@@ -910,7 +1285,7 @@ func (c *Compiler) compileForInStmt(stmt *parser.ForInStmt) error {
 	postCondPos := c.emit(stmt, parser.OpJumpFalsy, 0)
 
 	// enter loop
-	loop := c.enterLoop()
+	loop := c.enterLoop(label)
 
 	// assign key variable
 	if stmt.Key.Name != "_" {
@@ -978,10 +1353,15 @@ func (c *Compiler) checkCyclicImports(
 	node parser.Node,
 	modulePath string,
 ) error {
-	if c.modulePath == modulePath {
-		return c.errorf(node, "cyclic module import: %s", modulePath)
-	} else if c.parent != nil {
-		return c.parent.checkCyclicImports(node, modulePath)
+	for p := c; p != nil; {
+		if p.modulePath == modulePath {
+			return c.errorf(node, "cyclic module import: %s", modulePath)
+		}
+		if p.parent != nil {
+			p = p.parent
+		} else {
+			p = p.importer
+		}
 	}
 	return nil
 }
@@ -1000,6 +1380,9 @@ func (c *Compiler) compileModule(
 	if exists {
 		return compiledModule, nil
 	}
+	if c.moduleCache != nil {
+		return c.compileSharedModule(node, modulePath, src, isFile)
+	}
 
 	modFile := c.file.Set().AddFile(modulePath, -1, len(src))
 	p := parser.NewParser(modFile, src, nil)
@@ -1051,8 +1434,8 @@ func (c *Compiler) storeCompiledModule(
 	c.compiledModules[modulePath] = module
 }
 
-func (c *Compiler) enterLoop() *loop {
-	loop := &loop{}
+func (c *Compiler) enterLoop(label string) *loop {
+	loop := &loop{Label: label, Scope: c.scopeIndex}
 	c.loops = append(c.loops, loop)
 	c.loopIndex++
 	if c.trace != nil {
@@ -1069,13 +1452,29 @@ func (c *Compiler) leaveLoop() {
 	c.loopIndex--
 }
 
-func (c *Compiler) currentLoop() *loop {
-	if c.loopIndex >= 0 {
-		return c.loops[c.loopIndex]
+// findLoop returns the innermost loop of the current function with the given
+// label, or the innermost loop of the current function if label is empty.
+func (c *Compiler) findLoop(label string) *loop {
+	for i := c.loopIndex; i >= 0; i-- {
+		l := c.loops[i]
+		if l.Scope != c.scopeIndex {
+			break
+		}
+		if label == "" || l.Label == label {
+			return l
+		}
 	}
 	return nil
 }
 
+// emitOptionalJump emits op with a jump to the end of the innermost optional
+// chain. OpOptionalJump jumps if the value on top of the stack is undefined,
+// and OpOptionalIndex if the value it indexes is not indexable.
+func (c *Compiler) emitOptionalJump(node parser.Node, op parser.Opcode) {
+	n := len(c.optionalJumps) - 1
+	c.optionalJumps[n] = append(c.optionalJumps[n], c.emit(node, op, 0))
+}
+
 func (c *Compiler) currentInstructions() []byte {
 	return c.scopes[c.scopeIndex].Instructions
 }
@@ -1124,6 +1523,7 @@ func (c *Compiler) fork(
 	child.allowFileImport = c.allowFileImport
 	child.importDir = c.importDir
 	child.importFileExt = c.importFileExt
+	child.moduleCache = c.moduleCache
 	if isFile && c.importDir != "" {
 		child.importDir = filepath.Dir(modulePath)
 	}
@@ -1198,8 +1598,12 @@ func (c *Compiler) optimizeFunc(node parser.Node) {
 		func(pos int, opcode parser.Opcode, operands []int) bool {
 			switch opcode {
 			case parser.OpJump, parser.OpJumpFalsy,
-				parser.OpAndJump, parser.OpOrJump:
+				parser.OpAndJump, parser.OpOrJump,
+				parser.OpOptionalJump, parser.OpCoalesceJump,
+				parser.OpOptionalIndex:
 				dsts[operands[0]] = true
+			case parser.OpDefaultJump:
+				dsts[operands[1]] = true
 			}
 			return true
 		})
@@ -1239,7 +1643,8 @@ func (c *Compiler) optimizeFunc(node parser.Node) {
 		func(pos int, opcode parser.Opcode, operands []int) bool {
 			switch opcode {
 			case parser.OpJump, parser.OpJumpFalsy, parser.OpAndJump,
-				parser.OpOrJump:
+				parser.OpOrJump, parser.OpOptionalJump, parser.OpCoalesceJump,
+				parser.OpOptionalIndex:
 				newDst, ok := posMap[operands[0]]
 				if ok {
 					copy(newInsts[pos:],
@@ -1253,6 +1658,18 @@ func (c *Compiler) optimizeFunc(node parser.Node) {
 				} else {
 					panic(fmt.Errorf("invalid jump position: %d", newDst))
 				}
+			case parser.OpDefaultJump:
+				newDst, ok := posMap[operands[1]]
+				if ok {
+					copy(newInsts[pos:],
+						MakeInstruction(opcode, operands[0], newDst))
+				} else if endPos == operands[1] {
+					copy(newInsts[pos:],
+						MakeInstruction(opcode, operands[0], newEndPost))
+					appendReturn = true
+				} else {
+					panic(fmt.Errorf("invalid jump position: %d", newDst))
+				}
 			}
 			lastOp = opcode
 			return true
@@ -1353,6 +1770,43 @@ func resolveAssignLHS(
 	return
 }
 
+// hasYield reports whether the statement contains a yield statement that
+// belongs to the enclosing function. Function literals are expressions, so
+// their bodies are never visited. Every statement type is listed: a new
+// compound statement must be added here, otherwise compiling a yield in it
+// fails with an error.
+func hasYield(stmt parser.Stmt) bool {
+	switch stmt := stmt.(type) {
+	case nil:
+		return false
+	case *parser.YieldStmt:
+		return true
+	case *parser.BlockStmt:
+		for _, s := range stmt.Stmts {
+			if hasYield(s) {
+				return true
+			}
+		}
+		return false
+	case *parser.IfStmt:
+		return hasYield(stmt.Init) || hasYield(stmt.Body) ||
+			hasYield(stmt.Else)
+	case *parser.ForStmt:
+		return hasYield(stmt.Init) || hasYield(stmt.Post) ||
+			hasYield(stmt.Body)
+	case *parser.ForInStmt:
+		return hasYield(stmt.Body)
+	case *parser.LabeledStmt:
+		return hasYield(stmt.Stmt)
+	case *parser.AssignStmt, *parser.BadStmt, *parser.BranchStmt,
+		*parser.ConstStmt, *parser.DeferStmt, *parser.EmptyStmt,
+		*parser.ExportStmt, *parser.ExprStmt, *parser.IncDecStmt,
+		*parser.ReturnStmt:
+		return false
+	}
+	return false
+}
+
 func iterateInstructions(
 	b []byte,
 	fn func(pos int, opcode parser.Opcode, operands []int) bool,
@@ -162,6 +162,31 @@ func TestCompiler_Compile(t *testing.T) {
 				intObject(1),
 				intObject(2))))
 
+	expectCompile(t, `1 in 2`,
+		bytecode(
+			concatInsts(
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpConstant, 1),
+				tengo.MakeInstruction(parser.OpContains),
+				tengo.MakeInstruction(parser.OpPop),
+				tengo.MakeInstruction(parser.OpSuspend)),
+			objectsArray(
+				intObject(1),
+				intObject(2))))
+
+	expectCompile(t, `1 not in 2`,
+		bytecode(
+			concatInsts(
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpConstant, 1),
+				tengo.MakeInstruction(parser.OpContains),
+				tengo.MakeInstruction(parser.OpLNot),
+				tengo.MakeInstruction(parser.OpPop),
+				tengo.MakeInstruction(parser.OpSuspend)),
+			objectsArray(
+				intObject(1),
+				intObject(2))))
+
 	expectCompile(t, `true == false`,
 		bytecode(
 			concatInsts(
@@ -270,6 +295,23 @@ func TestCompiler_Compile(t *testing.T) {
 				intObject(1),
 				intObject(2))))
 
+	expectCompile(t, `const a = 1; b := [a, true]; const c = b; c`,
+		bytecode(
+			concatInsts(
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpSetGlobal, 0),
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpTrue),
+				tengo.MakeInstruction(parser.OpArray, 2),
+				tengo.MakeInstruction(parser.OpSetGlobal, 1),
+				tengo.MakeInstruction(parser.OpGetGlobal, 1),
+				tengo.MakeInstruction(parser.OpSetGlobal, 2),
+				tengo.MakeInstruction(parser.OpGetGlobal, 2),
+				tengo.MakeInstruction(parser.OpPop),
+				tengo.MakeInstruction(parser.OpSuspend)),
+			objectsArray(
+				intObject(1))))
+
 	expectCompile(t, `a := 1; b := 2; a /= b`,
 		bytecode(
 			concatInsts(
@@ -381,6 +423,39 @@ func TestCompiler_Compile(t *testing.T) {
 				intObject(5),
 				intObject(6))))
 
+	expectCompile(t, `[1, ...[2], 3, 4]`,
+		bytecode(
+			concatInsts(
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpArray, 1),
+				tengo.MakeInstruction(parser.OpConstant, 1),
+				tengo.MakeInstruction(parser.OpArray, 1),
+				tengo.MakeInstruction(parser.OpConstant, 2),
+				tengo.MakeInstruction(parser.OpConstant, 3),
+				tengo.MakeInstruction(parser.OpArray, 2),
+				tengo.MakeInstruction(parser.OpArrayConcat, 3),
+				tengo.MakeInstruction(parser.OpPop),
+				tengo.MakeInstruction(parser.OpSuspend)),
+			objectsArray(
+				intObject(1),
+				intObject(2),
+				intObject(3),
+				intObject(4))))
+
+	expectCompile(t, `{...{}, a: 2}`,
+		bytecode(
+			concatInsts(
+				tengo.MakeInstruction(parser.OpMap, 0),
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpConstant, 1),
+				tengo.MakeInstruction(parser.OpMap, 2),
+				tengo.MakeInstruction(parser.OpMapMerge, 2),
+				tengo.MakeInstruction(parser.OpPop),
+				tengo.MakeInstruction(parser.OpSuspend)),
+			objectsArray(
+				stringObject("a"),
+				intObject(2))))
+
 	expectCompile(t, `[1, 2, 3][1 + 1]`,
 		bytecode(
 			concatInsts(
@@ -486,6 +561,45 @@ func TestCompiler_Compile(t *testing.T) {
 				intObject(3),
 				intObject(0))))
 
+	expectCompile(t, `[1, 2, 3][::-1]`,
+		bytecode(
+			concatInsts(
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpConstant, 1),
+				tengo.MakeInstruction(parser.OpConstant, 2),
+				tengo.MakeInstruction(parser.OpArray, 3),
+				tengo.MakeInstruction(parser.OpNull),
+				tengo.MakeInstruction(parser.OpNull),
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpMinus),
+				tengo.MakeInstruction(parser.OpSliceStep),
+				tengo.MakeInstruction(parser.OpPop),
+				tengo.MakeInstruction(parser.OpSuspend)),
+			objectsArray(
+				intObject(1),
+				intObject(2),
+				intObject(3))))
+
+	expectCompile(t, `a := [1, 2]; a[:1] = [3]`,
+		bytecode(
+			concatInsts(
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpConstant, 1),
+				tengo.MakeInstruction(parser.OpArray, 2),
+				tengo.MakeInstruction(parser.OpSetGlobal, 0),
+				tengo.MakeInstruction(parser.OpConstant, 2),
+				tengo.MakeInstruction(parser.OpArray, 1),
+				tengo.MakeInstruction(parser.OpGetGlobal, 0),
+				tengo.MakeInstruction(parser.OpNull),
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpNull),
+				tengo.MakeInstruction(parser.OpSetSlice),
+				tengo.MakeInstruction(parser.OpSuspend)),
+			objectsArray(
+				intObject(1),
+				intObject(2),
+				intObject(3))))
+
 	expectCompile(t, `f1 := func(a) { return a }; f1([1, 2]...);`,
 		bytecode(
 			concatInsts(
@@ -619,6 +733,26 @@ func TestCompiler_Compile(t *testing.T) {
 				compiledFunction(0, 0,
 					tengo.MakeInstruction(parser.OpReturn, 0)))))
 
+	expectCompile(t, `func(a, b = 2) { return b }(a: 1)`,
+		bytecode(
+			concatInsts(
+				tengo.MakeInstruction(parser.OpConstant, 1),
+				tengo.MakeInstruction(parser.OpConstant, 2),
+				tengo.MakeInstruction(parser.OpConstant, 3),
+				tengo.MakeInstruction(parser.OpCallKw, 0, 1),
+				tengo.MakeInstruction(parser.OpPop),
+				tengo.MakeInstruction(parser.OpSuspend)),
+			objectsArray(
+				intObject(2),
+				compiledFunction(2, 2,
+					tengo.MakeInstruction(parser.OpDefaultJump, 1, 11), // 0000
+					tengo.MakeInstruction(parser.OpConstant, 0),        // 0006
+					tengo.MakeInstruction(parser.OpDefineLocal, 1),     // 0009
+					tengo.MakeInstruction(parser.OpGetLocal, 1),        // 0011
+					tengo.MakeInstruction(parser.OpReturn, 1)),         // 0013
+				stringObject("a"),
+				intObject(1))))
+
 	expectCompile(t, `func() { 24 }()`,
 		bytecode(
 			concatInsts(
@@ -840,6 +974,23 @@ func TestCompiler_Compile(t *testing.T) {
 					tengo.MakeInstruction(parser.OpPop),
 					tengo.MakeInstruction(parser.OpReturn, 0)))))
 
+	expectCompile(t, `a => b => a + b`,
+		bytecode(
+			concatInsts(
+				tengo.MakeInstruction(parser.OpConstant, 1),
+				tengo.MakeInstruction(parser.OpPop),
+				tengo.MakeInstruction(parser.OpSuspend)),
+			objectsArray(
+				compiledFunction(1, 1,
+					tengo.MakeInstruction(parser.OpGetFree, 0),
+					tengo.MakeInstruction(parser.OpGetLocal, 0),
+					tengo.MakeInstruction(parser.OpBinaryOp, 11),
+					tengo.MakeInstruction(parser.OpReturn, 1)),
+				compiledFunction(1, 1,
+					tengo.MakeInstruction(parser.OpGetLocalPtr, 0),
+					tengo.MakeInstruction(parser.OpClosure, 0, 1),
+					tengo.MakeInstruction(parser.OpReturn, 1)))))
+
 	expectCompile(t, `
 func(a) {
 	return func(b) {
@@ -1009,6 +1160,12 @@ r["x"] = {
 
 	expectCompileError(t, `import("")`, "empty module name")
 
+	// errors inside interpolated strings
+	expectCompileError(t, `x := f"a ${1 +} b"`,
+		"Parse Error: expected operand, found } b\"\n\tat test:1:15")
+	expectCompileError(t, "x := 1\ny := f\"${x} ${z}\"",
+		"Compile Error: unresolved reference 'z'\n\tat test:2:15")
+
 	// https://github.com/d5/tengo/issues/314
 	expectCompileError(t, `
 (func() {
@@ -1029,15 +1186,37 @@ func TestCompilerErrorReport(t *testing.T) {
 		"Compile Error: tuple assignment not allowed\n\tat test:1:1")
 	expectCompileError(t, `a.b := 1`,
 		"not allowed with selector")
+	expectCompileError(t, `a := [1]; a[:] += [2]`,
+		"Compile Error: operator '+=' not allowed with slice\n\tat test:1:11")
+	expectCompileError(t, `a := [1]; a?.[:] = [2]`,
+		"Compile Error: optional chaining not allowed in assignment\n\tat test:1:11")
 	expectCompileError(t, `a:=1; a:=3`,
 		"Compile Error: 'a' redeclared in this block\n\tat test:1:7")
+	expectCompileError(t, `const a = 1; a = 2`,
+		"Compile Error: cannot assign to constant 'a'\n\tat test:1:14")
+	expectCompileError(t, `const a = 1; func() { a++ }`,
+		"Compile Error: cannot assign to constant 'a'\n\tat test:1:23")
+	expectCompileError(t, `func() { const a = {}; return func() { a = {} } }`,
+		"Compile Error: cannot assign to constant 'a'\n\tat test:1:40")
+	expectCompileError(t, `const a = "x"; a[0] = 1`,
+		"Compile Error: cannot assign to constant 'a'\n\tat test:1:16")
+	expectCompileError(t, `a := 1; const a = 2`,
+		"Compile Error: 'a' redeclared in this block\n\tat test:1:9")
 
 	expectCompileError(t, `return 5`,
 		"Compile Error: return not allowed outside function\n\tat test:1:1")
+	expectCompileError(t, `yield 5`,
+		"Compile Error: yield not allowed outside function\n\tat test:1:1")
+	expectCompileError(t, `func() { yield 1; return 2 }`,
+		"Compile Error: return with value not allowed in generator\n\tat test:1:19")
 	expectCompileError(t, `func() { break }`,
 		"Compile Error: break not allowed outside loop\n\tat test:1:10")
 	expectCompileError(t, `func() { continue }`,
 		"Compile Error: continue not allowed outside loop\n\tat test:1:10")
+	expectCompileError(t, `a: for { for { break b } }`,
+		"Compile Error: unknown label 'b'\n\tat test:1:16")
+	expectCompileError(t, `a: for { func() { continue a } }`,
+		"Compile Error: unknown label 'a'\n\tat test:1:19")
 	expectCompileError(t, `func() { export 5 }`,
 		"Compile Error: export not allowed inside function\n\tat test:1:10")
 }
@@ -0,0 +1,371 @@
+package tengo
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/shelepuginivan/tengo/token"
+)
+
+// RoundingMode specifies how a Decimal is rounded when digits are dropped.
+type RoundingMode int
+
+// List of rounding modes.
+const (
+	// RoundHalfEven rounds to the nearest neighbor, ties to the even one.
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds to the nearest neighbor, ties away from zero.
+	RoundHalfUp
+	// RoundHalfDown rounds to the nearest neighbor, ties toward zero.
+	RoundHalfDown
+	// RoundUp rounds away from zero.
+	RoundUp
+	// RoundDown rounds toward zero (truncation).
+	RoundDown
+	// RoundCeiling rounds toward positive infinity.
+	RoundCeiling
+	// RoundFloor rounds toward negative infinity.
+	RoundFloor
+)
+
+var (
+	// DecimalPrecision is the default number of digits after the decimal
+	// point kept in the result of a decimal division. Scripts and VMs use
+	// the value at the time they are created, unless they set their own
+	// with SetDecimalPrecision. It is also used by Decimal.BinaryOp.
+	DecimalPrecision = 16
+
+	// DecimalRounding is the default rounding mode of decimal division, like
+	// DecimalPrecision, and the rounding mode of formatting with an explicit
+	// precision.
+	DecimalRounding = RoundHalfEven
+)
+
+// roundingModes are the names of the rounding modes in scripts.
+var roundingModes = map[string]RoundingMode{
+	"half_even": RoundHalfEven,
+	"half_up":   RoundHalfUp,
+	"half_down": RoundHalfDown,
+	"up":        RoundUp,
+	"down":      RoundDown,
+	"ceiling":   RoundCeiling,
+	"floor":     RoundFloor,
+}
+
+var errInvalidDecimal = errors.New("invalid decimal")
+
+// Decimal represents an exact decimal number: Value * 10^-Scale.
+type Decimal struct {
+	ObjectImpl
+	Value *big.Int
+	Scale int32
+}
+
+// ParseDecimal parses a decimal number such as "12.34", "-0.5" or "1.5e3".
+// All digits of s are kept. It returns ErrDecimalLimit if the number has
+// more than MaxDecimalDigits digits, or digits after the decimal point.
+func ParseDecimal(s string) (*Decimal, error) {
+	mant, exp := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return nil, errInvalidDecimal
+		}
+		mant, exp = s[:i], e
+	}
+	var scale int64
+	if i := strings.IndexByte(mant, '.'); i >= 0 {
+		scale = int64(len(mant) - i - 1)
+		mant = mant[:i] + mant[i+1:]
+	}
+	digits := strings.TrimLeft(mant, "+-")
+	if digits == "" || len(mant)-len(digits) > 1 ||
+		strings.TrimLeft(digits, "0123456789") != "" {
+		return nil, errInvalidDecimal
+	}
+	scale -= exp
+	if scale > math.MaxInt32 || -scale > math.MaxInt32 {
+		return nil, errInvalidDecimal
+	}
+	if int64(len(digits)) > int64(MaxDecimalDigits) ||
+		scale > int64(MaxDecimalDigits) ||
+		scale < 0 && int64(len(digits))-scale > int64(MaxDecimalDigits) {
+		return nil, ErrDecimalLimit
+	}
+	v, ok := new(big.Int).SetString(mant, 10)
+	if !ok {
+		return nil, errInvalidDecimal
+	}
+	if scale < 0 {
+		v.Mul(v, pow10(int32(-scale)))
+		scale = 0
+	}
+	return &Decimal{Value: v, Scale: int32(scale)}, nil
+}
+
+func (o *Decimal) String() string {
+	s := new(big.Int).Abs(o.Value).String()
+	if o.Scale > 0 {
+		if n := int(o.Scale) + 1 - len(s); n > 0 {
+			s = strings.Repeat("0", n) + s
+		}
+		p := len(s) - int(o.Scale)
+		s = s[:p] + "." + s[p:]
+	}
+	if o.Value.Sign() < 0 {
+		return "-" + s
+	}
+	return s
+}
+
+// TypeName returns the name of the type.
+func (o *Decimal) TypeName() string {
+	return "decimal"
+}
+
+// BinaryOp returns another object that is the result of a given binary
+// operator and a right-hand side object. Int, BigInt and Float operands are
+// converted to Decimal; floats use their shortest decimal representation.
+// Division keeps DecimalPrecision digits, see QuoRound.
+func (o *Decimal) BinaryOp(op token.Token, rhs Object) (Object, error) {
+	y, ok := decimalOperand(rhs)
+	if !ok {
+		return nil, ErrInvalidOperator
+	}
+
+	switch op {
+	case token.Add, token.Sub:
+		a, b, scale := alignDecimals(o, y)
+		if op == token.Add {
+			return checkDecimal(&Decimal{Value: a.Add(a, b), Scale: scale})
+		}
+		return checkDecimal(&Decimal{Value: a.Sub(a, b), Scale: scale})
+	case token.Mul:
+		return checkDecimal(&Decimal{
+			Value: new(big.Int).Mul(o.Value, y.Value),
+			Scale: o.Scale + y.Scale,
+		})
+	case token.Quo:
+		return o.QuoRound(y, DecimalPrecision, DecimalRounding)
+	case token.Rem:
+		if y.Value.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		a, b, scale := alignDecimals(o, y)
+		return checkDecimal(&Decimal{Value: a.Rem(a, b), Scale: scale})
+	case token.Pow:
+		// only integral, non-negative exponents give exact results
+		e := y.Copy().(*Decimal).trim()
+		if e.Scale != 0 || e.Value.Sign() < 0 || !e.Value.IsInt64() {
+			return nil, ErrInvalidOperator
+		}
+		n := e.Value.Int64()
+		if n > math.MaxInt32/int64(o.Scale+1) {
+			return nil, ErrDecimalLimit
+		}
+		// x**n has more than (bits(x)-1)*n bits
+		if b := int64(o.Value.BitLen()); b > 1 &&
+			(b-1)*n > int64(MaxDecimalDigits)*4 {
+			return nil, ErrDecimalLimit
+		}
+		return checkDecimal(&Decimal{
+			Value: new(big.Int).Exp(o.Value, e.Value, nil),
+			Scale: o.Scale * int32(n),
+		})
+	case token.Less, token.Greater, token.LessEq, token.GreaterEq:
+		c := o.Cmp(y)
+		if op == token.Less && c < 0 || op == token.Greater && c > 0 ||
+			op == token.LessEq && c <= 0 || op == token.GreaterEq && c >= 0 {
+			return TrueValue, nil
+		}
+		return FalseValue, nil
+	}
+	return nil, ErrInvalidOperator
+}
+
+// QuoRound returns o divided by rhs, keeping at most places digits after the
+// decimal point, rounded with mode. Trailing zeros of the result are
+// removed. rhs is converted like in BinaryOp.
+func (o *Decimal) QuoRound(
+	rhs Object,
+	places int,
+	mode RoundingMode,
+) (Object, error) {
+	y, ok := decimalOperand(rhs)
+	if !ok {
+		return nil, ErrInvalidOperator
+	}
+	if y.Value.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+	if int64(places) > int64(MaxDecimalDigits) {
+		places = MaxDecimalDigits
+	}
+	return checkDecimal(o.quo(y, int32(places), mode).trim())
+}
+
+// Copy returns a copy of the type.
+func (o *Decimal) Copy() Object {
+	return &Decimal{Value: new(big.Int).Set(o.Value), Scale: o.Scale}
+}
+
+// IsFalsy returns true if the value of the type is falsy.
+func (o *Decimal) IsFalsy() bool {
+	return o.Value.Sign() == 0
+}
+
+// Equals returns true if the value of the type is equal to the value of
+// another object. Decimals are compared by value like in the ordering
+// comparisons, so 1.5 equals 1.50 and the float 1.5.
+func (o *Decimal) Equals(x Object) bool {
+	y, ok := decimalOperand(x)
+	return ok && o.Cmp(y) == 0
+}
+
+// Cmp compares o and y and returns -1, 0 or +1.
+func (o *Decimal) Cmp(y *Decimal) int {
+	a, b, _ := alignDecimals(o, y)
+	return a.Cmp(b)
+}
+
+// Round returns o rounded to exactly places digits after the decimal point
+// using the given rounding mode. places is at most MaxDecimalDigits.
+func (o *Decimal) Round(places int32, mode RoundingMode) *Decimal {
+	if places < 0 {
+		places = 0
+	}
+	if int64(places) > int64(MaxDecimalDigits) {
+		places = int32(MaxDecimalDigits)
+	}
+	if places >= o.Scale {
+		return &Decimal{
+			Value: new(big.Int).Mul(o.Value, pow10(places-o.Scale)),
+			Scale: places,
+		}
+	}
+	return &Decimal{
+		Value: quoRound(o.Value, pow10(o.Scale-places), mode),
+		Scale: places,
+	}
+}
+
+// Float64 returns the float64 value nearest to o.
+func (o *Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(o.String(), 64)
+	return f
+}
+
+// BigInt returns the integer part of o, truncated toward zero.
+func (o *Decimal) BigInt() *big.Int {
+	return new(big.Int).Quo(o.Value, pow10(o.Scale))
+}
+
+// quo divides o by y keeping places digits after the decimal point, at
+// most MaxDecimalDigits.
+func (o *Decimal) quo(y *Decimal, places int32, mode RoundingMode) *Decimal {
+	if places < 0 {
+		places = 0
+	}
+	if int64(places) > int64(MaxDecimalDigits) {
+		places = int32(MaxDecimalDigits)
+	}
+	num := new(big.Int).Set(o.Value)
+	den := new(big.Int).Set(y.Value)
+	if k := int64(places) - int64(o.Scale) + int64(y.Scale); k >= 0 {
+		num.Mul(num, pow10(int32(k)))
+	} else {
+		den.Mul(den, pow10(int32(-k)))
+	}
+	return &Decimal{Value: quoRound(num, den, mode), Scale: places}
+}
+
+// trim removes trailing zeros after the decimal point.
+func (o *Decimal) trim() *Decimal {
+	ten := big.NewInt(10)
+	q, r := new(big.Int), new(big.Int)
+	for o.Scale > 0 {
+		q.QuoRem(o.Value, ten, r)
+		if r.Sign() != 0 {
+			break
+		}
+		o.Value.Set(q)
+		o.Scale--
+	}
+	return o
+}
+
+// alignDecimals returns the unscaled values of x and y brought to a common
+// scale.
+func alignDecimals(x, y *Decimal) (a, b *big.Int, scale int32) {
+	a, b = new(big.Int).Set(x.Value), new(big.Int).Set(y.Value)
+	switch {
+	case x.Scale < y.Scale:
+		a.Mul(a, pow10(y.Scale-x.Scale))
+		return a, b, y.Scale
+	case x.Scale > y.Scale:
+		b.Mul(b, pow10(x.Scale-y.Scale))
+	}
+	return a, b, x.Scale
+}
+
+// quoRound returns num/den rounded to an integer using the given mode.
+func quoRound(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	sign := num.Sign() * den.Sign()
+	var inc bool
+	switch mode {
+	case RoundUp:
+		inc = true
+	case RoundDown:
+	case RoundCeiling:
+		inc = sign > 0
+	case RoundFloor:
+		inc = sign < 0
+	default:
+		r.Abs(r).Lsh(r, 1)
+		switch c := r.Cmp(new(big.Int).Abs(den)); {
+		case c > 0:
+			inc = true
+		case c == 0:
+			inc = mode == RoundHalfUp ||
+				mode == RoundHalfEven && q.Bit(0) == 1
+		}
+	}
+	if inc {
+		q.Add(q, big.NewInt(int64(sign)))
+	}
+	return q
+}
+
+// decimalOperand converts the operand of a decimal operation to a Decimal.
+func decimalOperand(o Object) (*Decimal, bool) {
+	switch o := o.(type) {
+	case *Decimal:
+		return o, true
+	case *Int, *BigInt, *Float:
+		return ToDecimal(o)
+	}
+	return nil, false
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// checkDecimal returns d, or ErrDecimalLimit if d has more than
+// MaxDecimalDigits digits, or digits after the decimal point.
+func checkDecimal(d *Decimal) (Object, error) {
+	// a value of n bits has about n*log10(2) digits
+	digits := int64(d.Value.BitLen()) * 30103 / 100000
+	if digits > int64(MaxDecimalDigits) ||
+		int64(d.Scale) > int64(MaxDecimalDigits) {
+		return nil, ErrDecimalLimit
+	}
+	return d, nil
+}
@@ -0,0 +1,240 @@
+package tengo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Engine creates scripts for many tenants. The scripts share a module map,
+// a cache of compiled source modules, so that a module imported by many
+// scripts is compiled once, and builtin variables. Each tenant has a quota
+// that limits the resources its scripts can use in a run, and the engine
+// keeps the resource usage of each tenant. Engine is safe for concurrent
+// use.
+type Engine struct {
+	modules      *ModuleMap
+	cache        *moduleCache
+	mu           sync.RWMutex
+	builtins     map[string]Object
+	defaultQuota Quota
+	tenants      map[string]*tenant
+}
+
+// Quota limits the resources that a script can use in a single run. Zero
+// values mean no limit.
+type Quota struct {
+	// MaxAllocs is the maximum number of object allocations.
+	MaxAllocs int64
+
+	// MaxInstructions is the maximum number of executed instructions.
+	MaxInstructions int64
+
+	// MaxDuration is the maximum wall time.
+	MaxDuration time.Duration
+}
+
+// Usage is the resource usage of the scripts of a tenant.
+type Usage struct {
+	Compiles      int64 // number of compiled scripts
+	CompileErrors int64 // number of scripts that failed to compile
+	Runs          int64 // number of runs, including function calls
+	RunErrors     int64 // number of runs that failed
+	QuotaExceeded int64 // number of runs that exceeded the quota
+
+	Allocs       int64         // allocated objects
+	Instructions int64         // executed instructions
+	Duration     time.Duration // wall time of the runs
+}
+
+// EngineStats are the statistics of the compiled module cache of an engine.
+type EngineStats struct {
+	ModuleCacheHits   int64
+	ModuleCacheMisses int64
+}
+
+type tenant struct {
+	quota *Quota // nil for the default quota
+	usage tenantUsage
+}
+
+// tenantUsage is the resource usage of a tenant, updated by its scripts.
+type tenantUsage struct {
+	compiles      atomic.Int64
+	compileErrors atomic.Int64
+	runs          atomic.Int64
+	runErrors     atomic.Int64
+	quotaExceeded atomic.Int64
+	allocs        atomic.Int64
+	insts         atomic.Int64
+	duration      atomic.Int64
+}
+
+// NewEngine creates an engine whose scripts import modules from modules.
+func NewEngine(modules *ModuleMap) *Engine {
+	if modules == nil {
+		modules = NewModuleMap()
+	}
+	return &Engine{
+		modules:  modules,
+		cache:    newModuleCache(),
+		builtins: make(map[string]Object),
+		tenants:  make(map[string]*tenant),
+	}
+}
+
+// AddBuiltin adds a variable to all scripts created by the engine after
+// the call. The variable is read-only, and each script gets its own copy
+// of the value.
+func (e *Engine) AddBuiltin(name string, value any) error {
+	if name == reservedVar {
+		return fmt.Errorf("'%s' is reserved", name)
+	}
+	obj, err := FromInterface(value)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.builtins[name] = obj
+	return nil
+}
+
+// SetDefaultQuota sets the quota of the tenants without their own quota. The
+// quota applies to the scripts created after the call.
+func (e *Engine) SetDefaultQuota(q Quota) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.defaultQuota = q
+}
+
+// SetQuota sets the quota of a tenant. The quota applies to the scripts
+// created after the call.
+func (e *Engine) SetQuota(tenantName string, q Quota) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.tenant(tenantName).quota = &q
+}
+
+// NewScript creates a script of a tenant. The script imports modules from
+// the module map of the engine, has the builtin variables of the engine,
+// and is limited by the quota of the tenant. Its runs are added to the
+// usage of the tenant.
+func (e *Engine) NewScript(tenantName string, src []byte) *Script {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	t := e.tenant(tenantName)
+	quota := e.defaultQuota
+	if t.quota != nil {
+		quota = *t.quota
+	}
+
+	s := NewScript(src)
+	s.SetImports(e.modules)
+	s.moduleCache = e.cache
+	s.usage = &t.usage
+	for name, value := range e.builtins {
+		s.variables[name] = &Variable{
+			name:     name,
+			value:    value.Copy(),
+			readOnly: true,
+		}
+	}
+	if quota.MaxAllocs > 0 {
+		s.SetMaxAllocs(quota.MaxAllocs)
+	}
+	if quota.MaxInstructions > 0 {
+		s.SetMaxInstructions(quota.MaxInstructions)
+	}
+	s.timeout = quota.MaxDuration
+	return s
+}
+
+// Usage returns the resource usage of a tenant.
+func (e *Engine) Usage(tenantName string) Usage {
+	e.mu.RLock()
+	t, ok := e.tenants[tenantName]
+	e.mu.RUnlock()
+	if !ok {
+		return Usage{}
+	}
+
+	u := &t.usage
+	return Usage{
+		Compiles:      u.compiles.Load(),
+		CompileErrors: u.compileErrors.Load(),
+		Runs:          u.runs.Load(),
+		RunErrors:     u.runErrors.Load(),
+		QuotaExceeded: u.quotaExceeded.Load(),
+		Allocs:        u.allocs.Load(),
+		Instructions:  u.insts.Load(),
+		Duration:      time.Duration(u.duration.Load()),
+	}
+}
+
+// Tenants returns the sorted names of the tenants of the engine.
+func (e *Engine) Tenants() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	names := make([]string, 0, len(e.tenants))
+	for name := range e.tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats returns the statistics of the compiled module cache of the engine.
+func (e *Engine) Stats() EngineStats {
+	return EngineStats{
+		ModuleCacheHits:   e.cache.hits.Load(),
+		ModuleCacheMisses: e.cache.misses.Load(),
+	}
+}
+
+// tenant returns a tenant, creating it if it does not exist. e.mu must be
+// locked.
+func (e *Engine) tenant(name string) *tenant {
+	t, ok := e.tenants[name]
+	if !ok {
+		t = &tenant{}
+		e.tenants[name] = t
+	}
+	return t
+}
+
+func (u *tenantUsage) recordCompile(err error) {
+	u.compiles.Add(1)
+	if err != nil {
+		u.compileErrors.Add(1)
+	}
+}
+
+func (u *tenantUsage) recordRun(
+	vm *VM,
+	d time.Duration,
+	err error,
+	timedOut bool,
+) {
+	u.runs.Add(1)
+	u.allocs.Add(vm.maxAllocs + 1 - vm.allocs)
+	u.insts.Add(vm.maxInsts + 1 - vm.insts)
+	u.duration.Add(int64(d))
+	if err == nil {
+		return
+	}
+	u.runErrors.Add(1)
+	if timedOut || errors.Is(err, ErrObjectAllocLimit) ||
+		errors.Is(err, ErrInstructionLimit) {
+		u.quotaExceeded.Add(1)
+	}
+}
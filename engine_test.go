@@ -0,0 +1,219 @@
+package tengo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shelepuginivan/tengo"
+	"github.com/shelepuginivan/tengo/require"
+)
+
+func TestEngine_SharedModules(t *testing.T) {
+	mods := tengo.NewModuleMap()
+	mods.AddSourceModule("util", []byte(`
+base := 10
+export {
+	add: func(x) { return base + x },
+	greet: func(name) { return "hello, " + name },
+	fail: func(x) { return x + "a" }
+}`))
+	mods.AddSourceModule("mod", []byte(`
+util := import("util")
+export func(x) { return util.add(x) * 2 }`))
+	e := tengo.NewEngine(mods)
+
+	// each script has constants of its own before the imported ones
+	srcs := []string{
+		`out := import("util").add(1)`,
+		`a := "x"; b := 2.5; out := import("util").add(2)`,
+		`s := "y"; out := import("mod")(3)`,
+		`out := import("util").greet("bob")`,
+	}
+	expected := []any{int64(11), int64(12), int64(26), "hello, bob"}
+	for i, src := range srcs {
+		c, err := e.NewScript("t", []byte(src)).CompileRun()
+		require.NoError(t, err, src)
+		require.Equal(t, expected[i], c.Get("out").Value(), src)
+	}
+	stats := e.Stats()
+	require.Equal(t, int64(2), stats.ModuleCacheMisses)
+	require.Equal(t, int64(3), stats.ModuleCacheHits)
+
+	// runtime errors in a shared module report positions in the module
+	_, err := e.NewScript("t", []byte(`
+x := 1
+import("util").fail(x)`)).CompileRun()
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "at util:6:25"),
+		"unexpected error: %v", err)
+	require.True(t, strings.Contains(err.Error(), "at (main):3:1"),
+		"unexpected error: %v", err)
+
+	// scripts of another engine do not share the cache
+	e2 := tengo.NewEngine(mods)
+	_, err = e2.NewScript("t", []byte(`out := import("mod")(1)`)).
+		CompileRun()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), e2.Stats().ModuleCacheHits)
+	require.Equal(t, int64(2), e2.Stats().ModuleCacheMisses)
+}
+
+func TestEngine_CyclicImports(t *testing.T) {
+	mods := tengo.NewModuleMap()
+	mods.AddSourceModule("a", []byte(`import("b")`))
+	mods.AddSourceModule("b", []byte(`import("a")`))
+	e := tengo.NewEngine(mods)
+
+	_, err := e.NewScript("t", []byte(`import("a")`)).Compile()
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "cyclic module import: a"),
+		"unexpected error: %v", err)
+}
+
+func TestEngine_Quotas(t *testing.T) {
+	e := tengo.NewEngine(nil)
+	e.SetDefaultQuota(tengo.Quota{MaxInstructions: 1000})
+	e.SetQuota("allocs", tengo.Quota{MaxAllocs: 10})
+	e.SetQuota("time", tengo.Quota{MaxDuration: 10 * time.Millisecond})
+
+	loop := []byte(`for i := 0; i < 1000; i++ {}`)
+	_, err := e.NewScript("default", loop).CompileRun()
+	require.True(t, errors.Is(err, tengo.ErrInstructionLimit),
+		"unexpected error: %v", err)
+
+	// a tenant without its own quota uses the current default quota
+	e.SetDefaultQuota(tengo.Quota{MaxInstructions: 100000})
+	_, err = e.NewScript("default", loop).CompileRun()
+	require.NoError(t, err)
+	e.SetDefaultQuota(tengo.Quota{MaxInstructions: 1000})
+
+	_, err = e.NewScript("allocs", []byte(`a := [1, 2]`)).CompileRun()
+	require.NoError(t, err)
+	_, err = e.NewScript("allocs", []byte(`
+a := []
+for i := 0; i < 20; i++ { a = append(a, [i]) }`)).CompileRun()
+	require.True(t, errors.Is(err, tengo.ErrObjectAllocLimit),
+		"unexpected error: %v", err)
+
+	_, err = e.NewScript("time", []byte(`for {}`)).CompileRun()
+	require.True(t, errors.Is(err, context.DeadlineExceeded),
+		"unexpected error: %v", err)
+
+	// the quota applies to each run
+	c, err := e.NewScript("default", []byte(`
+n := 0
+f := func() { for i := 0; i < 50; i++ { n++ } }`)).Compile()
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, c.Run(context.Background()))
+	}
+	require.Equal(t, int64(0), c.Get("n").Value())
+
+	u := e.Usage("default")
+	require.Equal(t, int64(3), u.Compiles)
+	require.Equal(t, int64(7), u.Runs)
+	require.Equal(t, int64(1), u.RunErrors)
+	require.Equal(t, int64(1), u.QuotaExceeded)
+	require.True(t, u.Instructions > 1000, "instructions: %d",
+		u.Instructions)
+
+	u = e.Usage("time")
+	require.Equal(t, int64(1), u.QuotaExceeded)
+	require.True(t, u.Duration >= 10*time.Millisecond, "duration: %v",
+		u.Duration)
+
+	// a cancelled context is not a quota violation
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c, err = e.NewScript("time", []byte(`for {}`)).Compile()
+	require.NoError(t, err)
+	require.True(t, errors.Is(c.Run(ctx), context.Canceled))
+	u = e.Usage("time")
+	require.Equal(t, int64(2), u.RunErrors)
+	require.Equal(t, int64(1), u.QuotaExceeded)
+
+	require.Equal(t, []string{"allocs", "default", "time"}, e.Tenants())
+	require.True(t, e.Usage("unknown") == tengo.Usage{})
+}
+
+func TestEngine_Usage(t *testing.T) {
+	e := tengo.NewEngine(nil)
+	_, err := e.NewScript("t", []byte(`a := [1, 2, 3]`)).CompileRun()
+	require.NoError(t, err)
+	_, err = e.NewScript("t", []byte(`a :=`)).Compile()
+	require.Error(t, err)
+	_, err = e.NewScript("t", []byte(`a := 1; a()`)).CompileRun()
+	require.Error(t, err)
+
+	u := e.Usage("t")
+	require.Equal(t, int64(3), u.Compiles)
+	require.Equal(t, int64(1), u.CompileErrors)
+	require.Equal(t, int64(2), u.Runs)
+	require.Equal(t, int64(1), u.RunErrors)
+	require.Equal(t, int64(0), u.QuotaExceeded)
+	require.True(t, u.Allocs > 0, "allocs: %d", u.Allocs)
+	require.True(t, u.Instructions > 0, "instructions: %d", u.Instructions)
+}
+
+func TestEngine_Builtins(t *testing.T) {
+	e := tengo.NewEngine(nil)
+	require.NoError(t, e.AddBuiltin("config", map[string]any{"n": 1}))
+	require.NoError(t, e.AddBuiltin("upper", tengo.Func1(strings.ToUpper)))
+	require.Error(t, e.AddBuiltin("$out", 1))
+
+	c, err := e.NewScript("t", []byte(`
+config.n = 2
+out := [config.n, upper("a")]`)).CompileRun()
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(2), "A"}, c.Get("out").Value())
+
+	// each script gets its own copy of a builtin
+	c, err = e.NewScript("t", []byte(`out := config.n`)).CompileRun()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), c.Get("out").Value())
+
+	// builtins are read-only
+	_, err = e.NewScript("t", []byte(`config = 1`)).Compile()
+	require.Error(t, err)
+}
+
+func TestEngine_Concurrent(t *testing.T) {
+	mods := tengo.NewModuleMap()
+	mods.AddSourceModule("util", []byte(`
+export { double: func(x) { return x * 2 } }`))
+	e := tengo.NewEngine(mods)
+	e.SetDefaultQuota(tengo.Quota{MaxInstructions: 10000})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tenant := fmt.Sprintf("t%d", i%4)
+			src := fmt.Sprintf(`out := import("util").double(%d)`, i)
+			c, err := e.NewScript(tenant, []byte(src)).CompileRun()
+			if err == nil && c.Get("out").Int() != i*2 {
+				err = fmt.Errorf("unexpected result: %v", c.Get("out"))
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	stats := e.Stats()
+	require.Equal(t, int64(20),
+		stats.ModuleCacheHits+stats.ModuleCacheMisses)
+	for _, tenant := range e.Tenants() {
+		require.Equal(t, int64(5), e.Usage(tenant).Runs)
+	}
+}
@@ -12,6 +12,9 @@ var (
 	// ErrObjectAllocLimit is an objects allocation limit error.
 	ErrObjectAllocLimit = errors.New("object allocation limit exceeded")
 
+	// ErrInstructionLimit is an instruction limit error.
+	ErrInstructionLimit = errors.New("instruction limit exceeded")
+
 	// ErrIndexOutOfBounds is an error where a given index is out of the
 	// bounds.
 	ErrIndexOutOfBounds = errors.New("index out of bounds")
@@ -39,6 +42,14 @@ var (
 	// exceeds the limit.
 	ErrStringLimit = errors.New("exceeding string size limit")
 
+	// ErrBigIntLimit represents an error where the size of bigint value
+	// exceeds the limit.
+	ErrBigIntLimit = errors.New("exceeding bigint size limit")
+
+	// ErrDecimalLimit represents an error where the size of decimal value
+	// exceeds the limit.
+	ErrDecimalLimit = errors.New("exceeding decimal size limit")
+
 	// ErrNotIndexable is an error where an Object is not indexable.
 	ErrNotIndexable = errors.New("not indexable")
 
@@ -52,6 +63,17 @@ var (
 
 	// ErrInvalidRangeStep is an error where the step parameter is less than or equal to 0 when using builtin range function.
 	ErrInvalidRangeStep = errors.New("range step must be greater than 0")
+
+	// ErrDivisionByZero is an error where a number is divided by zero.
+	ErrDivisionByZero = errors.New("division by zero")
+
+	// ErrIntOverflow is an error where an int operation overflows while
+	// the VM checks int overflows.
+	ErrIntOverflow = errors.New("integer overflow")
+
+	// ErrNotHashable is an error where an object that cannot be a set
+	// element is added to a set.
+	ErrNotHashable = errors.New("not hashable")
 )
 
 // ErrInvalidArgumentType represents an invalid argument value type error.
@@ -1,35 +1,215 @@
 package tengo
 
 import (
+	"container/list"
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
+
+	"github.com/shelepuginivan/tengo/parser"
+	"github.com/shelepuginivan/tengo/token"
 )
 
+// EvalCacheSize is the maximum number of compiled expressions that Eval
+// keeps to reuse them. Set it to 0 to disable the cache. Note this limit
+// applies to all Eval calls in the process.
+var EvalCacheSize = 256
+
+var evalCache = &exprCache{
+	ll:    list.New(),
+	items: make(map[string]*list.Element),
+}
+
 // Eval compiles and executes given expr with params, and returns an
 // evaluated value. expr must be an expression. Otherwise it will fail to
 // compile. Expression must not use or define variable "__res__" as it's
 // reserved for the internal usage.
+//
+// The compiled expression is cached, so evaluating the same expression with
+// the same parameter names again does not compile it again. Use CompileExpr
+// to keep a compiled expression without the cache.
+//
+// An error compiling expr has the prefix "script compile:", and an error
+// evaluating it has the prefix "script run:". Positions in the errors are
+// positions in expr.
 func Eval(
 	ctx context.Context,
 	expr string,
 	params map[string]any,
 ) (any, error) {
-	expr = strings.TrimSpace(expr)
-	if expr == "" {
+	return evalCached(ctx, "", expr, params, CompileExpr)
+}
+
+// evalCached evaluates expr with params, compiled by compile or taken from
+// the cache. mode is a part of the cache key that tells how compile compiles
+// the expression.
+func evalCached(
+	ctx context.Context,
+	mode string,
+	expr string,
+	params map[string]any,
+	compile func(expr string, paramNames []string) (*Expr, error),
+) (any, error) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	key := fmt.Sprintf("%s%q%q", mode, names, expr)
+	e := evalCache.get(key)
+	if e == nil {
+		var err error
+		if e, err = compile(expr, names); err != nil {
+			return nil, err
+		}
+		evalCache.add(key, e)
+	}
+	return e.Eval(ctx, params)
+}
+
+// assignResult replaces the expression statement of file with an assignment
+// of the expression to the variable name. The expression is parsed as it is
+// written, so the positions in errors point into it.
+func assignResult(
+	srcFile *parser.SourceFile,
+	file *parser.File,
+	name string,
+) error {
+	var assigned bool
+	for i, stmt := range file.Stmts {
+		if _, ok := stmt.(*parser.EmptyStmt); ok {
+			continue
+		}
+		exprStmt, ok := stmt.(*parser.ExprStmt)
+		if !ok || assigned {
+			return &CompilerError{
+				FileSet: srcFile.Set(),
+				Node:    stmt,
+				Err:     fmt.Errorf("expression expected"),
+			}
+		}
+		file.Stmts[i] = &parser.AssignStmt{
+			LHS: []parser.Expr{
+				&parser.Ident{Name: name, NamePos: stmt.Pos()},
+			},
+			RHS:      []parser.Expr{exprStmt.Expr},
+			Token:    token.Define,
+			TokenPos: stmt.Pos(),
+		}
+		assigned = true
+	}
+	if !assigned {
+		return fmt.Errorf("empty expression")
+	}
+	return nil
+}
+
+// Expr is a compiled expression that can be evaluated many times with
+// different parameters. Expr is safe for concurrent use.
+type Expr struct {
+	compiled *Compiled
+	pool     sync.Pool
+}
+
+// CompileExpr compiles given expr with parameters paramNames. expr must be an
+// expression, and it can only use the parameters and the builtin functions.
+// Expression must not use or define variable "__res__" as it's reserved for
+// the internal usage.
+func CompileExpr(expr string, paramNames []string) (*Expr, error) {
+	if strings.TrimSpace(expr) == "" {
 		return nil, fmt.Errorf("empty expression")
 	}
 
-	script := NewScript(fmt.Appendf(nil, "__res__ := (%s)", expr))
-	for pk, pv := range params {
-		err := script.Add(pk, pv)
-		if err != nil {
+	script := NewScript([]byte(expr))
+	script.resultVar = "__res__"
+	for _, name := range paramNames {
+		if err := script.Add(name, nil); err != nil {
 			return nil, fmt.Errorf("script add: %w", err)
 		}
 	}
-	compiled, err := script.CompileRunContext(ctx)
+	compiled, err := script.Compile()
 	if err != nil {
+		return nil, fmt.Errorf("script compile: %w", err)
+	}
+
+	e := &Expr{compiled: compiled}
+	e.pool.New = func() any {
+		return e.compiled.Clone()
+	}
+	return e, nil
+}
+
+// Eval evaluates the expression with params, and returns an evaluated value.
+// Parameters that are missing from params are undefined, and an error is
+// returned if params has a parameter that the expression was not compiled
+// with.
+func (e *Expr) Eval(ctx context.Context, params map[string]any) (any, error) {
+	res, err := e.EvalObject(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return ToInterface(res), nil
+}
+
+// EvalObject evaluates the expression with params like Eval, but returns the
+// evaluated value as an Object. Objects in params are used as they are, so
+// values that are evaluated many times can be converted once.
+func (e *Expr) EvalObject(
+	ctx context.Context,
+	params map[string]any,
+) (Object, error) {
+	compiled := e.pool.Get().(*Compiled)
+	defer e.pool.Put(compiled)
+
+	if err := compiled.RunWith(ctx, params); err != nil {
 		return nil, fmt.Errorf("script run: %w", err)
 	}
-	return compiled.Get("__res__").Value(), nil
+	return compiled.Get("__res__").Object(), nil
+}
+
+// exprCache is a least recently used cache of compiled expressions.
+type exprCache struct {
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type exprCacheEntry struct {
+	key  string
+	expr *Expr
+}
+
+func (c *exprCache) get(key string) *Expr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*exprCacheEntry).expr
+}
+
+func (c *exprCache) add(key string, e *Expr) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if EvalCacheSize <= 0 {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*exprCacheEntry).expr = e
+		return
+	}
+	c.items[key] = c.ll.PushFront(&exprCacheEntry{key: key, expr: e})
+	for c.ll.Len() > EvalCacheSize {
+		el := c.ll.Back()
+		c.ll.Remove(el)
+		delete(c.items, el.Value.(*exprCacheEntry).key)
+	}
 }
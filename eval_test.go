@@ -2,6 +2,9 @@ package tengo_test
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/shelepuginivan/tengo"
@@ -57,3 +60,123 @@ func TestEval(t *testing.T) {
 		"success",
 	)
 }
+
+func TestEval_Cache(t *testing.T) {
+	ctx := context.Background()
+	size := tengo.EvalCacheSize
+	defer func() { tengo.EvalCacheSize = size }()
+
+	for _, n := range []int{0, 1, 256} {
+		tengo.EvalCacheSize = n
+		for i := 0; i < 3; i++ {
+			res, err := tengo.Eval(ctx, `a + b`, map[string]any{"a": i, "b": 1})
+			require.NoError(t, err)
+			require.Equal(t, int64(i+1), res)
+
+			// same expression with other parameters
+			res, err = tengo.Eval(ctx, `a + b`, map[string]any{"a": i})
+			require.True(t, err != nil &&
+				strings.Contains(err.Error(), "unresolved reference 'b'"))
+			require.Nil(t, res)
+			res, err = tengo.Eval(ctx, `a + b`, map[string]any{
+				"a": "x",
+				"b": "y",
+			})
+			require.NoError(t, err)
+			require.Equal(t, "xy", res)
+		}
+	}
+}
+
+func TestCompileExpr(t *testing.T) {
+	ctx := context.Background()
+	e, err := tengo.CompileExpr(`a > 1 ? len(b) : "none"`,
+		[]string{"a", "b"})
+	require.NoError(t, err)
+
+	res, err := e.Eval(ctx, map[string]any{"a": 2, "b": []any{1, 2}})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), res)
+	res, err = e.Eval(ctx, map[string]any{"a": 1})
+	require.NoError(t, err)
+	require.Equal(t, "none", res)
+
+	// missing parameters are undefined
+	res, err = e.Eval(ctx, map[string]any{"a": 2})
+	require.True(t, err != nil &&
+		strings.Contains(err.Error(), "invalid type for argument 'first'"))
+	require.Nil(t, res)
+
+	_, err = e.Eval(ctx, map[string]any{"c": 1})
+	require.True(t, err != nil &&
+		strings.Contains(err.Error(), "'c' is not defined"))
+
+	// concurrent evaluation
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res, err := e.Eval(ctx, map[string]any{
+				"a": i,
+				"b": make([]any, i),
+			})
+			switch {
+			case err != nil:
+				errs <- err
+			case i > 1 && res != int64(i), i <= 1 && res != "none":
+				errs <- fmt.Errorf("unexpected result for %d: %v", i, res)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// objects are passed and returned as they are
+	arr := &tengo.Array{Value: []tengo.Object{&tengo.Int{Value: 1}}}
+	obj, err := e.EvalObject(ctx, map[string]any{"a": 2, "b": arr})
+	require.NoError(t, err)
+	require.Equal(t, &tengo.Int{Value: 1}, obj)
+	obj, err = e.EvalObject(ctx, map[string]any{"a": 1})
+	require.NoError(t, err)
+	require.Equal(t, &tengo.String{Value: "none"}, obj)
+
+	_, err = tengo.CompileExpr(` `, nil)
+	require.Error(t, err)
+	_, err = tengo.CompileExpr(`a +`, []string{"a"})
+	require.Error(t, err)
+	_, err = tengo.CompileExpr(`a`, nil)
+	require.True(t, err != nil &&
+		strings.Contains(err.Error(), "unresolved reference 'a'"))
+}
+
+func TestEval_Errors(t *testing.T) {
+	ctx := context.Background()
+	expectErr := func(expr string, params map[string]any, expected string) {
+		_, err := tengo.Eval(ctx, expr, params)
+		require.Error(t, err)
+		require.Equal(t, expected, err.Error())
+	}
+
+	// compile errors point into the expression as written
+	expectErr(`x +`, nil, "script compile: Parse Error: "+
+		"expected operand, found 'EOF'\n\tat (main):1:4")
+	expectErr("\n  x +", nil, "script compile: Parse Error: "+
+		"expected operand, found 'EOF'\n\tat (main):2:6")
+	expectErr(`a + b`, map[string]any{"a": 1}, "script compile: "+
+		"Compile Error: unresolved reference 'b'\n\tat (main):1:5")
+	expectErr(`x := 1`, nil, "script compile: "+
+		"Compile Error: expression expected\n\tat (main):1:1")
+	expectErr(`1; 2`, nil, "script compile: "+
+		"Compile Error: expression expected\n\tat (main):1:4")
+
+	// runtime errors too
+	expectErr(`a + {}`, map[string]any{"a": 1}, "script run: "+
+		"Runtime Error: invalid operation: int + map\n\tat (main):1:1")
+	expectErr(`[1, a + {}]`, map[string]any{"a": 1}, "script run: "+
+		"Runtime Error: invalid operation: int + map\n\tat (main):1:5")
+}
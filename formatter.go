@@ -1,7 +1,10 @@
 package tengo
 
 import (
+	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 	"sync"
 	"unicode/utf8"
 )
@@ -67,6 +70,35 @@ type formatter struct {
 	intbuf [68]byte
 }
 
+// goFormat returns the format string of package fmt for verb with the
+// current flags, width and precision.
+func (f *formatter) goFormat(verb rune) string {
+	format := []byte{'%'}
+	if f.plus {
+		format = append(format, '+')
+	}
+	if f.minus {
+		format = append(format, '-')
+	}
+	if f.sharp {
+		format = append(format, '#')
+	}
+	if f.space {
+		format = append(format, ' ')
+	}
+	if f.zero {
+		format = append(format, '0')
+	}
+	if f.widPresent {
+		format = strconv.AppendInt(format, int64(f.wid), 10)
+	}
+	if f.precPresent {
+		format = append(format, '.')
+		format = strconv.AppendInt(format, int64(f.prec), 10)
+	}
+	return string(utf8.AppendRune(format, verb))
+}
+
 func (f *formatter) clearFlags() {
 	f.fmtFlags = fmtFlags{}
 }
@@ -616,6 +648,33 @@ func (f *formatter) fmtFloat(v float64, size int, verb rune, prec int) {
 	f.pad(num[1:])
 }
 
+// fmtDecimal formats the digits of a decimal number, honoring the sign
+// and padding flags the same way fmtFloat does.
+func (f *formatter) fmtDecimal(s string) {
+	num := make([]byte, 0, len(s)+2)
+	if s[0] != '-' {
+		num = append(num, '+')
+	}
+	num = append(num, s...)
+	if f.sharp && strings.IndexByte(s, '.') < 0 {
+		num = append(num, '.')
+	}
+	if f.space && num[0] == '+' && !f.plus {
+		num[0] = ' '
+	}
+	if f.plus || num[0] != '+' {
+		if f.zero && f.widPresent && f.wid > len(num) {
+			f.buf.WriteSingleByte(num[0])
+			f.writePadding(f.wid - len(num))
+			f.buf.Write(num[1:])
+			return
+		}
+		f.pad(num)
+		return
+	}
+	f.pad(num[1:])
+}
+
 // Use simple []byte instead of bytes.Buffer to avoid large dependency.
 type fmtbuf []byte
 
@@ -854,6 +913,39 @@ func (p *pp) fmtInteger(v uint64, isSigned bool, verb rune) {
 	}
 }
 
+// fmtBigInt formats an arbitrary-precision integer. big.Int implements
+// fmt.Formatter, so the verb is handed over to package fmt together with
+// the current flags, width and precision.
+func (p *pp) fmtBigInt(v *big.Int, verb rune) {
+	switch verb {
+	case 'd', 'b', 'o', 'O', 'x', 'X':
+		_, _ = p.WriteString(fmt.Sprintf(p.fmt.goFormat(verb), v))
+	default:
+		p.badVerb(verb)
+	}
+}
+
+// fmtDecimal formats an exact decimal number. %f prints every digit unless
+// a precision is given, in which case the value is rounded using
+// DecimalRounding. %e and %g are handed over to big.Float.
+func (p *pp) fmtDecimal(v *Decimal, verb rune) {
+	switch verb {
+	case 'f', 'F':
+		if p.fmt.precPresent {
+			v = v.Round(int32(p.fmt.prec), DecimalRounding)
+		}
+		p.fmt.fmtDecimal(v.String())
+	case 'e', 'E', 'g', 'G':
+		prec := uint(len(v.Value.String()))*4 + 64
+		f, _ := new(big.Float).SetPrec(prec).SetString(v.String())
+		_, _ = p.WriteString(fmt.Sprintf(p.fmt.goFormat(verb), f))
+	case 's', 'q':
+		p.fmtString(v.String(), verb)
+	default:
+		p.badVerb(verb)
+	}
+}
+
 // fmtFloat formats a float. The default precision for each verb
 // is specified as last argument in the call to fmt_float.
 func (p *pp) fmtFloat(v float64, size int, verb rune) {
@@ -957,6 +1049,10 @@ func (p *pp) printArg(arg Object, verb rune) {
 		p.fmtFloat(f.Value, 64, verb)
 	case *Int:
 		p.fmtInteger(uint64(f.Value), signed, verb)
+	case *BigInt:
+		p.fmtBigInt(f.Value, verb)
+	case *Decimal:
+		p.fmtDecimal(f, verb)
 	case *String:
 		p.fmtString(f.Value, verb)
 	case *Bytes:
@@ -1,5 +1,7 @@
 package tengo
 
+import "github.com/shelepuginivan/tengo/parser"
+
 // Iterator represents an iterator for underlying data type.
 type Iterator interface {
 	Object
@@ -207,3 +209,111 @@ func (i *StringIterator) Key() Object {
 func (i *StringIterator) Value() Object {
 	return &Char{Value: i.v[i.i-1]}
 }
+
+// Generator is the iterator returned by a call to a function containing
+// yield. It holds the suspended call frame of the function, which is
+// resumed each time the next value is requested.
+type Generator struct {
+	ObjectImpl
+	fn       *CompiledFunction
+	freeVars []*ObjectPtr
+	locals   []Object
+	defers   []*deferredCall
+	ip       int
+	done     bool
+	n        int
+	value    Object
+	vm       *VM // VM that created the generator
+	runner   *VM // VM used when iterated from Go code
+	err      error
+}
+
+// generatorNextFn is the main function of the VM running a generator
+// iterated from Go code.
+var generatorNextFn = &CompiledFunction{
+	Instructions: []byte{
+		parser.OpGetLocal, 0,
+		parser.OpIteratorNext,
+		parser.OpSuspend,
+	},
+}
+
+// TypeName returns the name of the type.
+func (g *Generator) TypeName() string {
+	return "generator"
+}
+
+func (g *Generator) String() string {
+	return "<generator>"
+}
+
+// Equals returns true if the value of the type is equal to the value of
+// another object.
+func (g *Generator) Equals(x Object) bool {
+	return g == x
+}
+
+// Copy returns the generator itself: its frame can only be resumed once.
+func (g *Generator) Copy() Object {
+	return g
+}
+
+// CanIterate returns whether the Object can be Iterated.
+func (g *Generator) CanIterate() bool {
+	return true
+}
+
+// Iterate returns the generator itself.
+func (g *Generator) Iterate() Iterator {
+	return g
+}
+
+// Next resumes the generator until it yields the next value or returns.
+// The VM resumes generators in place, Next is only used when a generator
+// is iterated from Go code. It runs with the context and limits of the VM
+// that created the generator, and stops when that VM is aborted. A runtime
+// error stops the iteration and is reported by Err.
+func (g *Generator) Next() bool {
+	if g.done {
+		return false
+	}
+	if g.runner == nil {
+		g.runner = NewVM(&Bytecode{
+			FileSet:      g.vm.fileSet,
+			MainFunction: generatorNextFn,
+			Constants:    g.vm.constants,
+		}, g.vm.globals, g.vm.maxAllocs)
+		g.runner.EnableIntOverflowCheck(g.vm.intOverflowCheck)
+		if g.vm.decimalQuo {
+			g.runner.SetDecimalPrecision(g.vm.decimalPrecision,
+				g.vm.decimalRounding)
+		}
+	}
+	g.runner.stack[0] = g
+	if err := g.runner.runNested(g.vm); err != nil {
+		g.err = err
+		g.done = true
+		return false
+	}
+	if g.runner.aborted() {
+		// the frame of the generator is lost when its run is aborted
+		g.done = true
+		return false
+	}
+	return !g.runner.stack[0].IsFalsy()
+}
+
+// Key returns the index of the last yielded value.
+func (g *Generator) Key() Object {
+	return &Int{Value: int64(g.n - 1)}
+}
+
+// Value returns the last yielded value.
+func (g *Generator) Value() Object {
+	return g.value
+}
+
+// Err returns the runtime error that stopped the iteration from Go code.
+func (g *Generator) Err() error {
+	return g.err
+}
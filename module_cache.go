@@ -0,0 +1,172 @@
+package tengo
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+
+	"github.com/shelepuginivan/tengo/parser"
+)
+
+// moduleCache is a cache of compiled source modules that is shared by many
+// compilers, so that a module imported by many scripts is compiled once.
+type moduleCache struct {
+	mu      sync.RWMutex
+	modules map[moduleCacheKey]*sharedModule
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+// moduleCacheKey identifies a compiled module: a module can import other
+// modules relative to the import directory of the script that imports it.
+type moduleCacheKey struct {
+	path       string
+	importDir  string
+	fileImport bool
+}
+
+// sharedModule is a module compiled with its own constants and source file
+// set. Its functions refer to the constants by their indexes in constants,
+// and its source map positions are relative to files.
+type sharedModule struct {
+	src       []byte
+	fn        *CompiledFunction
+	constants []Object
+	files     []*parser.SourceFile
+}
+
+func newModuleCache() *moduleCache {
+	return &moduleCache{modules: make(map[moduleCacheKey]*sharedModule)}
+}
+
+// get returns the module compiled from src, or nil if it is not cached or
+// was compiled from another source.
+func (mc *moduleCache) get(key moduleCacheKey, src []byte) *sharedModule {
+	mc.mu.RLock()
+	mod := mc.modules[key]
+	mc.mu.RUnlock()
+	if mod == nil || !bytes.Equal(mod.src, src) {
+		mc.misses.Add(1)
+		return nil
+	}
+	mc.hits.Add(1)
+	return mod
+}
+
+func (mc *moduleCache) store(key moduleCacheKey, mod *sharedModule) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.modules[key] = mod
+}
+
+// compileSharedModule returns the module compiled from src, compiling it if
+// it is not in the shared cache yet, with its constants added to the
+// constants of c.
+func (c *Compiler) compileSharedModule(
+	node parser.Node,
+	modulePath string,
+	src []byte,
+	isFile bool,
+) (*CompiledFunction, error) {
+	key := moduleCacheKey{
+		path:       modulePath,
+		importDir:  c.importDir,
+		fileImport: c.allowFileImport,
+	}
+	mod := c.moduleCache.get(key, src)
+	if mod == nil {
+		fileSet := parser.NewFileSet()
+		modFile := fileSet.AddFile(modulePath, -1, len(src))
+		p := parser.NewParser(modFile, src, nil)
+		file, err := p.ParseFile()
+		if err != nil {
+			return nil, err
+		}
+
+		// inherit builtin functions
+		symbolTable := NewSymbolTable()
+		for _, sym := range c.symbolTable.BuiltinSymbols() {
+			symbolTable.DefineBuiltin(sym.Index, sym.Name)
+		}
+
+		// no global scope for the module
+		symbolTable = symbolTable.Fork(false)
+
+		// compile module with its own constants
+		moduleCompiler := c.fork(modFile, modulePath, symbolTable, isFile)
+		moduleCompiler.parent = nil
+		moduleCompiler.importer = c
+		if err := moduleCompiler.Compile(file); err != nil {
+			return nil, err
+		}
+
+		// code optimization
+		moduleCompiler.optimizeFunc(node)
+		compiledFunc := moduleCompiler.Bytecode().MainFunction
+		compiledFunc.NumLocals = symbolTable.MaxSymbols()
+
+		mod = &sharedModule{
+			src:       src,
+			fn:        compiledFunc,
+			constants: moduleCompiler.constants,
+			files:     fileSet.Files,
+		}
+		c.moduleCache.store(key, mod)
+	}
+
+	compiledFunc := mod.relocate(c)
+	c.storeCompiledModule(modulePath, compiledFunc)
+	return compiledFunc, nil
+}
+
+// relocate adds the constants and the source files of the module to the
+// compiler c, and returns the module function that refers to them.
+func (m *sharedModule) relocate(c *Compiler) *CompiledFunction {
+	// the files are added in the same order and with the same sizes, so
+	// all their positions move by the same offset
+	fileSet := c.file.Set()
+	offset := parser.Pos(fileSet.Base - m.files[0].Base)
+	for _, f := range m.files {
+		file := fileSet.AddFile(f.Name, -1, f.Size)
+		file.Lines = append([]int(nil), f.Lines...)
+	}
+
+	// the constants are appended after the constants of c
+	n := c.numConstants()
+	indexMap := make(map[int]int, len(m.constants))
+	for i := range m.constants {
+		indexMap[i] = n + i
+	}
+	for _, o := range m.constants {
+		if fn, ok := o.(*CompiledFunction); ok {
+			o = relocateFunc(fn, indexMap, offset)
+		}
+		c.addConstant(o)
+	}
+	return relocateFunc(m.fn, indexMap, offset)
+}
+
+// relocateFunc returns a copy of fn with its constant indexes mapped by
+// indexMap and its source positions moved by offset.
+func relocateFunc(
+	fn *CompiledFunction,
+	indexMap map[int]int,
+	offset parser.Pos,
+) *CompiledFunction {
+	res := fn.Copy().(*CompiledFunction)
+	updateConstIndexes(res.Instructions, indexMap)
+	res.SourceMap = make(map[int]parser.Pos, len(fn.SourceMap))
+	for ip, pos := range fn.SourceMap {
+		res.SourceMap[ip] = pos + offset
+	}
+	return res
+}
+
+// numConstants returns the number of constants of the compiler.
+func (c *Compiler) numConstants() int {
+	if c.parent != nil {
+		return c.parent.numConstants()
+	}
+	return len(c.constants)
+}
@@ -2,8 +2,10 @@ package tengo
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
 	"strings"
 
@@ -78,6 +80,17 @@ type Object interface {
 	CanCall() bool
 }
 
+// Container is an optional interface for objects that support the
+// membership operators 'in' and 'not in'.
+type Container interface {
+	Object
+
+	// Contains should return true if x is an element of the object. If x
+	// cannot be an element of the object, ErrInvalidOperator should be
+	// returned as error.
+	Contains(x Object) (bool, error)
+}
+
 // ObjectImpl represents a default Object Implementation. To defined a new
 // value type, one can embed ObjectImpl in their type declarations to avoid
 // implementing all non-significant methods. TypeName() and String() methods
@@ -218,7 +231,8 @@ func (o *Array) Equals(x Object) bool {
 	return true
 }
 
-// IndexGet returns an element at a given index.
+// IndexGet returns an element at a given index. Negative indexes
+// count from the end.
 func (o *Array) IndexGet(index Object) (res Object, err error) {
 	intIdx, ok := index.(*Int)
 	if !ok {
@@ -226,6 +240,9 @@ func (o *Array) IndexGet(index Object) (res Object, err error) {
 		return
 	}
 	idxVal := int(intIdx.Value)
+	if idxVal < 0 {
+		idxVal += len(o.Value)
+	}
 	if idxVal < 0 || idxVal >= len(o.Value) {
 		res = UndefinedValue
 		return
@@ -234,13 +251,17 @@ func (o *Array) IndexGet(index Object) (res Object, err error) {
 	return
 }
 
-// IndexSet sets an element at a given index.
+// IndexSet sets an element at a given index. Negative indexes count from
+// the end.
 func (o *Array) IndexSet(index, value Object) (err error) {
 	intIdx, ok := ToInt(index)
 	if !ok {
 		err = ErrInvalidIndexType
 		return
 	}
+	if intIdx < 0 {
+		intIdx += len(o.Value)
+	}
 	if intIdx < 0 || intIdx >= len(o.Value) {
 		err = ErrIndexOutOfBounds
 		return
@@ -262,6 +283,139 @@ func (o *Array) CanIterate() bool {
 	return true
 }
 
+// Contains returns true if the array has an element equal to x.
+func (o *Array) Contains(x Object) (bool, error) {
+	return containsElement(o.Value, x), nil
+}
+
+// BigInt represents an arbitrary-precision integer value.
+type BigInt struct {
+	ObjectImpl
+	Value *big.Int
+}
+
+func (o *BigInt) String() string {
+	return o.Value.String()
+}
+
+// TypeName returns the name of the type.
+func (o *BigInt) TypeName() string {
+	return "bigint"
+}
+
+// BinaryOp returns another object that is the result of a given binary
+// operator and a right-hand side object. Int operands are promoted to
+// BigInt, Float operands make the result a Float.
+func (o *BigInt) BinaryOp(op token.Token, rhs Object) (Object, error) {
+	var y *big.Int
+	switch rhs := rhs.(type) {
+	case *BigInt:
+		y = rhs.Value
+	case *Int:
+		y = big.NewInt(rhs.Value)
+	case *Float:
+		x, _ := new(big.Float).SetInt(o.Value).Float64()
+		return (&Float{Value: x}).BinaryOp(op, rhs)
+	case *Decimal:
+		return (&Decimal{Value: o.Value}).BinaryOp(op, rhs)
+	default:
+		return nil, ErrInvalidOperator
+	}
+
+	r := new(big.Int)
+	switch op {
+	case token.Add:
+		r.Add(o.Value, y)
+	case token.Sub:
+		r.Sub(o.Value, y)
+	case token.Mul:
+		r.Mul(o.Value, y)
+	case token.Quo:
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		r.Quo(o.Value, y)
+	case token.Rem:
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		r.Rem(o.Value, y)
+	case token.And:
+		r.And(o.Value, y)
+	case token.Or:
+		r.Or(o.Value, y)
+	case token.Xor:
+		r.Xor(o.Value, y)
+	case token.AndNot:
+		r.AndNot(o.Value, y)
+	case token.Pow:
+		if y.Sign() < 0 {
+			x, _ := new(big.Float).SetInt(o.Value).Float64()
+			e, _ := new(big.Float).SetInt(y).Float64()
+			return &Float{Value: math.Pow(x, e)}, nil
+		}
+		if !y.IsUint64() || y.Uint64() > math.MaxUint32 {
+			return nil, ErrInvalidOperator
+		}
+		// x**y has more than (bits(x)-1)*y bits
+		if b := uint64(o.Value.BitLen()); b > 1 &&
+			(b-1)*y.Uint64() > uint64(MaxBigIntBits) {
+			return nil, ErrBigIntLimit
+		}
+		r.Exp(o.Value, y, nil)
+	case token.Shl, token.Shr:
+		if y.Sign() < 0 || !y.IsUint64() || y.Uint64() > math.MaxUint32 {
+			return nil, ErrInvalidOperator
+		}
+		if op == token.Shl && o.Value.Sign() != 0 &&
+			uint64(o.Value.BitLen())+y.Uint64() > uint64(MaxBigIntBits) {
+			return nil, ErrBigIntLimit
+		}
+		if op == token.Shl {
+			r.Lsh(o.Value, uint(y.Uint64()))
+		} else {
+			r.Rsh(o.Value, uint(y.Uint64()))
+		}
+	case token.Less, token.Greater, token.LessEq, token.GreaterEq:
+		c := o.Value.Cmp(y)
+		if op == token.Less && c < 0 || op == token.Greater && c > 0 ||
+			op == token.LessEq && c <= 0 || op == token.GreaterEq && c >= 0 {
+			return TrueValue, nil
+		}
+		return FalseValue, nil
+	default:
+		return nil, ErrInvalidOperator
+	}
+	if r.BitLen() > MaxBigIntBits {
+		return nil, ErrBigIntLimit
+	}
+	return &BigInt{Value: r}, nil
+}
+
+// Copy returns a copy of the type.
+func (o *BigInt) Copy() Object {
+	return &BigInt{Value: new(big.Int).Set(o.Value)}
+}
+
+// IsFalsy returns true if the value of the type is falsy.
+func (o *BigInt) IsFalsy() bool {
+	return o.Value.Sign() == 0
+}
+
+// Equals returns true if the value of the type is equal to the value of
+// another object.
+func (o *BigInt) Equals(x Object) bool {
+	switch t := x.(type) {
+	case *BigInt:
+		return o.Value.Cmp(t.Value) == 0
+	case *Int:
+		return o.Value.IsInt64() && o.Value.Int64() == t.Value
+	case *Decimal:
+		return t.Equals(o)
+	}
+	return false
+}
+
 // Bool represents a boolean value.
 type Bool struct {
 	ObjectImpl
@@ -424,7 +578,8 @@ func (o *Bytes) Equals(x Object) bool {
 	return bytes.Equal(o.Value, t.Value)
 }
 
-// IndexGet returns an element (as Int) at a given index.
+// IndexGet returns an element (as Int) at a given index. Negative indexes
+// count from the end.
 func (o *Bytes) IndexGet(index Object) (res Object, err error) {
 	intIdx, ok := index.(*Int)
 	if !ok {
@@ -432,6 +587,9 @@ func (o *Bytes) IndexGet(index Object) (res Object, err error) {
 		return
 	}
 	idxVal := int(intIdx.Value)
+	if idxVal < 0 {
+		idxVal += len(o.Value)
+	}
 	if idxVal < 0 || idxVal >= len(o.Value) {
 		res = UndefinedValue
 		return
@@ -453,6 +611,20 @@ func (o *Bytes) CanIterate() bool {
 	return true
 }
 
+// Contains returns true if x is a subslice or a byte value of the bytes.
+func (o *Bytes) Contains(x Object) (bool, error) {
+	switch x := x.(type) {
+	case *Bytes:
+		return bytes.Contains(o.Value, x.Value), nil
+	case *Int:
+		if x.Value < 0 || x.Value > math.MaxUint8 {
+			return false, nil
+		}
+		return bytes.IndexByte(o.Value, byte(x.Value)) >= 0, nil
+	}
+	return false, ErrInvalidOperator
+}
+
 // Char represents a character value.
 type Char struct {
 	ObjectImpl
@@ -572,6 +744,8 @@ type CompiledFunction struct {
 	Instructions  []byte
 	NumLocals     int // number of local variables (including function parameters)
 	NumParameters int
+	NumDefaults   int      // number of parameters with default values
+	ParamNames    []string // parameter names, used to bind keyword arguments
 	VarArgs       bool
 	SourceMap     map[int]parser.Pos
 	Free          []*ObjectPtr
@@ -592,6 +766,8 @@ func (o *CompiledFunction) Copy() Object {
 		Instructions:  append([]byte{}, o.Instructions...),
 		NumLocals:     o.NumLocals,
 		NumParameters: o.NumParameters,
+		NumDefaults:   o.NumDefaults,
+		ParamNames:    o.ParamNames,
 		VarArgs:       o.VarArgs,
 		Free:          append([]*ObjectPtr{}, o.Free...), // DO NOT Copy() of elements; these are variable pointers
 	}
@@ -619,6 +795,57 @@ func (o *CompiledFunction) CanCall() bool {
 	return true
 }
 
+// wrongNumArgs returns the error for a call to the function with numArgs
+// arguments.
+func (o *CompiledFunction) wrongNumArgs(numArgs int) error {
+	if o.VarArgs {
+		return fmt.Errorf("wrong number of arguments: want>=%d, got=%d",
+			o.minArgs(), numArgs)
+	}
+	if o.NumDefaults > 0 {
+		return fmt.Errorf("wrong number of arguments: want=%d..%d, got=%d",
+			o.minArgs(), o.NumParameters, numArgs)
+	}
+	return fmt.Errorf("wrong number of arguments: want=%d, got=%d",
+		o.NumParameters, numArgs)
+}
+
+// deferredArgs checks the arguments of a deferred call to the function and
+// lays them out the way OpCall does on the stack: variadic arguments are
+// rolled up into an array and omitted defaults are left nil.
+func (o *CompiledFunction) deferredArgs(args []Object) ([]Object, error) {
+	numParams := o.NumParameters
+	if o.VarArgs {
+		numParams--
+		if len(args) >= numParams {
+			rest := append([]Object{}, args[numParams:]...)
+			args = append(args[:numParams:numParams], &Array{Value: rest})
+		}
+	}
+	if len(args) == o.NumParameters {
+		return args, nil
+	}
+	if o.NumDefaults == 0 || len(args) < o.minArgs() || len(args) > numParams {
+		return nil, o.wrongNumArgs(len(args))
+	}
+	for len(args) < numParams {
+		args = append(args, nil)
+	}
+	if o.VarArgs {
+		args = append(args, &Array{Value: []Object{}})
+	}
+	return args, nil
+}
+
+// minArgs returns the minimum number of arguments the function accepts.
+func (o *CompiledFunction) minArgs() int {
+	n := o.NumParameters - o.NumDefaults
+	if o.VarArgs {
+		n--
+	}
+	return n
+}
+
 // Error represents an error value.
 type Error struct {
 	ObjectImpl
@@ -708,6 +935,12 @@ func (o *Float) BinaryOp(op token.Token, rhs Object) (Object, error) {
 				return o, nil
 			}
 			return &Float{Value: r}, nil
+		case token.Pow:
+			r := math.Pow(o.Value, rhs.Value)
+			if r == o.Value {
+				return o, nil
+			}
+			return &Float{Value: r}, nil
 		case token.Less:
 			if o.Value < rhs.Value {
 				return TrueValue, nil
@@ -755,6 +988,12 @@ func (o *Float) BinaryOp(op token.Token, rhs Object) (Object, error) {
 				return o, nil
 			}
 			return &Float{Value: r}, nil
+		case token.Pow:
+			r := math.Pow(o.Value, float64(rhs.Value))
+			if r == o.Value {
+				return o, nil
+			}
+			return &Float{Value: r}, nil
 		case token.Less:
 			if o.Value < float64(rhs.Value) {
 				return TrueValue, nil
@@ -776,6 +1015,13 @@ func (o *Float) BinaryOp(op token.Token, rhs Object) (Object, error) {
 			}
 			return FalseValue, nil
 		}
+	case *BigInt:
+		y, _ := new(big.Float).SetInt(rhs.Value).Float64()
+		return o.BinaryOp(op, &Float{Value: y})
+	case *Decimal:
+		if x, ok := ToDecimal(o); ok {
+			return x.BinaryOp(op, rhs)
+		}
 	}
 	return nil, ErrInvalidOperator
 }
@@ -793,11 +1039,13 @@ func (o *Float) IsFalsy() bool {
 // Equals returns true if the value of the type is equal to the value of
 // another object.
 func (o *Float) Equals(x Object) bool {
-	t, ok := x.(*Float)
-	if !ok {
-		return false
+	switch t := x.(type) {
+	case *Float:
+		return o.Value == t.Value
+	case *Decimal:
+		return t.Equals(o)
 	}
-	return o.Value == t.Value
+	return false
 }
 
 // ImmutableArray represents an immutable array of objects.
@@ -868,7 +1116,8 @@ func (o *ImmutableArray) Equals(x Object) bool {
 	return true
 }
 
-// IndexGet returns an element at a given index.
+// IndexGet returns an element at a given index. Negative indexes
+// count from the end.
 func (o *ImmutableArray) IndexGet(index Object) (res Object, err error) {
 	intIdx, ok := index.(*Int)
 	if !ok {
@@ -876,6 +1125,9 @@ func (o *ImmutableArray) IndexGet(index Object) (res Object, err error) {
 		return
 	}
 	idxVal := int(intIdx.Value)
+	if idxVal < 0 {
+		idxVal += len(o.Value)
+	}
 	if idxVal < 0 || idxVal >= len(o.Value) {
 		res = UndefinedValue
 		return
@@ -897,6 +1149,11 @@ func (o *ImmutableArray) CanIterate() bool {
 	return true
 }
 
+// Contains returns true if the array has an element equal to x.
+func (o *ImmutableArray) Contains(x Object) (bool, error) {
+	return containsElement(o.Value, x), nil
+}
+
 // ImmutableMap represents an immutable map object.
 type ImmutableMap struct {
 	ObjectImpl
@@ -986,6 +1243,16 @@ func (o *ImmutableMap) CanIterate() bool {
 	return true
 }
 
+// Contains returns true if x is a key of the map.
+func (o *ImmutableMap) Contains(x Object) (bool, error) {
+	key, ok := ToString(x)
+	if !ok {
+		return false, nil
+	}
+	_, ok = o.Value[key]
+	return ok, nil
+}
+
 // Int represents an integer value.
 type Int struct {
 	ObjectImpl
@@ -1037,6 +1304,23 @@ func (o *Int) BinaryOp(op token.Token, rhs Object) (Object, error) {
 				return o, nil
 			}
 			return &Int{Value: r}, nil
+		case token.Pow:
+			if rhs.Value < 0 {
+				return &Float{
+					Value: math.Pow(float64(o.Value), float64(rhs.Value)),
+				}, nil
+			}
+			r, overflow := intPow(o.Value, rhs.Value)
+			if overflow {
+				// unlike the other operators, exact powers never wrap
+				// around: use bigint for large powers
+				return nil, fmt.Errorf("%w: %d ** %d",
+					ErrIntOverflow, o.Value, rhs.Value)
+			}
+			if r == o.Value {
+				return o, nil
+			}
+			return &Int{Value: r}, nil
 		case token.And:
 			r := o.Value & rhs.Value
 			if r == o.Value {
@@ -1104,6 +1388,8 @@ func (o *Int) BinaryOp(op token.Token, rhs Object) (Object, error) {
 			return &Float{Value: float64(o.Value) * rhs.Value}, nil
 		case token.Quo:
 			return &Float{Value: float64(o.Value) / rhs.Value}, nil
+		case token.Pow:
+			return &Float{Value: math.Pow(float64(o.Value), rhs.Value)}, nil
 		case token.Less:
 			if float64(o.Value) < rhs.Value {
 				return TrueValue, nil
@@ -1125,6 +1411,10 @@ func (o *Int) BinaryOp(op token.Token, rhs Object) (Object, error) {
 			}
 			return FalseValue, nil
 		}
+	case *BigInt:
+		return (&BigInt{Value: big.NewInt(o.Value)}).BinaryOp(op, rhs)
+	case *Decimal:
+		return (&Decimal{Value: big.NewInt(o.Value)}).BinaryOp(op, rhs)
 	case *Char:
 		switch op {
 		case token.Add:
@@ -1169,11 +1459,13 @@ func (o *Int) IsFalsy() bool {
 // Equals returns true if the value of the type is equal to the value of
 // another object.
 func (o *Int) Equals(x Object) bool {
-	t, ok := x.(*Int)
-	if !ok {
-		return false
+	switch t := x.(type) {
+	case *Int:
+		return o.Value == t.Value
+	case *BigInt, *Decimal:
+		return t.Equals(o)
 	}
-	return o.Value == t.Value
+	return false
 }
 
 // Map represents a map of objects.
@@ -1276,6 +1568,16 @@ func (o *Map) CanIterate() bool {
 	return true
 }
 
+// Contains returns true if x is a key of the map.
+func (o *Map) Contains(x Object) (bool, error) {
+	key, ok := ToString(x)
+	if !ok {
+		return false, nil
+	}
+	_, ok = o.Value[key]
+	return ok, nil
+}
+
 // ObjectPtr represents a free variable.
 type ObjectPtr struct {
 	ObjectImpl
@@ -1397,7 +1699,8 @@ func (o *String) Equals(x Object) bool {
 	return o.Value == t.Value
 }
 
-// IndexGet returns a character at a given index.
+// IndexGet returns a character at a given index. Negative indexes
+// count from the end.
 func (o *String) IndexGet(index Object) (res Object, err error) {
 	intIdx, ok := index.(*Int)
 	if !ok {
@@ -1408,6 +1711,9 @@ func (o *String) IndexGet(index Object) (res Object, err error) {
 	if o.runeStr == nil {
 		o.runeStr = []rune(o.Value)
 	}
+	if idxVal < 0 {
+		idxVal += len(o.runeStr)
+	}
 	if idxVal < 0 || idxVal >= len(o.runeStr) {
 		res = UndefinedValue
 		return
@@ -1432,6 +1738,17 @@ func (o *String) CanIterate() bool {
 	return true
 }
 
+// Contains returns true if x is a substring or a character of the string.
+func (o *String) Contains(x Object) (bool, error) {
+	switch x := x.(type) {
+	case *String:
+		return strings.Contains(o.Value, x.Value), nil
+	case *Char:
+		return strings.ContainsRune(o.Value, x.Value), nil
+	}
+	return false, ErrInvalidOperator
+}
+
 // Undefined represents an undefined value.
 type Undefined struct {
 	ObjectImpl
@@ -1497,6 +1814,10 @@ type UserFunction struct {
 	ObjectImpl
 	Name  string
 	Value CallableFunc
+
+	// ContextValue, if set, is called instead of Value when the function is
+	// called from a VM, with the context the VM runs with.
+	ContextValue ContextCallableFunc
 }
 
 // TypeName returns the name of the type.
@@ -1510,7 +1831,11 @@ func (o *UserFunction) String() string {
 
 // Copy returns a copy of the type.
 func (o *UserFunction) Copy() Object {
-	return &UserFunction{Value: o.Value, Name: o.Name}
+	return &UserFunction{
+		Value:        o.Value,
+		Name:         o.Name,
+		ContextValue: o.ContextValue,
+	}
 }
 
 // Equals returns true if the value of the type is equal to the value of
@@ -1524,7 +1849,28 @@ func (o *UserFunction) Call(args ...Object) (Object, error) {
 	return o.Value(args...)
 }
 
+// CallContext invokes a user function with a context. It calls ContextValue
+// if set, and Value otherwise.
+func (o *UserFunction) CallContext(
+	ctx context.Context,
+	args ...Object,
+) (Object, error) {
+	if o.ContextValue != nil {
+		return o.ContextValue(ctx, args...)
+	}
+	return o.Value(args...)
+}
+
 // CanCall returns whether the Object can be Called.
 func (o *UserFunction) CanCall() bool {
 	return true
 }
+
+func containsElement(elems []Object, x Object) bool {
+	for _, e := range elems {
+		if e.Equals(x) {
+			return true
+		}
+	}
+	return false
+}
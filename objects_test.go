@@ -1,6 +1,9 @@
 package tengo_test
 
 import (
+	"errors"
+	"math"
+	"math/big"
 	"testing"
 
 	"github.com/shelepuginivan/tengo"
@@ -41,6 +44,14 @@ func TestObject_TypeName(t *testing.T) {
 	require.Equal(t, "error", o.TypeName())
 	o = &tengo.Bytes{}
 	require.Equal(t, "bytes", o.TypeName())
+	o = &tengo.BigInt{}
+	require.Equal(t, "bigint", o.TypeName())
+	o = &tengo.Decimal{}
+	require.Equal(t, "decimal", o.TypeName())
+	o = &tengo.Set{}
+	require.Equal(t, "set", o.TypeName())
+	o = &tengo.ImmutableSet{}
+	require.Equal(t, "immutable-set", o.TypeName())
 }
 
 func TestObject_IsFalsy(t *testing.T) {
@@ -260,6 +271,14 @@ func TestFloat_BinaryOp(t *testing.T) {
 		}
 	}
 
+	// float ** float, float ** int
+	testBinaryOp(t, &tengo.Float{Value: 2.5}, token.Pow,
+		&tengo.Float{Value: 2}, &tengo.Float{Value: 6.25})
+	testBinaryOp(t, &tengo.Float{Value: 0.5}, token.Pow,
+		&tengo.Int{Value: -3}, &tengo.Float{Value: 8})
+	testBinaryOp(t, &tengo.Float{Value: 9}, token.Pow,
+		&tengo.Float{Value: 0.5}, &tengo.Float{Value: 3})
+
 	// float < float
 	for l := float64(-2); l <= 2.1; l += 0.4 {
 		for r := float64(-2); r <= 2.1; r += 0.4 {
@@ -405,6 +424,22 @@ func TestInt_BinaryOp(t *testing.T) {
 		}
 	}
 
+	// int ** int
+	for l := int64(-2); l <= 2; l++ {
+		expected := int64(1)
+		for r := int64(0); r <= 4; r++ {
+			testBinaryOp(t, &tengo.Int{Value: l}, token.Pow,
+				&tengo.Int{Value: r}, &tengo.Int{Value: expected})
+			expected *= l
+		}
+	}
+	testBinaryOp(t, &tengo.Int{Value: 2}, token.Pow,
+		&tengo.Int{Value: 62}, &tengo.Int{Value: 1 << 62})
+	testBinaryOp(t, &tengo.Int{Value: 2}, token.Pow,
+		&tengo.Int{Value: -2}, &tengo.Float{Value: 0.25})
+	testBinaryOp(t, &tengo.Int{Value: 4}, token.Pow,
+		&tengo.Float{Value: 0.5}, &tengo.Float{Value: 2})
+
 	// int & int
 	testBinaryOp(t,
 		&tengo.Int{Value: 0}, token.And, &tengo.Int{Value: 0},
@@ -683,6 +718,231 @@ func TestInt_BinaryOp(t *testing.T) {
 	}
 }
 
+func TestBigInt_BinaryOp(t *testing.T) {
+	bigInt := func(v int64) *tengo.BigInt {
+		return &tengo.BigInt{Value: big.NewInt(v)}
+	}
+
+	for l := int64(-3); l <= 3; l++ {
+		for r := int64(-3); r <= 3; r++ {
+			testBinaryOp(t, bigInt(l), token.Add, bigInt(r), bigInt(l+r))
+			testBinaryOp(t, bigInt(l), token.Sub, &tengo.Int{Value: r},
+				bigInt(l-r))
+			testBinaryOp(t, &tengo.Int{Value: l}, token.Mul, bigInt(r),
+				bigInt(l*r))
+			testBinaryOp(t, bigInt(l), token.And, bigInt(r), bigInt(l&r))
+			testBinaryOp(t, bigInt(l), token.Or, bigInt(r), bigInt(l|r))
+			testBinaryOp(t, bigInt(l), token.Xor, bigInt(r), bigInt(l^r))
+			testBinaryOp(t, bigInt(l), token.AndNot, bigInt(r),
+				bigInt(l&^r))
+			if r != 0 {
+				testBinaryOp(t, bigInt(l), token.Quo, bigInt(r),
+					bigInt(l/r))
+				testBinaryOp(t, bigInt(l), token.Rem, bigInt(r),
+					bigInt(l%r))
+			}
+			testBinaryOp(t, bigInt(l), token.Less, &tengo.Int{Value: r},
+				boolValue(l < r))
+			testBinaryOp(t, &tengo.Int{Value: l}, token.Greater, bigInt(r),
+				boolValue(l > r))
+			testBinaryOp(t, bigInt(l), token.LessEq, bigInt(r),
+				boolValue(l <= r))
+			testBinaryOp(t, bigInt(l), token.GreaterEq, bigInt(r),
+				boolValue(l >= r))
+			require.Equal(t, l == r, bigInt(l).Equals(&tengo.Int{Value: r}))
+			require.Equal(t, l == r, (&tengo.Int{Value: l}).Equals(bigInt(r)))
+		}
+	}
+
+	testBinaryOp(t, bigInt(2), token.Pow, &tengo.Int{Value: 100},
+		&tengo.BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 100)})
+	testBinaryOp(t, bigInt(2), token.Pow, bigInt(-1), &tengo.Float{Value: 0.5})
+
+	// no overflow
+	max := &tengo.Int{Value: math.MaxInt64}
+	expected, _ := new(big.Int).SetString("18446744073709551614", 10)
+	testBinaryOp(t, bigInt(2), token.Mul, max,
+		&tengo.BigInt{Value: expected})
+	testBinaryOp(t, bigInt(1), token.Shl, &tengo.Int{Value: 64},
+		&tengo.BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 64)})
+	testBinaryOp(t, bigInt(3), token.Add, &tengo.Float{Value: 0.5},
+		&tengo.Float{Value: 3.5})
+
+	_, err := bigInt(1).BinaryOp(token.Quo, bigInt(0))
+	require.Equal(t, tengo.ErrDivisionByZero, err)
+	_, err = bigInt(1).BinaryOp(token.Shl, bigInt(-1))
+	require.Equal(t, tengo.ErrInvalidOperator, err)
+	_, err = bigInt(1).BinaryOp(token.Add, &tengo.String{Value: "1"})
+	require.Equal(t, tengo.ErrInvalidOperator, err)
+}
+
+func TestDecimal_BinaryOp(t *testing.T) {
+	dec := func(s string) *tengo.Decimal {
+		d, err := tengo.ParseDecimal(s)
+		require.NoError(t, err)
+		return d
+	}
+	testDecimalOp := func(lhs tengo.Object, op token.Token, rhs tengo.Object,
+		expected string) {
+		t.Helper()
+		actual, err := lhs.BinaryOp(op, rhs)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual.String())
+	}
+
+	testDecimalOp(dec("0.1"), token.Add, dec("0.2"), "0.3")
+	testDecimalOp(dec("12.30"), token.Sub, dec("0.005"), "12.295")
+	testDecimalOp(dec("1.5"), token.Mul, dec("1.5"), "2.25")
+	testDecimalOp(dec("1"), token.Quo, dec("4"), "0.25")
+	testDecimalOp(dec("2"), token.Quo, dec("3"), "0.6666666666666667")
+	testDecimalOp(dec("-2"), token.Quo, dec("3"), "-0.6666666666666667")
+	testDecimalOp(dec("10.00"), token.Quo, dec("0.5"), "20")
+	testDecimalOp(dec("7.5"), token.Rem, dec("2"), "1.5")
+	testDecimalOp(dec("1.1"), token.Pow, &tengo.Int{Value: 2}, "1.21")
+	testDecimalOp(dec("-0.5"), token.Pow, dec("3.0"), "-0.125")
+	testDecimalOp(&tengo.Int{Value: 2}, token.Pow, dec("0"), "1")
+	testDecimalOp(dec("1.25"), token.Add, &tengo.Int{Value: 2}, "3.25")
+	testDecimalOp(&tengo.Int{Value: 2}, token.Sub, dec("1.25"), "0.75")
+	testDecimalOp(&tengo.BigInt{Value: big.NewInt(3)}, token.Mul,
+		dec("0.5"), "1.5")
+	testDecimalOp(&tengo.Float{Value: 0.1}, token.Add, dec("0.2"), "0.3")
+	testDecimalOp(dec("0.2"), token.Add, &tengo.Float{Value: 0.1}, "0.3")
+
+	testBinaryOp(t, dec("1.50"), token.Less, dec("1.6"), tengo.TrueValue)
+	testBinaryOp(t, dec("1.50"), token.GreaterEq, dec("1.5"), tengo.TrueValue)
+	testBinaryOp(t, dec("-1"), token.Greater, &tengo.Int{Value: 0},
+		tengo.FalseValue)
+	testBinaryOp(t, &tengo.Int{Value: 2}, token.LessEq, dec("2.0"),
+		tengo.TrueValue)
+
+	require.True(t, dec("1.50").Equals(dec("1.5")))
+	require.True(t, dec("3.00").Equals(&tengo.Int{Value: 3}))
+	require.True(t, (&tengo.Int{Value: 3}).Equals(dec("3.0")))
+	require.False(t, dec("3.01").Equals(&tengo.Int{Value: 3}))
+
+	precision := tengo.DecimalPrecision
+	rounding := tengo.DecimalRounding
+	defer func() {
+		tengo.DecimalPrecision = precision
+		tengo.DecimalRounding = rounding
+	}()
+	tengo.DecimalPrecision = 2
+	testDecimalOp(dec("2"), token.Quo, dec("3"), "0.67")
+	tengo.DecimalRounding = tengo.RoundDown
+	testDecimalOp(dec("2"), token.Quo, dec("3"), "0.66")
+
+	_, err := dec("1").BinaryOp(token.Quo, dec("0.0"))
+	require.Equal(t, tengo.ErrDivisionByZero, err)
+	_, err = dec("1").BinaryOp(token.Add, &tengo.String{Value: "1"})
+	require.Equal(t, tengo.ErrInvalidOperator, err)
+	_, err = dec("1").BinaryOp(token.Add, &tengo.Float{Value: math.Inf(1)})
+	require.Equal(t, tengo.ErrInvalidOperator, err)
+}
+
+func TestDecimal_Round(t *testing.T) {
+	modes := []tengo.RoundingMode{
+		tengo.RoundHalfEven, tengo.RoundHalfUp, tengo.RoundHalfDown,
+		tengo.RoundUp, tengo.RoundDown, tengo.RoundCeiling, tengo.RoundFloor,
+	}
+	for _, tc := range []struct {
+		value    string
+		expected []string
+	}{
+		{"2.5", []string{"2", "3", "2", "3", "2", "3", "2"}},
+		{"3.5", []string{"4", "4", "3", "4", "3", "4", "3"}},
+		{"-2.5", []string{"-2", "-3", "-2", "-3", "-2", "-2", "-3"}},
+		{"2.51", []string{"3", "3", "3", "3", "2", "3", "2"}},
+		{"-0.4", []string{"0", "0", "0", "-1", "0", "0", "-1"}},
+	} {
+		d, err := tengo.ParseDecimal(tc.value)
+		require.NoError(t, err)
+		for i, mode := range modes {
+			require.Equal(t, tc.expected[i], d.Round(0, mode).String(),
+				tc.value)
+		}
+	}
+
+	d, err := tengo.ParseDecimal("1.5")
+	require.NoError(t, err)
+	require.Equal(t, "1.500", d.Round(3, tengo.RoundHalfEven).String())
+}
+
+func TestParseDecimal(t *testing.T) {
+	for in, expected := range map[string]string{
+		"12.34":  "12.34",
+		"-0.5":   "-0.5",
+		"+.25":   "0.25",
+		"1.":     "1",
+		"007.10": "7.10",
+		"1.5e3":  "1500",
+		"15E-3":  "0.015",
+		"-1e0":   "-1",
+	} {
+		d, err := tengo.ParseDecimal(in)
+		require.NoError(t, err, in)
+		require.Equal(t, expected, d.String())
+	}
+	for _, in := range []string{"", ".", "-", "1.2.3", "1e", "abc", "--1",
+		"0x10", "1_000", "1e99999999999"} {
+		_, err := tengo.ParseDecimal(in)
+		require.Error(t, err, in)
+	}
+}
+
+func TestSet_BinaryOp(t *testing.T) {
+	set := func(elems ...int64) *tengo.Set {
+		s, err := tengo.NewSet()
+		require.NoError(t, err)
+		for _, e := range elems {
+			require.NoError(t, s.Add(&tengo.Int{Value: e}))
+		}
+		return s
+	}
+
+	testBinaryOp(t, set(1, 2), token.Or, set(2, 3), set(1, 2, 3))
+	testBinaryOp(t, set(1, 2), token.And, set(2, 3), set(2))
+	testBinaryOp(t, set(1, 2), token.Sub, set(2, 3), set(1))
+	testBinaryOp(t, set(1, 2), token.Xor, set(2, 3), set(1, 3))
+	testBinaryOp(t, &tengo.ImmutableSet{Value: set(1).Value}, token.Or,
+		set(2), set(1, 2))
+	testBinaryOp(t, set(1), token.And, &tengo.ImmutableSet{Value: set(1).Value},
+		set(1))
+
+	_, err := set(1).BinaryOp(token.Add, set(2))
+	require.Equal(t, tengo.ErrInvalidOperator, err)
+	_, err = set(1).BinaryOp(token.Or, &tengo.Array{})
+	require.Equal(t, tengo.ErrInvalidOperator, err)
+}
+
+func TestSet_Elements(t *testing.T) {
+	d, err := tengo.ParseDecimal("2.00")
+	require.NoError(t, err)
+	s, err := tengo.NewSet(&tengo.Int{Value: 2},
+		&tengo.BigInt{Value: big.NewInt(2)}, d,
+		&tengo.String{Value: "2"}, &tengo.Float{Value: 2})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(s.Value))
+	contains := func(x tengo.Object) bool {
+		ok, err := s.Contains(x)
+		require.NoError(t, err)
+		return ok
+	}
+	require.True(t, contains(&tengo.Int{Value: 2}))
+	require.True(t, contains(&tengo.String{Value: "2"}))
+	require.False(t, contains(&tengo.Char{Value: '2'}))
+	require.False(t, contains(&tengo.Array{}))
+
+	s.Remove(&tengo.Int{Value: 2})
+	require.False(t, contains(d))
+	require.True(t, s.Equals(&tengo.ImmutableSet{Value: s.Value}))
+	require.Equal(t, `set("2", 2)`, s.String())
+
+	err = s.Add(&tengo.Map{})
+	require.True(t, errors.Is(err, tengo.ErrNotHashable))
+	_, err = tengo.NewSet(&tengo.Array{})
+	require.Equal(t, "not hashable: array", err.Error())
+}
+
 func TestMap_Index(t *testing.T) {
 	m := &tengo.Map{Value: make(map[string]tengo.Object)}
 	k := &tengo.Int{Value: 1}
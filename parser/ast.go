@@ -20,10 +20,11 @@ type Node interface {
 
 // IdentList represents a list of identifiers.
 type IdentList struct {
-	LParen  Pos
-	VarArgs bool
-	List    []*Ident
-	RParen  Pos
+	LParen   Pos
+	VarArgs  bool
+	List     []*Ident
+	Defaults []Expr // default values, nil entries for required identifiers
+	RParen   Pos
 }
 
 // Pos returns the position of first character belonging to the node.
@@ -56,11 +57,36 @@ func (n *IdentList) NumFields() int {
 	return len(n.List)
 }
 
+// Default returns the default value of the i-th identifier, or nil if it has
+// none.
+func (n *IdentList) Default(i int) Expr {
+	if n == nil || i >= len(n.Defaults) {
+		return nil
+	}
+	return n.Defaults[i]
+}
+
+// NumDefaults returns the number of identifiers with default values.
+func (n *IdentList) NumDefaults() int {
+	if n == nil {
+		return 0
+	}
+	var c int
+	for _, d := range n.Defaults {
+		if d != nil {
+			c++
+		}
+	}
+	return c
+}
+
 func (n *IdentList) String() string {
 	var list []string
 	for i, e := range n.List {
 		if n.VarArgs && i == len(n.List)-1 {
 			list = append(list, "..."+e.String())
+		} else if d := n.Default(i); d != nil {
+			list = append(list, e.String()+" = "+d.String())
 		} else {
 			list = append(list, e.String())
 		}
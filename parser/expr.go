@@ -111,11 +111,13 @@ func (e *BoolLit) String() string {
 
 // CallExpr represents a function call expression.
 type CallExpr struct {
-	Func     Expr
-	LParen   Pos
-	Args     []Expr
-	Ellipsis Pos
-	RParen   Pos
+	Func        Expr
+	LParen      Pos
+	Args        []Expr
+	KeywordArgs []*KeywordArg
+	Ellipsis    Pos
+	RParen      Pos
+	Optional    bool // f?.()
 }
 
 func (e *CallExpr) exprNode() {}
@@ -138,7 +140,14 @@ func (e *CallExpr) String() string {
 	if len(args) > 0 && e.Ellipsis.IsValid() {
 		args[len(args)-1] = args[len(args)-1] + "..."
 	}
-	return e.Func.String() + "(" + strings.Join(args, ", ") + ")"
+	for _, e := range e.KeywordArgs {
+		args = append(args, e.String())
+	}
+	var opt string
+	if e.Optional {
+		opt = "?."
+	}
+	return e.Func.String() + opt + "(" + strings.Join(args, ", ") + ")"
 }
 
 // CharLit represents a character literal.
@@ -239,8 +248,9 @@ func (e *FloatLit) String() string {
 
 // FuncLit represents a function literal.
 type FuncLit struct {
-	Type *FuncType
-	Body *BlockStmt
+	Type  *FuncType
+	Body  *BlockStmt
+	Arrow Pos // position of "=>" for arrow functions
 }
 
 func (e *FuncLit) exprNode() {}
@@ -256,6 +266,9 @@ func (e *FuncLit) End() Pos {
 }
 
 func (e *FuncLit) String() string {
+	if e.Arrow.IsValid() {
+		return e.Type.Params.String() + " => " + e.Body.String()
+	}
 	return "func" + e.Type.Params.String() + " " + e.Body.String()
 }
 
@@ -356,10 +369,11 @@ func (e *ImportExpr) String() string {
 
 // IndexExpr represents an index expression.
 type IndexExpr struct {
-	Expr   Expr
-	LBrack Pos
-	Index  Expr
-	RBrack Pos
+	Expr     Expr
+	LBrack   Pos
+	Index    Expr
+	RBrack   Pos
+	Optional bool // a?.[i]
 }
 
 func (e *IndexExpr) exprNode() {}
@@ -379,6 +393,9 @@ func (e *IndexExpr) String() string {
 	if e.Index != nil {
 		index = e.Index.String()
 	}
+	if e.Optional {
+		return e.Expr.String() + "?.[" + index + "]"
+	}
 	return e.Expr.String() + "[" + index + "]"
 }
 
@@ -405,18 +422,76 @@ func (e *IntLit) String() string {
 	return e.Literal
 }
 
+// InterpStringLit represents an interpolated string literal. Strings holds
+// the literal parts surrounding the embedded expressions, so it always has
+// one more element than Exprs.
+type InterpStringLit struct {
+	Strings []*StringLit
+	Exprs   []Expr
+}
+
+func (e *InterpStringLit) exprNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (e *InterpStringLit) Pos() Pos {
+	return e.Strings[0].Pos()
+}
+
+// End returns the position of first character immediately after the node.
+func (e *InterpStringLit) End() Pos {
+	return e.Strings[len(e.Strings)-1].End()
+}
+
+func (e *InterpStringLit) String() string {
+	var b strings.Builder
+	for i, str := range e.Strings {
+		b.WriteString(str.Literal)
+		if i < len(e.Exprs) {
+			b.WriteString(e.Exprs[i].String())
+		}
+	}
+	return b.String()
+}
+
+// KeywordArg represents a keyword argument of a call expression.
+type KeywordArg struct {
+	Name     *Ident
+	ColonPos Pos
+	Value    Expr
+}
+
+func (e *KeywordArg) exprNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (e *KeywordArg) Pos() Pos {
+	return e.Name.Pos()
+}
+
+// End returns the position of first character immediately after the node.
+func (e *KeywordArg) End() Pos {
+	return e.Value.End()
+}
+
+func (e *KeywordArg) String() string {
+	return e.Name.String() + ": " + e.Value.String()
+}
+
 // MapElementLit represents a map element.
 type MapElementLit struct {
 	Key      string
 	KeyPos   Pos
 	ColonPos Pos
 	Value    Expr
+	Ellipsis Pos // position of "..." if the element spreads Value
 }
 
 func (e *MapElementLit) exprNode() {}
 
 // Pos returns the position of first character belonging to the node.
 func (e *MapElementLit) Pos() Pos {
+	if e.Ellipsis.IsValid() {
+		return e.Ellipsis
+	}
 	return e.KeyPos
 }
 
@@ -426,6 +501,9 @@ func (e *MapElementLit) End() Pos {
 }
 
 func (e *MapElementLit) String() string {
+	if e.Ellipsis.IsValid() {
+		return "..." + e.Value.String()
+	}
 	return e.Key + ": " + e.Value.String()
 }
 
@@ -456,6 +534,29 @@ func (e *MapLit) String() string {
 	return "{" + strings.Join(elements, ", ") + "}"
 }
 
+// OptionalChainExpr represents a chain of selector, index, slice and call
+// expressions containing at least one optional link. If any optional link
+// is applied to undefined, the whole chain evaluates to undefined.
+type OptionalChainExpr struct {
+	Expr Expr
+}
+
+func (e *OptionalChainExpr) exprNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (e *OptionalChainExpr) Pos() Pos {
+	return e.Expr.Pos()
+}
+
+// End returns the position of first character immediately after the node.
+func (e *OptionalChainExpr) End() Pos {
+	return e.Expr.End()
+}
+
+func (e *OptionalChainExpr) String() string {
+	return e.Expr.String()
+}
+
 // ParenExpr represents a parenthesis wrapped expression.
 type ParenExpr struct {
 	Expr   Expr
@@ -481,8 +582,9 @@ func (e *ParenExpr) String() string {
 
 // SelectorExpr represents a selector expression.
 type SelectorExpr struct {
-	Expr Expr
-	Sel  Expr
+	Expr     Expr
+	Sel      Expr
+	Optional bool // a?.b
 }
 
 func (e *SelectorExpr) exprNode() {}
@@ -498,16 +600,21 @@ func (e *SelectorExpr) End() Pos {
 }
 
 func (e *SelectorExpr) String() string {
+	if e.Optional {
+		return e.Expr.String() + "?." + e.Sel.String()
+	}
 	return e.Expr.String() + "." + e.Sel.String()
 }
 
 // SliceExpr represents a slice expression.
 type SliceExpr struct {
-	Expr   Expr
-	LBrack Pos
-	Low    Expr
-	High   Expr
-	RBrack Pos
+	Expr     Expr
+	LBrack   Pos
+	Low      Expr
+	High     Expr
+	Step     Expr // nil unless a[low:high:step]
+	RBrack   Pos
+	Optional bool // a?.[low:high]
 }
 
 func (e *SliceExpr) exprNode() {}
@@ -530,9 +637,38 @@ func (e *SliceExpr) String() string {
 	if e.High != nil {
 		high = e.High.String()
 	}
+	if e.Step != nil {
+		high += ":" + e.Step.String()
+	}
+	if e.Optional {
+		return e.Expr.String() + "?.[" + low + ":" + high + "]"
+	}
 	return e.Expr.String() + "[" + low + ":" + high + "]"
 }
 
+// SpreadExpr represents an array literal element that spreads the elements
+// of another array.
+type SpreadExpr struct {
+	Ellipsis Pos
+	Expr     Expr
+}
+
+func (e *SpreadExpr) exprNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (e *SpreadExpr) Pos() Pos {
+	return e.Ellipsis
+}
+
+// End returns the position of first character immediately after the node.
+func (e *SpreadExpr) End() Pos {
+	return e.Expr.End()
+}
+
+func (e *SpreadExpr) String() string {
+	return "..." + e.Expr.String()
+}
+
 // StringLit represents a string literal.
 type StringLit struct {
 	Value    string
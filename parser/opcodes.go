@@ -47,6 +47,19 @@ const (
 	OpIteratorValue               // Iterator value
 	OpBinaryOp                    // Binary operation
 	OpSuspend                     // Suspend VM
+	OpCallKw                      // Call function with keyword arguments
+	OpDefaultJump                 // Jump if parameter was passed
+	OpOptionalJump                // Optional chaining jump
+	OpCoalesceJump                // Nullish coalescing jump
+	OpDefer                       // Defer function call
+	OpGenerator                   // Create generator from current frame
+	OpYield                       // Yield value from generator
+	OpArrayConcat                 // Concatenate arrays
+	OpMapMerge                    // Merge maps
+	OpContains                    // Membership test (in)
+	OpSliceStep                   // Slice operation with a step
+	OpSetSlice                    // Slice assignment
+	OpOptionalIndex               // Index operation in optional chaining
 )
 
 // OpcodeNames are string representation of opcodes.
@@ -93,6 +106,19 @@ var OpcodeNames = [...]string{
 	OpIteratorValue: "ITVAL",
 	OpBinaryOp:      "BINARYOP",
 	OpSuspend:       "SUSPEND",
+	OpCallKw:        "CALLKW",
+	OpDefaultJump:   "DEFJMP",
+	OpOptionalJump:  "OPTJMP",
+	OpCoalesceJump:  "COALJMP",
+	OpDefer:         "DEFER",
+	OpGenerator:     "GEN",
+	OpYield:         "YIELD",
+	OpArrayConcat:   "ACONCAT",
+	OpMapMerge:      "MMERGE",
+	OpContains:      "CONTAINS",
+	OpSliceStep:     "SLICESTEP",
+	OpSetSlice:      "SETSLICE",
+	OpOptionalIndex: "OPTINDEX",
 }
 
 // OpcodeOperands is the number of operands.
@@ -139,6 +165,19 @@ var OpcodeOperands = [...][]int{
 	OpIteratorValue: {},
 	OpBinaryOp:      {1},
 	OpSuspend:       {},
+	OpCallKw:        {1, 1},
+	OpDefaultJump:   {1, 4},
+	OpOptionalJump:  {4},
+	OpCoalesceJump:  {4},
+	OpDefer:         {1, 1},
+	OpGenerator:     {},
+	OpYield:         {},
+	OpArrayConcat:   {2},
+	OpMapMerge:      {2},
+	OpContains:      {},
+	OpSliceStep:     {},
+	OpSetSlice:      {},
+	OpOptionalIndex: {4},
 }
 
 // ReadOperands reads operands from the bytecode.
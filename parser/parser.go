@@ -5,6 +5,8 @@ import (
 	"io"
 	"sort"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/shelepuginivan/tengo/token"
 )
@@ -18,6 +20,8 @@ var stmtStart = map[token.Token]bool{
 	token.If:       true,
 	token.Return:   true,
 	token.Export:   true,
+	token.Defer:    true,
+	token.Yield:    true,
 }
 
 // Error represents a parser error.
@@ -98,9 +102,10 @@ type Parser struct {
 	pos       Pos
 	token     token.Token
 	tokenLit  string
-	exprLevel int // < 0: in control clause, >= 0: in expression
-	syncPos   Pos // last sync position
-	syncCount int // number of advance calls without progress
+	exprLevel int  // < 0: in control clause, >= 0: in expression
+	noIn      bool // in for-in header: 'in' is not a binary operator
+	syncPos   Pos  // last sync position
+	syncCount int  // number of advance calls without progress
 	trace     bool
 	indent    int
 	traceOut  io.Writer
@@ -178,12 +183,26 @@ func (p *Parser) parseBinaryExpr(prec1 int) Expr {
 
 	for {
 		op, prec := p.token, p.token.Precedence()
-		if prec < prec1 {
+		if op == token.Ident && p.tokenLit == "not" {
+			// 'not' is only special when followed by 'in'
+			op, prec = token.NotIn, token.NotIn.Precedence()
+		}
+		if prec < prec1 || op == token.In && p.noIn && p.exprLevel < 0 {
 			return x
 		}
 
-		pos := p.expect(op)
+		pos := p.pos
+		if op == token.NotIn {
+			p.next()
+			p.expect(token.In)
+		} else {
+			p.expect(op)
+		}
 
+		// "**" is right-associative
+		if op == token.Pow {
+			prec--
+		}
 		y := p.parseBinaryExpr(prec + 1)
 
 		x = &BinaryExpr{
@@ -236,6 +255,7 @@ func (p *Parser) parsePrimaryExpr() Expr {
 
 	x := p.parseOperand()
 
+	var optional bool
 L:
 	for {
 		switch p.token {
@@ -251,6 +271,24 @@ L:
 				p.advance(stmtStart)
 				return &BadExpr{From: pos, To: p.pos}
 			}
+		case token.QuestionPeriod:
+			p.next()
+			optional = true
+
+			switch p.token {
+			case token.Ident:
+				x = p.parseSelector(x)
+			case token.LBrack:
+				x = p.parseIndexOrSlice(x)
+			case token.LParen:
+				x = p.parseCall(x)
+			default:
+				pos := p.pos
+				p.errorExpected(pos, "selector")
+				p.advance(stmtStart)
+				return &BadExpr{From: pos, To: p.pos}
+			}
+			setOptional(x)
 		case token.LBrack:
 			x = p.parseIndexOrSlice(x)
 		case token.LParen:
@@ -259,9 +297,27 @@ L:
 			break L
 		}
 	}
+	if optional {
+		return &OptionalChainExpr{Expr: x}
+	}
 	return x
 }
 
+// setOptional marks the last link of a selector, index, slice or call chain
+// as optional.
+func setOptional(x Expr) {
+	switch x := x.(type) {
+	case *SelectorExpr:
+		x.Optional = true
+	case *IndexExpr:
+		x.Optional = true
+	case *SliceExpr:
+		x.Optional = true
+	case *CallExpr:
+		x.Optional = true
+	}
+}
+
 func (p *Parser) parseCall(x Expr) *CallExpr {
 	if p.trace {
 		defer untracep(tracep(p, "Call"))
@@ -271,12 +327,22 @@ func (p *Parser) parseCall(x Expr) *CallExpr {
 	p.exprLevel++
 
 	var list []Expr
+	var kwargs []*KeywordArg
 	var ellipsis Pos
 	for p.token != token.RParen && p.token != token.EOF && !ellipsis.IsValid() {
-		list = append(list, p.parseExpr())
-		if p.token == token.Ellipsis {
-			ellipsis = p.pos
-			p.next()
+		arg := p.parseExpr()
+		if p.token == token.Colon {
+			kwargs = append(kwargs, p.parseKeywordArg(arg))
+		} else {
+			if len(kwargs) > 0 {
+				p.error(arg.Pos(),
+					"positional argument follows keyword argument")
+			}
+			list = append(list, arg)
+			if p.token == token.Ellipsis {
+				ellipsis = p.pos
+				p.next()
+			}
 		}
 		if !p.expectComma(token.RParen, "call argument") {
 			break
@@ -286,11 +352,98 @@ func (p *Parser) parseCall(x Expr) *CallExpr {
 	p.exprLevel--
 	rparen := p.expect(token.RParen)
 	return &CallExpr{
-		Func:     x,
-		LParen:   lparen,
-		RParen:   rparen,
-		Ellipsis: ellipsis,
-		Args:     list,
+		Func:        x,
+		LParen:      lparen,
+		RParen:      rparen,
+		Ellipsis:    ellipsis,
+		Args:        list,
+		KeywordArgs: kwargs,
+	}
+}
+
+func (p *Parser) parseInterpStringLit() Expr {
+	if p.trace {
+		defer untracep(tracep(p, "InterpStringLit"))
+	}
+
+	x := &InterpStringLit{}
+	for {
+		lit := p.tokenLit
+		str := &StringLit{ValuePos: p.pos, Literal: lit}
+		x.Strings = append(x.Strings, str)
+
+		// strip the opening 'f"' or '}' and the closing '"' or '${'
+		if p.token == token.InterpBegin {
+			lit = lit[2:]
+		} else {
+			lit = lit[1:]
+		}
+		embed := strings.HasSuffix(lit, "${")
+		if embed {
+			lit = lit[:len(lit)-2]
+		} else {
+			lit = strings.TrimSuffix(lit, `"`)
+		}
+		v, err := unquoteInterp(lit)
+		if err != nil {
+			p.error(str.ValuePos, "invalid interpolated string literal")
+		}
+		str.Value = v
+		p.next()
+		if !embed {
+			return x
+		}
+
+		p.exprLevel++
+		x.Exprs = append(x.Exprs, p.parseExpr())
+		p.exprLevel--
+		if p.token != token.InterpCont {
+			p.errorExpected(p.pos, "'}'")
+			return &BadExpr{From: x.Pos(), To: p.pos}
+		}
+	}
+}
+
+// unquoteInterp interprets the escape sequences of an interpolated string
+// part, which additionally allows \$ to write a literal dollar sign.
+func unquoteInterp(s string) (string, error) {
+	var b strings.Builder
+	for len(s) > 0 {
+		if strings.HasPrefix(s, `\$`) {
+			b.WriteByte('$')
+			s = s[2:]
+			continue
+		}
+		r, multibyte, tail, err := strconv.UnquoteChar(s, '"')
+		if err != nil {
+			return "", err
+		}
+		if r < utf8.RuneSelf || !multibyte {
+			b.WriteByte(byte(r))
+		} else {
+			b.WriteRune(r)
+		}
+		s = tail
+	}
+	return b.String(), nil
+}
+
+func (p *Parser) parseKeywordArg(name Expr) *KeywordArg {
+	if p.trace {
+		defer untracep(tracep(p, "KeywordArg"))
+	}
+
+	ident, ok := name.(*Ident)
+	if !ok {
+		p.errorExpected(name.Pos(), "argument name")
+		ident = &Ident{Name: "_", NamePos: name.Pos()}
+	}
+	colonPos := p.expect(token.Colon)
+	value := p.parseExpr()
+	return &KeywordArg{
+		Name:     ident,
+		ColonPos: colonPos,
+		Value:    value,
 	}
 }
 
@@ -319,17 +472,18 @@ func (p *Parser) parseIndexOrSlice(x Expr) Expr {
 	lbrack := p.expect(token.LBrack)
 	p.exprLevel++
 
-	var index [2]Expr
+	var index [3]Expr
 	if p.token != token.Colon {
 		index[0] = p.parseExpr()
 	}
 	numColons := 0
-	if p.token == token.Colon {
+	for numColons < 2 && p.token == token.Colon {
 		numColons++
 		p.next()
 
-		if p.token != token.RBrack && p.token != token.EOF {
-			index[1] = p.parseExpr()
+		if p.token != token.RBrack && p.token != token.Colon &&
+			p.token != token.EOF {
+			index[numColons] = p.parseExpr()
 		}
 	}
 
@@ -344,6 +498,7 @@ func (p *Parser) parseIndexOrSlice(x Expr) Expr {
 			RBrack: rbrack,
 			Low:    index[0],
 			High:   index[1],
+			Step:   index[2],
 		}
 	}
 	return &IndexExpr{
@@ -374,7 +529,11 @@ func (p *Parser) parseOperand() Expr {
 
 	switch p.token {
 	case token.Ident:
-		return p.parseIdent()
+		ident := p.parseIdent()
+		if p.token == token.Arrow {
+			return p.parseArrowFunc(&IdentList{List: []*Ident{ident}})
+		}
+		return ident
 	case token.Int:
 		v, err := strconv.ParseInt(p.tokenLit, 0, 64)
 		if err == strconv.ErrRange {
@@ -415,6 +574,8 @@ func (p *Parser) parseOperand() Expr {
 		}
 		p.next()
 		return x
+	case token.InterpBegin:
+		return p.parseInterpStringLit()
 	case token.True:
 		x := &BoolLit{
 			Value:    true,
@@ -438,17 +599,7 @@ func (p *Parser) parseOperand() Expr {
 	case token.Import:
 		return p.parseImportExpr()
 	case token.LParen:
-		lparen := p.pos
-		p.next()
-		p.exprLevel++
-		x := p.parseExpr()
-		p.exprLevel--
-		rparen := p.expect(token.RParen)
-		return &ParenExpr{
-			LParen: lparen,
-			Expr:   x,
-			RParen: rparen,
-		}
+		return p.parseParenExpr()
 	case token.LBrack: // array literal
 		return p.parseArrayLit()
 	case token.LBrace: // map literal
@@ -468,6 +619,86 @@ func (p *Parser) parseOperand() Expr {
 	return &BadExpr{From: pos, To: p.pos}
 }
 
+// parseParenExpr parses a parenthesized expression or, if the parentheses
+// turn out to hold a parameter list, an arrow function.
+func (p *Parser) parseParenExpr() Expr {
+	lparen := p.pos
+	p.next()
+	p.exprLevel++
+	if p.token == token.RParen || p.token == token.Ellipsis {
+		// () => ... or (...args) => ...
+		params := p.parseParams(lparen, nil)
+		p.exprLevel--
+		return p.parseArrowFunc(params)
+	}
+
+	x := p.parseExpr()
+	if p.token == token.Comma || p.token == token.Assign {
+		// (a, b) => ... or (a = 1) => ...
+		params := p.parseParams(lparen, p.exprToParam(x))
+		p.exprLevel--
+		return p.parseArrowFunc(params)
+	}
+	p.exprLevel--
+	rparen := p.expect(token.RParen)
+	if p.token == token.Arrow {
+		return p.parseArrowFunc(&IdentList{
+			LParen: lparen,
+			List:   []*Ident{p.exprToParam(x)},
+			RParen: rparen,
+		})
+	}
+	return &ParenExpr{
+		LParen: lparen,
+		Expr:   x,
+		RParen: rparen,
+	}
+}
+
+// exprToParam converts an expression parsed inside parentheses to an arrow
+// function parameter.
+func (p *Parser) exprToParam(x Expr) *Ident {
+	if ident, ok := x.(*Ident); ok {
+		return ident
+	}
+	p.errorExpected(x.Pos(), "identifier")
+	return &Ident{Name: "_", NamePos: x.Pos()}
+}
+
+// parseArrowFunc parses the "=>" and the body of an arrow function. An
+// expression body is a shorthand for a block that returns it.
+func (p *Parser) parseArrowFunc(params *IdentList) Expr {
+	if p.trace {
+		defer untracep(tracep(p, "ArrowFunc"))
+	}
+
+	arrow := p.expect(token.Arrow)
+	p.exprLevel++
+	var body *BlockStmt
+	if p.token == token.LBrace {
+		body = p.parseBody()
+	} else {
+		x := p.parseExpr()
+		body = &BlockStmt{
+			LBrace: x.Pos(),
+			RBrace: x.End() - 1,
+			Stmts: []Stmt{&ReturnStmt{
+				ReturnPos: x.Pos(),
+				Result:    x,
+			}},
+		}
+	}
+	p.exprLevel--
+	return &FuncLit{
+		Type: &FuncType{
+			FuncPos: params.Pos(),
+			Params:  params,
+		},
+		Body:  body,
+		Arrow: arrow,
+	}
+}
+
 func (p *Parser) parseImportExpr() Expr {
 	pos := p.pos
 	p.next()
@@ -539,7 +770,16 @@ func (p *Parser) parseArrayLit() Expr {
 
 	var elements []Expr
 	for p.token != token.RBrack && p.token != token.EOF {
-		elements = append(elements, p.parseExpr())
+		if p.token == token.Ellipsis {
+			pos := p.pos
+			p.next()
+			elements = append(elements, &SpreadExpr{
+				Ellipsis: pos,
+				Expr:     p.parseExpr(),
+			})
+		} else {
+			elements = append(elements, p.parseExpr())
+		}
 
 		if !p.expectComma(token.RBrack, "array element") {
 			break
@@ -645,16 +885,27 @@ func (p *Parser) parseIdentList() *IdentList {
 		defer untracep(tracep(p, "IdentList"))
 	}
 
-	var params []*Ident
 	lparen := p.expect(token.LParen)
+	return p.parseParams(lparen, nil)
+}
+
+// parseParams parses a parameter list after the opening parenthesis. If
+// first is not nil, it is the already parsed first parameter.
+func (p *Parser) parseParams(lparen Pos, first *Ident) *IdentList {
+	var params []*Ident
+	var defaults []Expr
 	isVarArgs := false
-	if p.token != token.RParen {
-		if p.token == token.Ellipsis {
+	if first != nil || p.token != token.RParen {
+		if first == nil && p.token == token.Ellipsis {
 			isVarArgs = true
 			p.next()
 		}
+		if first == nil {
+			first = p.parseIdent()
+		}
 
-		params = append(params, p.parseIdent())
+		params = append(params, first)
+		defaults = p.parseParamDefault(defaults, params, isVarArgs)
 		for !isVarArgs && p.token == token.Comma {
 			p.next()
 			if p.token == token.Ellipsis {
@@ -662,16 +913,46 @@ func (p *Parser) parseIdentList() *IdentList {
 				p.next()
 			}
 			params = append(params, p.parseIdent())
+			defaults = p.parseParamDefault(defaults, params, isVarArgs)
 		}
 	}
 
 	rparen := p.expect(token.RParen)
 	return &IdentList{
-		LParen:  lparen,
-		RParen:  rparen,
-		VarArgs: isVarArgs,
-		List:    params,
+		LParen:   lparen,
+		RParen:   rparen,
+		VarArgs:  isVarArgs,
+		List:     params,
+		Defaults: defaults,
+	}
+}
+
+func (p *Parser) parseParamDefault(
+	defaults []Expr,
+	params []*Ident,
+	isVarArgs bool,
+) []Expr {
+	last := params[len(params)-1]
+	if p.token != token.Assign {
+		if defaults != nil {
+			if !isVarArgs {
+				p.error(last.Pos(),
+					"non-default parameter follows default parameter")
+			}
+			defaults = append(defaults, nil)
+		}
+		return defaults
+	}
+
+	pos := p.pos
+	p.next()
+	if isVarArgs {
+		p.error(pos, "variadic parameter cannot have a default value")
 	}
+	if defaults == nil {
+		defaults = make([]Expr, len(params)-1)
+	}
+	return append(defaults, p.parseExpr())
 }
 
 func (p *Parser) parseStmt() (stmt Stmt) {
@@ -682,15 +963,26 @@ func (p *Parser) parseStmt() (stmt Stmt) {
 	switch p.token {
 	case // simple statements
 		token.Func, token.Error, token.Immutable, token.Ident, token.Int,
-		token.Float, token.Char, token.String, token.True, token.False,
+		token.Float, token.Char, token.String, token.InterpBegin, token.True, token.False,
 		token.Undefined, token.Import, token.LParen, token.LBrace,
 		token.LBrack, token.Add, token.Sub, token.Mul, token.And, token.Xor,
 		token.Not:
 		s := p.parseSimpleStmt(false)
+		if x, ok := s.(*ExprStmt); ok && p.token == token.Colon {
+			if label, ok := x.Expr.(*Ident); ok {
+				return p.parseLabeledStmt(label)
+			}
+		}
 		p.expectSemi()
 		return s
 	case token.Return:
 		return p.parseReturnStmt()
+	case token.Defer:
+		return p.parseDeferStmt()
+	case token.Const:
+		return p.parseConstStmt()
+	case token.Yield:
+		return p.parseYieldStmt()
 	case token.Export:
 		return p.parseExportStmt()
 	case token.If:
@@ -782,6 +1074,19 @@ func (p *Parser) parseForStmt() Stmt {
 	}
 }
 
+func (p *Parser) parseLabeledStmt(label *Ident) Stmt {
+	if p.trace {
+		defer untracep(tracep(p, "LabeledStmt"))
+	}
+
+	colon := p.expect(token.Colon)
+	return &LabeledStmt{
+		Label: label,
+		Colon: colon,
+		Stmt:  p.parseStmt(),
+	}
+}
+
 func (p *Parser) parseBranchStmt(tok token.Token) Stmt {
 	if p.trace {
 		defer untracep(tracep(p, "BranchStmt"))
@@ -924,6 +1229,60 @@ func (p *Parser) parseReturnStmt() Stmt {
 	}
 }
 
+func (p *Parser) parseConstStmt() Stmt {
+	if p.trace {
+		defer untracep(tracep(p, "ConstStmt"))
+	}
+
+	pos := p.pos
+	p.expect(token.Const)
+	name := p.parseIdent()
+	p.expect(token.Assign)
+	value := p.parseExpr()
+	p.expectSemi()
+	return &ConstStmt{
+		ConstPos: pos,
+		Name:     name,
+		Value:    value,
+	}
+}
+
+func (p *Parser) parseDeferStmt() Stmt {
+	if p.trace {
+		defer untracep(tracep(p, "DeferStmt"))
+	}
+
+	pos := p.pos
+	p.expect(token.Defer)
+	x := p.parseExpr()
+	p.expectSemi()
+
+	call, ok := x.(*CallExpr)
+	if !ok {
+		p.error(x.Pos(), "expression in defer must be function call")
+		return &BadStmt{From: pos, To: x.End()}
+	}
+	return &DeferStmt{
+		DeferPos: pos,
+		Call:     call,
+	}
+}
+
+func (p *Parser) parseYieldStmt() Stmt {
+	if p.trace {
+		defer untracep(tracep(p, "YieldStmt"))
+	}
+
+	pos := p.pos
+	p.expect(token.Yield)
+	x := p.parseExpr()
+	p.expectSemi()
+	return &YieldStmt{
+		YieldPos: pos,
+		Value:    x,
+	}
+}
+
 func (p *Parser) parseExportStmt() Stmt {
 	if p.trace {
 		defer untracep(tracep(p, "ExportStmt"))
@@ -944,7 +1303,9 @@ func (p *Parser) parseSimpleStmt(forIn bool) Stmt {
 		defer untracep(tracep(p, "SimpleStmt"))
 	}
 
+	p.noIn = forIn
 	x := p.parseExprList()
+	p.noIn = false
 
 	switch p.token {
 	case token.Assign, token.Define: // assignment statement
@@ -1002,7 +1363,8 @@ func (p *Parser) parseSimpleStmt(forIn bool) Stmt {
 	case token.Define,
 		token.AddAssign, token.SubAssign, token.MulAssign, token.QuoAssign,
 		token.RemAssign, token.AndAssign, token.OrAssign, token.XorAssign,
-		token.ShlAssign, token.ShrAssign, token.AndNotAssign:
+		token.ShlAssign, token.ShrAssign, token.AndNotAssign,
+		token.PowAssign:
 		pos, tok := p.pos, p.token
 		p.next()
 		y := p.parseExpr()
@@ -1040,6 +1402,14 @@ func (p *Parser) parseMapElementLit() *MapElementLit {
 	}
 
 	pos := p.pos
+	if p.token == token.Ellipsis {
+		p.next()
+		return &MapElementLit{
+			Ellipsis: pos,
+			Value:    p.parseExpr(),
+		}
+	}
+
 	name := "_"
 	if p.token == token.Ident {
 		name = p.tokenLit
@@ -126,6 +126,20 @@ func TestParseArray(t *testing.T) {
 
 ]`)
 	expectParseError(t, `[1, 2, 3, ,]`)
+
+	expectParse(t, "[...a, 1]", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				arrayLit(p(1, 1), p(1, 9),
+					&SpreadExpr{
+						Ellipsis: p(1, 2),
+						Expr:     ident("a", p(1, 5)),
+					},
+					intLit(1, p(1, 8)))))
+	})
+
+	expectParseString(t, "[1, ...a, ...b[1:]]", "[1, ...a, ...b[1:]]")
+	expectParseError(t, "[...]")
 }
 
 func TestParseAssignment(t *testing.T) {
@@ -308,6 +322,18 @@ func TestParseCall(t *testing.T) {
 					ident("v", p(1, 11)))))
 	})
 
+	expectParse(t, "add(1, b: 2, c: 3)", func(p pfn) []Stmt {
+		call := callExpr(
+			ident("add", p(1, 1)),
+			p(1, 4), p(1, 18), NoPos,
+			intLit(1, p(1, 5)))
+		call.KeywordArgs = []*KeywordArg{
+			keywordArg(ident("b", p(1, 8)), p(1, 9), intLit(2, p(1, 11))),
+			keywordArg(ident("c", p(1, 14)), p(1, 15), intLit(3, p(1, 17))),
+		}
+		return stmts(exprStmt(call))
+	})
+
 	expectParse(t, "a = add(1, 2, 3)", func(p pfn) []Stmt {
 		return stmts(
 			assignStmt(
@@ -370,6 +396,12 @@ func TestParseCall(t *testing.T) {
 	expectParseString(t, "add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))",
 		"add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))")
 	expectParseString(t, "f1(a) + f2(b) * f3(c)", "(f1(a) + (f2(b) * f3(c)))")
+	expectParseString(t, "f(a, b: c + 1)", "f(a, b: (c + 1))")
+	expectParseString(t, "f(a ? b : c, d: e)", "f((a ? b : c), d: e)")
+
+	expectParseError(t, `f(a: 1, 2)`)
+	expectParseError(t, `f(a + b: 1)`)
+	expectParseError(t, `f(a..., b: 1)`)
 	expectParseString(t, "(f1(a) + f2(b)) * f3(c)",
 		"(((f1(a) + f2(b))) * f3(c))")
 
@@ -713,6 +745,32 @@ func TestParseFor(t *testing.T) {
 	})
 }
 
+func TestParseLabeledStmt(t *testing.T) {
+	expectParse(t, "a: for { break a }", func(p pfn) []Stmt {
+		return stmts(
+			&LabeledStmt{
+				Label: ident("a", p(1, 1)),
+				Colon: p(1, 2),
+				Stmt: forStmt(nil, nil, nil,
+					blockStmt(p(1, 8), p(1, 18),
+						&BranchStmt{
+							Token:    token.Break,
+							TokenPos: p(1, 10),
+							Label:    ident("a", p(1, 16)),
+						}),
+					p(1, 4)),
+			})
+	})
+
+	expectParseString(t, "outer: for x in y { continue outer }",
+		"outer: for _, x in y {continue outer}")
+	expectParseString(t, "a:\nfor {}", "a: for {}")
+
+	expectParseError(t, "a.b: for {}")
+	expectParseError(t, "a, b: for {}")
+	expectParseError(t, "a:")
+}
+
 func TestParseFunction(t *testing.T) {
 	expectParse(t, "a = func(b, c, d) { return d }", func(p pfn) []Stmt {
 		return stmts(
@@ -734,6 +792,72 @@ func TestParseFunction(t *testing.T) {
 	})
 }
 
+func TestParseFunctionDefaults(t *testing.T) {
+	expectParse(t, "a = func(b, c = 2) { return c }", func(p pfn) []Stmt {
+		params := identList(p(1, 9), p(1, 18), false,
+			ident("b", p(1, 10)),
+			ident("c", p(1, 13)))
+		params.Defaults = []Expr{nil, intLit(2, p(1, 17))}
+		return stmts(
+			assignStmt(
+				exprs(
+					ident("a", p(1, 1))),
+				exprs(
+					funcLit(
+						funcType(params, p(1, 5)),
+						blockStmt(p(1, 20), p(1, 31),
+							returnStmt(p(1, 22), ident("c", p(1, 29)))))),
+				token.Assign,
+				p(1, 3)))
+	})
+
+	expectParseString(t, "func(a, b = a * 2, ...c) {}",
+		"func(a, b = (a * 2), ...c) {}")
+
+	expectParseError(t, "func(a = 1, b) {}")
+	expectParseError(t, "func(a, ...b = 1) {}")
+}
+
+func TestParseArrowFunction(t *testing.T) {
+	expectParse(t, "a = (b, c) => b + c", func(p pfn) []Stmt {
+		f := funcLit(
+			funcType(
+				identList(p(1, 5), p(1, 10), false,
+					ident("b", p(1, 6)),
+					ident("c", p(1, 9))),
+				p(1, 5)),
+			blockStmt(p(1, 15), p(1, 19),
+				returnStmt(p(1, 15),
+					binaryExpr(
+						ident("b", p(1, 15)),
+						ident("c", p(1, 19)),
+						token.Add,
+						p(1, 17)))))
+		f.Arrow = p(1, 12)
+		return stmts(
+			assignStmt(
+				exprs(ident("a", p(1, 1))),
+				exprs(f),
+				token.Assign,
+				p(1, 3)))
+	})
+
+	expectParseString(t, "x => x * 2", "(x) => {return (x * 2)}")
+	expectParseString(t, "(x) => { return x }", "(x) => {return x}")
+	expectParseString(t, "() => 1", "() => {return 1}")
+	expectParseString(t, "(a, b = 2, ...c) => a",
+		"(a, b = 2, ...c) => {return a}")
+	expectParseString(t, "a => b => a + b",
+		"(a) => {return (b) => {return (a + b)}}")
+	expectParseString(t, "f(x => x, 1)", "f((x) => {return x}, 1)")
+	expectParseString(t, "(a + b) * c", "(((a + b)) * c)")
+
+	expectParseError(t, "(1, 2) => 3")
+	expectParseError(t, "(a + 1) => 3")
+	expectParseError(t, "f := (a, b)")
+	expectParseError(t, "x =>")
+}
+
 func TestParseVariadicFunction(t *testing.T) {
 	expectParse(t, "a = func(...args) { return args }", func(p pfn) []Stmt {
 		return stmts(
@@ -1099,6 +1223,43 @@ func TestParseIndex(t *testing.T) {
 					p(1, 10), p(1, 24))))
 	})
 
+	expectParse(t, "a[1:2:3]", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				sliceStepExpr(
+					ident("a", p(1, 1)),
+					intLit(1, p(1, 3)),
+					intLit(2, p(1, 5)),
+					intLit(3, p(1, 7)),
+					p(1, 2), p(1, 8))))
+	})
+
+	expectParse(t, "a[::-1]", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				sliceStepExpr(
+					ident("a", p(1, 1)),
+					nil,
+					nil,
+					unaryExpr(intLit(1, p(1, 6)), token.Sub, p(1, 5)),
+					p(1, 2), p(1, 7))))
+	})
+
+	expectParse(t, "a[1::]", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				sliceExpr(
+					ident("a", p(1, 1)),
+					intLit(1, p(1, 3)),
+					nil,
+					p(1, 2), p(1, 6))))
+	})
+
+	expectParseString(t, "a[1:2:3]", "a[1:2:3]")
+	expectParseString(t, "a[::2]", "a[::2]")
+	expectParseString(t, "a[:-1]", "a[:(-1)]")
+	expectParseError(t, "a[1:2:3:4]")
+
 	expectParse(t, `{a: 1, b: 2}["b"]`, func(p pfn) []Stmt {
 		return stmts(
 			exprStmt(
@@ -1262,6 +1423,22 @@ func TestParseMap(t *testing.T) {
 key1: 1,
 key2: 2,
 }`)
+
+	expectParse(t, "{...a, b: 1}", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				mapLit(p(1, 1), p(1, 12),
+					&MapElementLit{
+						Ellipsis: p(1, 2),
+						Value:    ident("a", p(1, 5)),
+					},
+					mapElementLit(
+						"b", p(1, 8), p(1, 9), intLit(1, p(1, 11))))))
+	})
+
+	expectParseString(t, "{a: 1, ...b.c, ...d}", "{a: 1, ...b.c, ...d}")
+	expectParseError(t, "{...}")
+	expectParseError(t, "{...a: 1}")
 }
 
 func TestParsePrecedence(t *testing.T) {
@@ -1448,6 +1625,146 @@ func TestParseSelector(t *testing.T) {
 	expectParseError(t, `a.(b.c)`)
 }
 
+func TestParseConst(t *testing.T) {
+	expectParse(t, "const a = 1", func(p pfn) []Stmt {
+		return stmts(
+			&ConstStmt{
+				ConstPos: p(1, 1),
+				Name:     ident("a", p(1, 7)),
+				Value:    intLit(1, p(1, 11)),
+			})
+	})
+
+	expectParseString(t, "const a = b + 1; const c = func() {}",
+		"const a = (b + 1); const c = func() {}")
+	expectParseString(t, "func() { const a = \"x\" }",
+		"func() {const a = \"x\"}")
+
+	expectParseError(t, "const a")
+	expectParseError(t, "const a := 1")
+	expectParseError(t, "const a.b = 1")
+	expectParseError(t, "const = 1")
+}
+
+func TestParseDefer(t *testing.T) {
+	expectParse(t, "defer f(a)", func(p pfn) []Stmt {
+		return stmts(
+			&DeferStmt{
+				DeferPos: p(1, 1),
+				Call: callExpr(
+					ident("f", p(1, 7)),
+					p(1, 8), p(1, 10), NoPos,
+					ident("a", p(1, 9))),
+			})
+	})
+
+	expectParseString(t, "func() { defer a.b(c...) }",
+		"func() {defer a.b(c...)}")
+
+	expectParseError(t, "defer 1")
+	expectParseError(t, "defer f")
+	expectParseError(t, "defer f?.()")
+}
+
+func TestParseYield(t *testing.T) {
+	expectParse(t, "yield a", func(p pfn) []Stmt {
+		return stmts(
+			&YieldStmt{
+				YieldPos: p(1, 1),
+				Value:    ident("a", p(1, 7)),
+			})
+	})
+
+	expectParseString(t, "func() { for { yield a + 1 } }",
+		"func() {for {yield (a + 1)}}")
+
+	expectParseError(t, "yield")
+}
+
+func TestParseOptionalChain(t *testing.T) {
+	expectParse(t, "a?.b", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				&OptionalChainExpr{Expr: &SelectorExpr{
+					Expr:     ident("a", p(1, 1)),
+					Sel:      stringLit("b", p(1, 4)),
+					Optional: true,
+				}}))
+	})
+
+	expectParseString(t, "a?.b.c", "a?.b.c")
+	expectParseString(t, "a?.[0]?.b", "a?.[0]?.b")
+	expectParseString(t, "a?.[1:2]", "a?.[1:2]")
+	expectParseString(t, "f?.(1, 2)", "f?.(1, 2)")
+	expectParseString(t, "a.b?.c(d?.e)", "a.b?.c(d?.e)")
+	expectParseString(t, "a ?.5 : 1", "(a ? .5 : 1)")
+	expectParseString(t, "a ? [1] : [2]", "(a ? [1] : [2])")
+	expectParseString(t, "a ?[1] : [2]", "(a ? [1] : [2])")
+	expectParseString(t, "a?[1]:[2]", "(a ? [1] : [2])")
+
+	expectParseString(t, "a ?? b", "(a ?? b)")
+	expectParseString(t, "a ?? b ?? c", "((a ?? b) ?? c)")
+	expectParseString(t, "a || b ?? c && d", "((a || b) ?? (c && d))")
+	expectParseString(t, "a?.b ?? c", "(a?.b ?? c)")
+
+	expectParseError(t, "a?.1")
+	expectParseError(t, "a?.")
+}
+
+func TestParseIn(t *testing.T) {
+	expectParse(t, "a in b", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				binaryExpr(
+					ident("a", p(1, 1)),
+					ident("b", p(1, 6)),
+					token.In,
+					p(1, 3))))
+	})
+	expectParse(t, "a not in b", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				binaryExpr(
+					ident("a", p(1, 1)),
+					ident("b", p(1, 10)),
+					token.NotIn,
+					p(1, 3))))
+	})
+
+	expectParseString(t, "a in b == c", "((a in b) == c)")
+	expectParseString(t, "a + 1 not in b && c", "(((a + 1) not in b) && c)")
+	expectParseString(t, "!a in b", "((!a) in b)")
+	expectParseString(t, "if a in b { c }", "if (a in b) {c}")
+	expectParseString(t, "for x in a in b {}", "for _, x in (a in b) {}")
+	expectParseString(t, "for (x in y) {}", "for ((x in y)) {}")
+	expectParseString(t, "for i := 0; x in y; i++ {}",
+		"for i := 0 ; (x in y)  ; i++{}")
+	expectParseString(t, "not := 1; not in x", "not := 1; (not in x)")
+
+	expectParseError(t, "a not b")
+	expectParseError(t, "a in")
+}
+
+func TestParsePow(t *testing.T) {
+	expectParse(t, "a ** b", func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				binaryExpr(
+					ident("a", p(1, 1)),
+					ident("b", p(1, 6)),
+					token.Pow,
+					p(1, 3))))
+	})
+
+	expectParseString(t, "a ** b ** c", "(a ** (b ** c))")
+	expectParseString(t, "a * b ** c", "(a * (b ** c))")
+	expectParseString(t, "a ** b * c", "((a ** b) * c)")
+	expectParseString(t, "-a ** b", "((-a) ** b)")
+	expectParseString(t, "a **= b", "a **= b")
+
+	expectParseError(t, "a ** ")
+}
+
 func TestParseSemicolon(t *testing.T) {
 	expectParse(t, "1", func(p pfn) []Stmt {
 		return stmts(
@@ -1506,6 +1823,29 @@ func TestParseString(t *testing.T) {
 	})
 }
 
+func TestParseInterpString(t *testing.T) {
+	expectParse(t, `f"a${b}c"`, func(p pfn) []Stmt {
+		return stmts(
+			exprStmt(
+				&InterpStringLit{
+					Strings: []*StringLit{
+						stringLit("a", p(1, 1)),
+						stringLit("c", p(1, 7)),
+					},
+					Exprs: []Expr{ident("b", p(1, 6))},
+				}))
+	})
+
+	expectParseString(t, `f"a ${b + 1} \${c} ${d[0]}"`,
+		`f"a ${(b + 1)} \${c} ${d[0]}"`)
+	expectParseString(t, `f"${f"${x}"}"`, `f"${f"${x}"}"`)
+	expectParseString(t, `f"plain"`, `f"plain"`)
+
+	expectParseError(t, `f"${}"`)
+	expectParseError(t, `f"${a b}"`)
+	expectParseError(t, `f"${a"`)
+}
+
 func TestParseInt(t *testing.T) {
 	testCases := []string{
 		// All valid digits
@@ -1942,6 +2282,10 @@ func callExpr(
 		Ellipsis: ellipsis, Args: args}
 }
 
+func keywordArg(name *Ident, colonPos Pos, value Expr) *KeywordArg {
+	return &KeywordArg{Name: name, ColonPos: colonPos, Value: value}
+}
+
 func indexExpr(
 	x, index Expr,
 	lbrack, rbrack Pos,
@@ -1960,6 +2304,16 @@ func sliceExpr(
 	}
 }
 
+func sliceStepExpr(
+	x, low, high, step Expr,
+	lbrack, rbrack Pos,
+) *SliceExpr {
+	return &SliceExpr{
+		Expr: x, Low: low, High: high, Step: step,
+		LBrack: lbrack, RBrack: rbrack,
+	}
+}
+
 func errorExpr(
 	pos Pos,
 	x Expr,
@@ -2019,6 +2373,10 @@ func equalStmt(t *testing.T, expected, actual Stmt) {
 			actual.(*IncDecStmt).Token)
 		require.Equal(t, expected.TokenPos,
 			actual.(*IncDecStmt).TokenPos)
+	case *LabeledStmt:
+		equalExpr(t, expected.Label, actual.(*LabeledStmt).Label)
+		require.Equal(t, expected.Colon, actual.(*LabeledStmt).Colon)
+		equalStmt(t, expected.Stmt, actual.(*LabeledStmt).Stmt)
 	case *ForStmt:
 		equalStmt(t, expected.Init, actual.(*ForStmt).Init)
 		equalExpr(t, expected.Cond, actual.(*ForStmt).Cond)
@@ -2036,6 +2394,16 @@ func equalStmt(t *testing.T, expected, actual Stmt) {
 			actual.(*ForInStmt).Body)
 		require.Equal(t, expected.ForPos,
 			actual.(*ForInStmt).ForPos)
+	case *ConstStmt:
+		require.Equal(t, expected.ConstPos, actual.(*ConstStmt).ConstPos)
+		equalExpr(t, expected.Name, actual.(*ConstStmt).Name)
+		equalExpr(t, expected.Value, actual.(*ConstStmt).Value)
+	case *DeferStmt:
+		require.Equal(t, expected.DeferPos, actual.(*DeferStmt).DeferPos)
+		equalExpr(t, expected.Call, actual.(*DeferStmt).Call)
+	case *YieldStmt:
+		require.Equal(t, expected.YieldPos, actual.(*YieldStmt).YieldPos)
+		equalExpr(t, expected.Value, actual.(*YieldStmt).Value)
 	case *ReturnStmt:
 		equalExpr(t, expected.Result,
 			actual.(*ReturnStmt).Result)
@@ -2087,6 +2455,13 @@ func equalExpr(t *testing.T, expected, actual Expr) {
 			actual.(*CharLit).Value)
 		require.Equal(t, int(expected.ValuePos),
 			int(actual.(*CharLit).ValuePos))
+	case *InterpStringLit:
+		require.Equal(t, len(expected.Strings),
+			len(actual.(*InterpStringLit).Strings))
+		for i, str := range expected.Strings {
+			equalExpr(t, str, actual.(*InterpStringLit).Strings[i])
+		}
+		equalExprs(t, expected.Exprs, actual.(*InterpStringLit).Exprs)
 	case *StringLit:
 		require.Equal(t, expected.Value,
 			actual.(*StringLit).Value)
@@ -2099,6 +2474,11 @@ func equalExpr(t *testing.T, expected, actual Expr) {
 			actual.(*ArrayLit).RBrack)
 		equalExprs(t, expected.Elements,
 			actual.(*ArrayLit).Elements)
+	case *SpreadExpr:
+		require.Equal(t, expected.Ellipsis,
+			actual.(*SpreadExpr).Ellipsis)
+		equalExpr(t, expected.Expr,
+			actual.(*SpreadExpr).Expr)
 	case *MapLit:
 		require.Equal(t, expected.LBrace,
 			actual.(*MapLit).LBrace)
@@ -2123,6 +2503,7 @@ func equalExpr(t *testing.T, expected, actual Expr) {
 		require.Equal(t, expected.TokenPos,
 			actual.(*UnaryExpr).TokenPos)
 	case *FuncLit:
+		require.Equal(t, expected.Arrow, actual.(*FuncLit).Arrow)
 		equalFuncType(t, expected.Type,
 			actual.(*FuncLit).Type)
 		equalStmt(t, expected.Body,
@@ -2136,6 +2517,15 @@ func equalExpr(t *testing.T, expected, actual Expr) {
 			actual.(*CallExpr).RParen)
 		equalExprs(t, expected.Args,
 			actual.(*CallExpr).Args)
+		require.Equal(t, len(expected.KeywordArgs),
+			len(actual.(*CallExpr).KeywordArgs))
+		for i, kwarg := range expected.KeywordArgs {
+			equalExpr(t, kwarg, actual.(*CallExpr).KeywordArgs[i])
+		}
+	case *KeywordArg:
+		equalExpr(t, expected.Name, actual.(*KeywordArg).Name)
+		require.Equal(t, expected.ColonPos, actual.(*KeywordArg).ColonPos)
+		equalExpr(t, expected.Value, actual.(*KeywordArg).Value)
 	case *ParenExpr:
 		equalExpr(t, expected.Expr,
 			actual.(*ParenExpr).Expr)
@@ -2159,15 +2549,21 @@ func equalExpr(t *testing.T, expected, actual Expr) {
 			actual.(*SliceExpr).Low)
 		equalExpr(t, expected.High,
 			actual.(*SliceExpr).High)
+		equalExpr(t, expected.Step,
+			actual.(*SliceExpr).Step)
 		require.Equal(t, expected.LBrack,
 			actual.(*SliceExpr).LBrack)
 		require.Equal(t, expected.RBrack,
 			actual.(*SliceExpr).RBrack)
+	case *OptionalChainExpr:
+		equalExpr(t, expected.Expr, actual.(*OptionalChainExpr).Expr)
 	case *SelectorExpr:
 		equalExpr(t, expected.Expr,
 			actual.(*SelectorExpr).Expr)
 		equalExpr(t, expected.Sel,
 			actual.(*SelectorExpr).Sel)
+		require.Equal(t, expected.Optional,
+			actual.(*SelectorExpr).Optional)
 	case *ImportExpr:
 		require.Equal(t, expected.ModuleName,
 			actual.(*ImportExpr).ModuleName)
@@ -2204,6 +2600,13 @@ func equalFuncType(t *testing.T, expected, actual *FuncType) {
 	require.Equal(t, expected.Params.LParen, actual.Params.LParen)
 	require.Equal(t, expected.Params.RParen, actual.Params.RParen)
 	equalIdents(t, expected.Params.List, actual.Params.List)
+	require.Equal(t, expected.Params.NumDefaults(),
+		actual.Params.NumDefaults())
+	for i := range expected.Params.List {
+		if def := expected.Params.Default(i); def != nil {
+			equalExpr(t, def, actual.Params.Default(i))
+		}
+	}
 }
 
 func equalIdents(t *testing.T, expected, actual []*Ident) {
@@ -2236,6 +2639,7 @@ func equalMapElements(
 		require.Equal(t, expected[i].Key, actual[i].Key)
 		require.Equal(t, expected[i].KeyPos, actual[i].KeyPos)
 		require.Equal(t, expected[i].ColonPos, actual[i].ColonPos)
+		require.Equal(t, expected[i].Ellipsis, actual[i].Ellipsis)
 		equalExpr(t, expected[i].Value, actual[i].Value)
 	}
 }
@@ -35,6 +35,7 @@ type Scanner struct {
 	insertSemi   bool                // insert a semicolon before next newline
 	errorHandler ScannerErrorHandler // error reporting; or nil
 	errorCount   int                 // number of errors encountered
+	interpDepth  []int               // brace depth of each open string interpolation
 	mode         ScanMode
 }
 
@@ -85,6 +86,14 @@ func (s *Scanner) Scan() (
 
 	// determine token value
 	switch ch := s.ch; {
+	case ch == 'f' && s.peek() == '"':
+		offs := s.offset
+		s.next()
+		s.next() // consume 'f"'
+		var embed bool
+		tok = token.InterpBegin
+		literal, embed = s.scanInterpString(offs)
+		insertSemi = !embed
 	case isLetter(ch):
 		literal = s.scanIdentifier()
 		tok = token.Lookup(literal)
@@ -134,7 +143,16 @@ func (s *Scanner) Scan() (
 		case ',':
 			tok = token.Comma
 		case '?':
-			tok = token.Question
+			switch {
+			case s.ch == '?':
+				s.next()
+				tok = token.Coalesce
+			case s.ch == '.' && !isDigit(rune(s.peek())):
+				s.next()
+				tok = token.QuestionPeriod
+			default:
+				tok = token.Question
+			}
 		case ';':
 			tok = token.Semicolon
 			literal = ";"
@@ -149,8 +167,24 @@ func (s *Scanner) Scan() (
 			insertSemi = true
 			tok = token.RBrack
 		case '{':
+			if n := len(s.interpDepth); n > 0 {
+				s.interpDepth[n-1]++
+			}
 			tok = token.LBrace
 		case '}':
+			n := len(s.interpDepth)
+			if n > 0 && s.interpDepth[n-1] == 0 {
+				// end of the embedded expression
+				s.interpDepth = s.interpDepth[:n-1]
+				var embed bool
+				tok = token.InterpCont
+				literal, embed = s.scanInterpString(s.file.Offset(pos))
+				insertSemi = !embed
+				break
+			}
+			if n > 0 {
+				s.interpDepth[n-1]--
+			}
 			insertSemi = true
 			tok = token.RBrace
 		case '+':
@@ -164,7 +198,8 @@ func (s *Scanner) Scan() (
 				insertSemi = true
 			}
 		case '*':
-			tok = s.switch2(token.Mul, token.MulAssign)
+			tok = s.switch4(token.Mul, token.MulAssign, '*', token.Pow,
+				token.PowAssign)
 		case '/':
 			if s.ch == '/' || s.ch == '*' {
 				// comment
@@ -198,7 +233,12 @@ func (s *Scanner) Scan() (
 			tok = s.switch4(token.Greater, token.GreaterEq, '>',
 				token.Shr, token.ShrAssign)
 		case '=':
-			tok = s.switch2(token.Assign, token.Equal)
+			if s.ch == '>' {
+				s.next()
+				tok = token.Arrow
+			} else {
+				tok = s.switch2(token.Assign, token.Equal)
+			}
 		case '!':
 			tok = s.switch2(token.Not, token.NotEqual)
 		case '&':
@@ -535,6 +575,37 @@ func (s *Scanner) scanString() string {
 	return string(s.src[offs:s.offset])
 }
 
+// scanInterpString scans a part of an interpolated string literal up to and
+// including either the closing quote or the opening "${" of the next embedded
+// expression. It reports whether an embedded expression follows.
+func (s *Scanner) scanInterpString(offs int) (lit string, embed bool) {
+	for {
+		ch := s.ch
+		if ch == '\n' || ch < 0 {
+			s.error(offs, "string literal not terminated")
+			break
+		}
+		s.next()
+		if ch == '"' {
+			break
+		}
+		if ch == '$' && s.ch == '{' {
+			s.next()
+			s.interpDepth = append(s.interpDepth, 0)
+			embed = true
+			break
+		}
+		if ch == '\\' {
+			if s.ch == '$' {
+				s.next()
+			} else {
+				s.scanEscape('"')
+			}
+		}
+	}
+	return string(s.src[offs:s.offset]), embed
+}
+
 func (s *Scanner) scanRawString() string {
 	offs := s.offset - 1 // '`' opening already consumed
 
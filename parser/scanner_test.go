@@ -98,6 +98,9 @@ func TestScanner_Scan(t *testing.T) {
 		{token.GreaterEq, ">="},
 		{token.Define, ":="},
 		{token.Ellipsis, "..."},
+		{token.Arrow, "=>"},
+		{token.Pow, "**"},
+		{token.PowAssign, "**="},
 		{token.LParen, "("},
 		{token.LBrack, "["},
 		{token.LBrace, "{"},
@@ -108,6 +111,8 @@ func TestScanner_Scan(t *testing.T) {
 		{token.RBrace, "}"},
 		{token.Semicolon, ";"},
 		{token.Colon, ":"},
+		{token.Coalesce, "??"},
+		{token.QuestionPeriod, "?."},
 		{token.Break, "break"},
 		{token.Continue, "continue"},
 		{token.Else, "else"},
@@ -116,6 +121,8 @@ func TestScanner_Scan(t *testing.T) {
 		{token.If, "if"},
 		{token.Return, "return"},
 		{token.Export, "export"},
+		{token.Defer, "defer"},
+		{token.Yield, "yield"},
 	}
 
 	// combine
@@ -194,6 +201,29 @@ func TestScanner_Scan(t *testing.T) {
 		parser.DontInsertSemis, expectedSkipComments...)
 }
 
+func TestScanner_ScanInterpString(t *testing.T) {
+	scanExpect(t, `f"a ${b} c"`, parser.DontInsertSemis,
+		scanResult{token.InterpBegin, `f"a ${`, 1, 1},
+		scanResult{token.Ident, "b", 1, 7},
+		scanResult{token.InterpCont, `} c"`, 1, 8})
+
+	scanExpect(t, `f"${ {x: 1}.x }${"}"}\${"`, parser.DontInsertSemis,
+		scanResult{token.InterpBegin, `f"${`, 1, 1},
+		scanResult{token.LBrace, "", 1, 6},
+		scanResult{token.Ident, "x", 1, 7},
+		scanResult{token.Colon, "", 1, 8},
+		scanResult{token.Int, "1", 1, 10},
+		scanResult{token.RBrace, "", 1, 11},
+		scanResult{token.Period, "", 1, 12},
+		scanResult{token.Ident, "x", 1, 13},
+		scanResult{token.InterpCont, "}${", 1, 15},
+		scanResult{token.String, `"}"`, 1, 18},
+		scanResult{token.InterpCont, `}\${"`, 1, 21})
+
+	scanExpect(t, `f"plain"`, parser.DontInsertSemis,
+		scanResult{token.InterpBegin, `f"plain"`, 1, 1})
+}
+
 func TestStripCR(t *testing.T) {
 	for _, tc := range []struct {
 		input  string
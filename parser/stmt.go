@@ -124,6 +124,51 @@ func (s *BranchStmt) String() string {
 	return s.Token.String() + label
 }
 
+// ConstStmt represents a constant declaration.
+type ConstStmt struct {
+	ConstPos Pos
+	Name     *Ident
+	Value    Expr
+}
+
+func (s *ConstStmt) stmtNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (s *ConstStmt) Pos() Pos {
+	return s.ConstPos
+}
+
+// End returns the position of first character immediately after the node.
+func (s *ConstStmt) End() Pos {
+	return s.Value.End()
+}
+
+func (s *ConstStmt) String() string {
+	return "const " + s.Name.String() + " = " + s.Value.String()
+}
+
+// DeferStmt represents a defer statement.
+type DeferStmt struct {
+	DeferPos Pos
+	Call     *CallExpr
+}
+
+func (s *DeferStmt) stmtNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (s *DeferStmt) Pos() Pos {
+	return s.DeferPos
+}
+
+// End returns the position of first character immediately after the node.
+func (s *DeferStmt) End() Pos {
+	return s.Call.End()
+}
+
+func (s *DeferStmt) String() string {
+	return "defer " + s.Call.String()
+}
+
 // EmptyStmt represents an empty statement.
 type EmptyStmt struct {
 	Semicolon Pos
@@ -320,6 +365,29 @@ func (s *IncDecStmt) String() string {
 	return s.Expr.String() + s.Token.String()
 }
 
+// LabeledStmt represents a labeled statement.
+type LabeledStmt struct {
+	Label *Ident
+	Colon Pos
+	Stmt  Stmt
+}
+
+func (s *LabeledStmt) stmtNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (s *LabeledStmt) Pos() Pos {
+	return s.Label.Pos()
+}
+
+// End returns the position of first character immediately after the node.
+func (s *LabeledStmt) End() Pos {
+	return s.Stmt.End()
+}
+
+func (s *LabeledStmt) String() string {
+	return s.Label.String() + ": " + s.Stmt.String()
+}
+
 // ReturnStmt represents a return statement.
 type ReturnStmt struct {
 	ReturnPos Pos
@@ -347,3 +415,25 @@ func (s *ReturnStmt) String() string {
 	}
 	return "return"
 }
+
+// YieldStmt represents a yield statement.
+type YieldStmt struct {
+	YieldPos Pos
+	Value    Expr
+}
+
+func (s *YieldStmt) stmtNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (s *YieldStmt) Pos() Pos {
+	return s.YieldPos
+}
+
+// End returns the position of first character immediately after the node.
+func (s *YieldStmt) End() Pos {
+	return s.Value.End()
+}
+
+func (s *YieldStmt) String() string {
+	return "yield " + s.Value.String()
+}
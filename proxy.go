@@ -0,0 +1,374 @@
+package tengo
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Proxy is an object that gives scripts access to a Go map, slice or struct
+// by reference: unlike with FromValue, changes made by a script are made to
+// the Go value itself, and the Go value is not copied.
+//
+// Elements and fields are converted when they are read: maps, slices,
+// arrays, structs and pointers to them become proxies as well, and other
+// values are converted as FromValue does. Values assigned by a script are
+// converted as ToValue does. Struct fields are named as FromValue names
+// them, and exported methods of a struct can be called like WrapFunc
+// functions; their results are converted like elements, so a method can
+// return a proxy. A slice proxy cannot change the length of the slice.
+type Proxy struct {
+	ObjectImpl
+
+	// value is a map, a slice, or an addressable array or struct.
+	value reflect.Value
+}
+
+// NewProxy returns a proxy for v, which must be a map, a slice, or a
+// non-nil pointer to a struct, an array or a slice.
+func NewProxy(v any) (*Proxy, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice:
+		return &Proxy{value: rv}, nil
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil, fmt.Errorf("cannot proxy %T: nil pointer", v)
+		}
+		switch rv.Elem().Kind() {
+		case reflect.Struct, reflect.Array, reflect.Slice:
+			return &Proxy{value: rv.Elem()}, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot proxy %T", v)
+}
+
+// Value returns the Go value of the proxy: the map or slice, or a pointer
+// to the struct or array.
+func (o *Proxy) Value() any {
+	switch o.value.Kind() {
+	case reflect.Struct, reflect.Array:
+		return o.value.Addr().Interface()
+	}
+	return o.value.Interface()
+}
+
+// TypeName returns the name of the type.
+func (o *Proxy) TypeName() string {
+	return "proxy"
+}
+
+func (o *Proxy) String() string {
+	return fmt.Sprintf("<proxy:%s>", o.value.Type())
+}
+
+// Copy returns a copy of the Go value converted as FromValue does. Unlike
+// the proxy, the copy does not refer to the Go value.
+func (o *Proxy) Copy() Object {
+	res, err := fromValue(o.value)
+	if err != nil {
+		return o
+	}
+	return res
+}
+
+// IsFalsy returns true if the map, slice or array is empty.
+func (o *Proxy) IsFalsy() bool {
+	if o.value.Kind() == reflect.Struct {
+		return false
+	}
+	return o.value.Len() == 0
+}
+
+// Equals returns true if x is a proxy of the same Go value.
+func (o *Proxy) Equals(x Object) bool {
+	p, ok := x.(*Proxy)
+	if !ok || p.value.Type() != o.value.Type() {
+		return false
+	}
+	switch o.value.Kind() {
+	case reflect.Map:
+		return p.value.UnsafePointer() == o.value.UnsafePointer()
+	case reflect.Slice:
+		return p.value.UnsafePointer() == o.value.UnsafePointer() &&
+			p.value.Len() == o.value.Len()
+	}
+	return p.value.Addr().UnsafePointer() == o.value.Addr().UnsafePointer()
+}
+
+// IndexGet returns an element of the map, slice or array, or a field or a
+// method of the struct. Negative slice and array indexes count from the
+// end. It returns undefined if there is no such element.
+func (o *Proxy) IndexGet(index Object) (Object, error) {
+	switch o.value.Kind() {
+	case reflect.Map:
+		k := reflect.New(o.value.Type().Key()).Elem()
+		if err := toValue(index, k); err != nil {
+			return nil, ErrInvalidIndexType
+		}
+		v := o.value.MapIndex(k)
+		if !v.IsValid() {
+			return UndefinedValue, nil
+		}
+		return proxyValue(v)
+	case reflect.Slice, reflect.Array:
+		i, ok := o.index(index)
+		if !ok {
+			return nil, ErrInvalidIndexType
+		}
+		if i < 0 {
+			return UndefinedValue, nil
+		}
+		return proxyValue(o.value.Index(i))
+	}
+
+	name, ok := index.(*String)
+	if !ok {
+		return nil, ErrInvalidIndexType
+	}
+	if f, ok := o.field(name.Value); ok {
+		v, ok := fieldByIndex(o.value, f.index)
+		if !ok {
+			return UndefinedValue, nil
+		}
+		return proxyValue(v)
+	}
+	m := o.value.Addr().MethodByName(name.Value)
+	if !m.IsValid() {
+		return UndefinedValue, nil
+	}
+	fn, err := wrapFunc(m.Interface(), proxyValue)
+	if err != nil {
+		return nil, err
+	}
+	fn.Name = name.Value
+	return fn, nil
+}
+
+// IndexSet sets an element of the map, slice or array, or a field of the
+// struct. Negative slice and array indexes count from the end.
+func (o *Proxy) IndexSet(index, value Object) error {
+	var dst reflect.Value
+	switch o.value.Kind() {
+	case reflect.Map:
+		if o.value.IsNil() {
+			return ErrNotIndexAssignable
+		}
+		k := reflect.New(o.value.Type().Key()).Elem()
+		if err := toValue(index, k); err != nil {
+			return ErrInvalidIndexType
+		}
+		v := reflect.New(o.value.Type().Elem()).Elem()
+		if err := toValue(value, v); err != nil {
+			return ErrInvalidIndexValueType
+		}
+		o.value.SetMapIndex(k, v)
+		return nil
+	case reflect.Slice, reflect.Array:
+		i, ok := o.index(index)
+		if !ok {
+			return ErrInvalidIndexType
+		}
+		if i < 0 {
+			return ErrIndexOutOfBounds
+		}
+		dst = o.value.Index(i)
+	default:
+		name, ok := index.(*String)
+		if !ok {
+			return ErrInvalidIndexType
+		}
+		f, ok := o.field(name.Value)
+		if !ok {
+			return fmt.Errorf("no field '%s' in %s", name.Value,
+				o.value.Type())
+		}
+		dst = fieldByIndexAlloc(o.value, f.index)
+	}
+
+	if !dst.CanSet() { // in an unexported embedded struct
+		return ErrNotIndexAssignable
+	}
+
+	// convert into a new value, so that dst is unchanged on errors
+	v := reflect.New(dst.Type()).Elem()
+	if err := toValue(value, v); err != nil {
+		return ErrInvalidIndexValueType
+	}
+	dst.Set(v)
+	return nil
+}
+
+// Iterate creates an iterator over the elements of the map, slice or array,
+// or the fields of the struct.
+func (o *Proxy) Iterate() Iterator {
+	it := &ProxyIterator{proxy: o}
+	switch o.value.Kind() {
+	case reflect.Map:
+		it.keys = o.value.MapKeys()
+		it.l = len(it.keys)
+	case reflect.Slice, reflect.Array:
+		it.l = o.value.Len()
+	default:
+		it.fields = structFields(o.value.Type())
+		it.l = len(it.fields)
+	}
+	return it
+}
+
+// CanIterate returns whether the Object can be Iterated.
+func (o *Proxy) CanIterate() bool {
+	return true
+}
+
+// Len returns the number of elements of the map, slice or array, or the
+// number of fields of the struct.
+func (o *Proxy) Len() int {
+	if o.value.Kind() == reflect.Struct {
+		return len(structFields(o.value.Type()))
+	}
+	return o.value.Len()
+}
+
+// index returns the slice or array index of an index object, or -1 if it
+// is out of bounds.
+func (o *Proxy) index(index Object) (int, bool) {
+	i, ok := index.(*Int)
+	if !ok {
+		return 0, false
+	}
+	n := int64(o.value.Len())
+	idx := i.Value
+	if idx < 0 {
+		idx += n
+	}
+	if idx < 0 || idx >= n {
+		return -1, true
+	}
+	return int(idx), true
+}
+
+func (o *Proxy) field(name string) (structField, bool) {
+	for _, f := range structFields(o.value.Type()) {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return structField{}, false
+}
+
+// proxyValue converts an element or a field v: maps, slices, and
+// addressable arrays and structs become proxies, and other values are
+// converted by FromValue.
+func proxyValue(v reflect.Value) (Object, error) {
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Pointer && !v.IsNil() && v.Type() != bigIntType &&
+		!v.Type().Implements(objectType) {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Elem() != emptyType {
+			return &Proxy{value: v}, nil
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return &Proxy{value: v}, nil
+		}
+	case reflect.Array, reflect.Struct:
+		if v.CanAddr() && v.Type() != timeType {
+			return &Proxy{value: v}, nil
+		}
+	}
+	return fromValue(v)
+}
+
+// ProxyIterator is an iterator for a proxy.
+type ProxyIterator struct {
+	ObjectImpl
+	proxy  *Proxy
+	keys   []reflect.Value
+	fields []structField
+	i      int
+	l      int
+}
+
+// TypeName returns the name of the type.
+func (i *ProxyIterator) TypeName() string {
+	return "proxy-iterator"
+}
+
+func (i *ProxyIterator) String() string {
+	return "<proxy-iterator>"
+}
+
+// IsFalsy returns true if the value of the type is falsy.
+func (i *ProxyIterator) IsFalsy() bool {
+	return true
+}
+
+// Equals returns true if the value of the type is equal to the value of
+// another object.
+func (i *ProxyIterator) Equals(Object) bool {
+	return false
+}
+
+// Copy returns a copy of the type.
+func (i *ProxyIterator) Copy() Object {
+	return &ProxyIterator{
+		proxy:  i.proxy,
+		keys:   i.keys,
+		fields: i.fields,
+		i:      i.i,
+		l:      i.l,
+	}
+}
+
+// Next returns true if there are more elements to iterate.
+func (i *ProxyIterator) Next() bool {
+	i.i++
+	return i.i <= i.l
+}
+
+// Key returns the key or index value of the current element.
+func (i *ProxyIterator) Key() Object {
+	switch {
+	case i.keys != nil:
+		k, err := fromValue(i.keys[i.i-1])
+		if err != nil {
+			return UndefinedValue
+		}
+		return k
+	case i.fields != nil:
+		return &String{Value: i.fields[i.i-1].name}
+	}
+	return &Int{Value: int64(i.i - 1)}
+}
+
+// Value returns the value of the current element.
+func (i *ProxyIterator) Value() Object {
+	v := i.proxy.value
+	switch {
+	case i.keys != nil:
+		v = v.MapIndex(i.keys[i.i-1])
+		if !v.IsValid() { // deleted during the iteration
+			return UndefinedValue
+		}
+	case i.fields != nil:
+		var ok bool
+		if v, ok = fieldByIndex(v, i.fields[i.i-1].index); !ok {
+			return UndefinedValue
+		}
+	default:
+		if i.i > v.Len() {
+			return UndefinedValue
+		}
+		v = v.Index(i.i - 1)
+	}
+	res, err := proxyValue(v)
+	if err != nil {
+		return UndefinedValue
+	}
+	return res
+}
@@ -0,0 +1,158 @@
+package tengo_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shelepuginivan/tengo"
+	"github.com/shelepuginivan/tengo/require"
+)
+
+type proxyItem struct {
+	Name  string `tengo:"name"`
+	Count int    `tengo:"count"`
+}
+
+type proxyStore struct {
+	Title  string                `tengo:"title"`
+	Items  []proxyItem           `tengo:"items"`
+	Index  map[string]*proxyItem `tengo:"index"`
+	Tags   [2]string             `tengo:"tags"`
+	Owner  *proxyItem            `tengo:"owner"`
+	hidden int
+}
+
+func (s *proxyStore) Add(name string, count int) int {
+	s.Items = append(s.Items, proxyItem{Name: name, Count: count})
+	return len(s.Items)
+}
+
+func (s *proxyStore) Find(name string) (*proxyItem, error) {
+	for i := range s.Items {
+		if s.Items[i].Name == name {
+			return &s.Items[i], nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (s *proxyStore) Caller(ctx context.Context) string {
+	v, _ := ctx.Value(wrapCtxKey{}).(string)
+	return v
+}
+
+func TestProxy(t *testing.T) {
+	store := &proxyStore{
+		Title: "a",
+		Items: []proxyItem{{Name: "x", Count: 1}, {Name: "y", Count: 2}},
+		Index: map[string]*proxyItem{"z": {Name: "z", Count: 3}},
+		Tags:  [2]string{"t1", "t2"},
+	}
+	expectProxy(t, store, `
+p.title = "b"
+p.items[0].count += 10
+p.items[-1].name = "w"
+p.index.z.count = 30
+p.index.v = {name: "v", count: 4}
+p.tags[1] = "t3"
+p.owner = {name: "o"}
+out = [p.title, len(p.items), p.items[1].name, p.items[5], p.index.q]
+`, ARR{"b", 2, "w", tengo.UndefinedValue, tengo.UndefinedValue})
+	require.Equal(t, "b", store.Title)
+	require.Equal(t, 11, store.Items[0].Count)
+	require.Equal(t, "w", store.Items[1].Name)
+	require.Equal(t, 30, store.Index["z"].Count)
+	require.Equal(t, "v", store.Index["v"].Name)
+	require.Equal(t, "t3", store.Tags[1])
+	require.Equal(t, "o", store.Owner.Name)
+
+	// methods
+	expectProxy(t, store, `
+n := p.Add("u", 5)
+f := p.Find("u")
+f.count = 6
+out = [n, p.items[2].count, is_error(p.Find("none")), p.Caller()]
+`, ARR{3, 6, true, ""})
+	require.Equal(t, 6, store.Items[2].Count)
+
+	// iteration
+	expectProxy(t, store, `
+out = []
+for k, v in p.index { if k == "z" { out = append(out, v.count) } }
+for i, v in p.items { out = append(out, i, v.name) }
+for k, v in p.owner { out = append(out, k, v) }
+`, ARR{30, 0, "x", 1, "w", 2, "u", "name", "o", "count", 0})
+
+	// copies are detached from the Go value
+	expectProxy(t, store, `
+c := copy(p.owner)
+c.name = "c"
+out = [type_name(c), c.name, p.owner.name]
+`, ARR{"map", "c", "o"})
+
+	// errors
+	expectProxyError(t, store, `p.title = 1`,
+		"invalid index value type: int")
+	expectProxyError(t, store, `p.items[10] = {}`, "index out of bounds")
+	expectProxyError(t, store, `p.items["a"] = {}`, "invalid index type")
+	expectProxyError(t, store, `p.missing = 1`,
+		"no field 'missing' in tengo_test.proxyStore")
+	expectProxyError(t, store, `p.hidden = 1`,
+		"no field 'hidden' in tengo_test.proxyStore")
+
+	// plain maps and slices
+	m := map[string]int{"a": 1}
+	expectProxy(t, m, `p.b = p.a + 1; out = len(p)`, 2)
+	require.Equal(t, 2, m["b"])
+	s := []float64{1, 2}
+	expectProxy(t, s, `p[0] = 0.5; out = p[1]`, 2.0)
+	require.True(t, s[0] == 0.5)
+}
+
+func TestProxy_Value(t *testing.T) {
+	store := &proxyStore{}
+	p, err := tengo.NewProxy(store)
+	require.NoError(t, err)
+	require.True(t, p.Value() == any(store))
+	require.True(t, tengo.ToInterface(p) == any(store))
+
+	s, err := tengo.ToValue[*proxyStore](p)
+	require.NoError(t, err)
+	require.True(t, s == store)
+
+	// wrapped functions receive the Go value by reference
+	f, err := tengo.WrapFunc(func(s *proxyStore) { s.Title = "set" })
+	require.NoError(t, err)
+	_, err = f.Call(p)
+	require.NoError(t, err)
+	require.Equal(t, "set", store.Title)
+
+	q, err := tengo.NewProxy(store)
+	require.NoError(t, err)
+	require.True(t, p.Equals(q))
+
+	_, err = tengo.NewProxy(proxyStore{})
+	require.Error(t, err)
+	_, err = tengo.NewProxy((*proxyStore)(nil))
+	require.Error(t, err)
+	_, err = tengo.NewProxy(1)
+	require.Error(t, err)
+}
+
+func expectProxy(t *testing.T, v any, input string, expected any) {
+	p, err := tengo.NewProxy(v)
+	require.NoError(t, err)
+	expectRun(t, input, Opts().Symbol("p", p).SkipSecondPass(), expected)
+}
+
+func expectProxyError(t *testing.T, v any, input, expected string) {
+	p, err := tengo.NewProxy(v)
+	require.NoError(t, err)
+	s := tengo.NewScript([]byte(input))
+	require.NoError(t, s.Add("p", p))
+	_, err = s.CompileRun()
+	require.True(t, err != nil && strings.Contains(err.Error(), expected),
+		"unexpected error: %v", err)
+}
@@ -0,0 +1,207 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shelepuginivan/tengo"
+)
+
+// Rule is a named rule: when its condition is true for the facts, the rule
+// fires and its action is called.
+type Rule struct {
+	// Name is the name of the rule. It must be unique in a rule set.
+	Name string
+
+	// Condition is a Tengo expression that uses the facts. The rule fires if
+	// the condition is truthy.
+	Condition string
+
+	// Action is called with the facts when the rule fires. It is optional.
+	Action func(ctx context.Context, facts map[string]any) error
+
+	// Priority orders the evaluation of the rules: rules with a higher
+	// priority are evaluated first, and rules with the same priority are
+	// evaluated in the order they were added.
+	Priority int
+
+	// Stop stops the evaluation of the remaining rules when the rule fires.
+	Stop bool
+}
+
+// Options are the options of RuleSet.Evaluate.
+type Options struct {
+	// FirstMatch stops the evaluation after the first rule that fires.
+	FirstMatch bool
+
+	// ContinueOnError evaluates the remaining rules when the condition or
+	// the action of a rule fails, instead of returning the error. The error
+	// is reported in the result of the rule.
+	ContinueOnError bool
+}
+
+// Result is the result of the evaluation of a rule.
+type Result struct {
+	// Rule is the name of the rule.
+	Rule string
+
+	// Fired is true if the condition of the rule was true.
+	Fired bool
+
+	// Duration is the time spent on the condition and the action.
+	Duration time.Duration
+
+	// Err is the error of the condition or the action, if any.
+	Err error
+}
+
+// Report is the report of an evaluation of a rule set.
+type Report struct {
+	// Results are the results of the evaluated rules, in the evaluation
+	// order.
+	Results []Result
+
+	// Duration is the time spent on the evaluation.
+	Duration time.Duration
+}
+
+// Fired returns the names of the rules that fired, in the evaluation order.
+func (r *Report) Fired() []string {
+	var names []string
+	for _, res := range r.Results {
+		if res.Fired {
+			names = append(names, res.Rule)
+		}
+	}
+	return names
+}
+
+// RuleSet is a set of compiled rules. RuleSet is safe for concurrent use.
+type RuleSet struct {
+	rules     []*compiledRule
+	factNames []string
+}
+
+type compiledRule struct {
+	Rule
+	expr *tengo.Expr
+}
+
+// Compile compiles rules whose conditions use the facts named factNames.
+// Each condition is compiled once, and a condition that does not compile,
+// e.g. because it uses an identifier that is neither a fact nor a builtin
+// function, is an error.
+func Compile(rules []Rule, factNames []string) (*RuleSet, error) {
+	s := &RuleSet{
+		rules:     make([]*compiledRule, 0, len(rules)),
+		factNames: append([]string(nil), factNames...),
+	}
+	names := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		switch {
+		case r.Name == "":
+			return nil, errors.New("rule without a name")
+		case names[r.Name]:
+			return nil, fmt.Errorf("duplicate rule '%s'", r.Name)
+		}
+		names[r.Name] = true
+
+		expr, err := tengo.CompileExpr(r.Condition, factNames)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': %w", r.Name, err)
+		}
+		s.rules = append(s.rules, &compiledRule{Rule: r, expr: expr})
+	}
+	sort.SliceStable(s.rules, func(i, j int) bool {
+		return s.rules[i].Priority > s.rules[j].Priority
+	})
+	return s, nil
+}
+
+// Evaluate evaluates the rules against facts in the order of their
+// priorities, and reports the rules that were evaluated. Facts that are not
+// named in the fact names of the rule set are ignored by the conditions, and
+// the missing ones are undefined. The facts are converted to Tengo objects
+// once and shared by all the conditions, and a fact that cannot be converted
+// is an error. Actions are called with all the facts. If a rule fails, the
+// report of the rules evaluated so far is returned with the error, unless
+// opts.ContinueOnError is set.
+func (s *RuleSet) Evaluate(
+	ctx context.Context,
+	facts map[string]any,
+	opts Options,
+) (*Report, error) {
+	report := &Report{Results: make([]Result, 0, len(s.rules))}
+	start := time.Now()
+	defer func() {
+		report.Duration = time.Since(start)
+	}()
+
+	known, err := s.knownFacts(facts)
+	if err != nil {
+		return report, err
+	}
+	for _, r := range s.rules {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		res := r.evaluate(ctx, known, facts)
+		report.Results = append(report.Results, res)
+		if res.Err != nil && !opts.ContinueOnError {
+			return report, fmt.Errorf("rule '%s': %w", r.Name, res.Err)
+		}
+		if res.Fired && (r.Stop || opts.FirstMatch) {
+			break
+		}
+	}
+	return report, nil
+}
+
+// knownFacts returns the facts named in the fact names of the rule set,
+// converted to Tengo objects: the compiled conditions cannot be evaluated
+// with other variables.
+func (s *RuleSet) knownFacts(facts map[string]any) (map[string]any, error) {
+	known := make(map[string]any, len(s.factNames))
+	for _, name := range s.factNames {
+		v, ok := facts[name]
+		if !ok {
+			continue
+		}
+		o, err := tengo.FromInterface(v)
+		if err != nil {
+			return nil, fmt.Errorf("fact '%s': %w", name, err)
+		}
+		known[name] = o
+	}
+	return known, nil
+}
+
+func (r *compiledRule) evaluate(
+	ctx context.Context,
+	known, facts map[string]any,
+) (res Result) {
+	res.Rule = r.Name
+	start := time.Now()
+	defer func() {
+		res.Duration = time.Since(start)
+	}()
+
+	o, err := r.expr.EvalObject(ctx, known)
+	if err != nil {
+		res.Err = err
+		return
+	}
+	if o.IsFalsy() {
+		return
+	}
+
+	res.Fired = true
+	if r.Action != nil {
+		res.Err = r.Action(ctx, facts)
+	}
+	return
+}
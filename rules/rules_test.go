@@ -0,0 +1,159 @@
+package rules_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shelepuginivan/tengo/require"
+	"github.com/shelepuginivan/tengo/rules"
+)
+
+func TestRuleSet(t *testing.T) {
+	ctx := context.Background()
+	var actions []string
+	action := func(name string) func(context.Context, map[string]any) error {
+		return func(_ context.Context, facts map[string]any) error {
+			actions = append(actions, name+":"+facts["user"].(string))
+			return nil
+		}
+	}
+
+	s, err := rules.Compile([]rules.Rule{
+		{Name: "small", Condition: `amount < 10`, Action: action("small")},
+		{Name: "large", Condition: `amount >= 1000`, Priority: 10},
+		{Name: "vip", Condition: `user == "ann" && amount > 0`,
+			Action: action("vip"), Priority: 5},
+		{Name: "any", Condition: `len(user) > 0`},
+	}, []string{"amount", "user"})
+	require.NoError(t, err)
+
+	report, err := s.Evaluate(ctx, map[string]any{"amount": 5, "user": "ann"},
+		rules.Options{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"vip", "small", "any"}, report.Fired())
+	require.Equal(t, []string{"vip:ann", "small:ann"}, actions)
+	require.Equal(t, 4, len(report.Results))
+	require.Equal(t, "large", report.Results[0].Rule)
+	require.False(t, report.Results[0].Fired)
+	for _, res := range report.Results {
+		require.True(t, res.Duration <= report.Duration)
+	}
+
+	// short-circuit
+	actions = nil
+	report, err = s.Evaluate(ctx, map[string]any{"amount": 5, "user": "ann"},
+		rules.Options{FirstMatch: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"vip"}, report.Fired())
+	require.Equal(t, []string{"vip:ann"}, actions)
+	require.Equal(t, 2, len(report.Results))
+
+	s, err = rules.Compile([]rules.Rule{
+		{Name: "block", Condition: `amount > 100`, Stop: true, Priority: 1},
+		{Name: "review", Condition: `amount > 50`},
+	}, []string{"amount"})
+	require.NoError(t, err)
+	report, err = s.Evaluate(ctx, map[string]any{"amount": 500},
+		rules.Options{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"block"}, report.Fired())
+	report, err = s.Evaluate(ctx, map[string]any{"amount": 60},
+		rules.Options{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"review"}, report.Fired())
+
+	// facts that the rules do not use are ignored by the conditions, but
+	// passed to the actions
+	var country any
+	s, err = rules.Compile([]rules.Rule{
+		{Name: "large", Condition: `amount > 100`,
+			Action: func(_ context.Context, facts map[string]any) error {
+				country = facts["country"]
+				return nil
+			}},
+		{Name: "known", Condition: `!is_undefined(user)`},
+	}, []string{"amount", "user"})
+	require.NoError(t, err)
+	report, err = s.Evaluate(ctx, map[string]any{"amount": 500,
+		"country": "DE"}, rules.Options{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"large"}, report.Fired())
+	require.Equal(t, "DE", country)
+
+	// conditions fire on the truthiness of the value they evaluate to
+	s, err = rules.Compile([]rules.Rule{
+		{Name: "error", Condition: `error(x)`},
+		{Name: "empty", Condition: `x[:0]`},
+		{Name: "items", Condition: `x`},
+		{Name: "func", Condition: `func() {}`},
+		{Name: "undefined", Condition: `x[5]`},
+	}, []string{"x"})
+	require.NoError(t, err)
+	report, err = s.Evaluate(ctx, map[string]any{"x": []any{1, "a"}},
+		rules.Options{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"items", "func"}, report.Fired())
+}
+
+func TestRuleSet_Errors(t *testing.T) {
+	ctx := context.Background()
+	failed := errors.New("failed")
+	s, err := rules.Compile([]rules.Rule{
+		{Name: "a", Condition: `x.y.z`, Priority: 2},
+		{Name: "b", Condition: `true`, Priority: 1,
+			Action: func(context.Context, map[string]any) error {
+				return failed
+			}},
+		{Name: "c", Condition: `true`},
+	}, []string{"x"})
+	require.NoError(t, err)
+
+	report, err := s.Evaluate(ctx, map[string]any{"x": 1}, rules.Options{})
+	require.True(t, err != nil && strings.HasPrefix(err.Error(), "rule 'a': "),
+		"unexpected error: %v", err)
+	require.Equal(t, 1, len(report.Results))
+
+	report, err = s.Evaluate(ctx, map[string]any{"x": 1},
+		rules.Options{ContinueOnError: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "c"}, report.Fired())
+	require.Error(t, report.Results[0].Err)
+	require.True(t, errors.Is(report.Results[1].Err, failed))
+
+	// unknown facts are ignored
+	report, err = s.Evaluate(ctx, map[string]any{"x": 1, "y": 1},
+		rules.Options{ContinueOnError: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "c"}, report.Fired())
+
+	// facts are converted once, before the first rule
+	report, err = s.Evaluate(ctx, map[string]any{"x": struct{}{}},
+		rules.Options{ContinueOnError: true})
+	require.True(t, err != nil && strings.HasPrefix(err.Error(), "fact 'x': "),
+		"unexpected error: %v", err)
+	require.Equal(t, 0, len(report.Results))
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.Evaluate(cctx, map[string]any{"x": 1}, rules.Options{})
+	require.Equal(t, context.Canceled, err)
+
+	// load-time checks
+	_, err = rules.Compile([]rules.Rule{
+		{Name: "a", Condition: `amount > limit`},
+	}, []string{"amount"})
+	require.True(t, err != nil && strings.HasPrefix(err.Error(), "rule 'a': ") &&
+		strings.Contains(err.Error(), "unresolved reference 'limit'"),
+		"unexpected error: %v", err)
+	_, err = rules.Compile([]rules.Rule{
+		{Name: "a", Condition: `true`},
+		{Name: "a", Condition: `false`},
+	}, nil)
+	require.Equal(t, "duplicate rule 'a'", err.Error())
+	_, err = rules.Compile([]rules.Rule{{Condition: `true`}}, nil)
+	require.Error(t, err)
+	_, err = rules.Compile([]rules.Rule{{Name: "a", Condition: ``}}, nil)
+	require.Error(t, err)
+}
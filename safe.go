@@ -0,0 +1,211 @@
+package tengo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/shelepuginivan/tengo/parser"
+)
+
+// CompileSafeExpr compiles given expr like CompileExpr, but in a safe mode
+// for expressions written by end users. expr must be a single expression
+// without for loops, function literals, imports and assignments, so its
+// evaluation always terminates in time linear in the size of the expression
+// (unless a function it calls does not). The expression can only call the
+// builtin functions and the parameters named in allow, and it cannot refer
+// to other builtin functions. A violation is returned as a CompilerError
+// with the position of the violation in expr.
+func CompileSafeExpr(
+	expr string,
+	paramNames []string,
+	allow []string,
+) (*Expr, error) {
+	if err := checkSafeExpr(expr, paramNames, allow); err != nil {
+		return nil, err
+	}
+	return CompileExpr(expr, paramNames)
+}
+
+// EvalSafe evaluates given expr with params like Eval, but compiles it like
+// CompileSafeExpr, so expr can only call the builtin functions and the
+// parameters named in allow.
+func EvalSafe(
+	ctx context.Context,
+	expr string,
+	params map[string]any,
+	allow []string,
+) (any, error) {
+	allow = append([]string(nil), allow...)
+	sort.Strings(allow)
+	mode := fmt.Sprintf("safe%q", allow)
+	return evalCached(ctx, mode, expr, params,
+		func(expr string, paramNames []string) (*Expr, error) {
+			return CompileSafeExpr(expr, paramNames, allow)
+		})
+}
+
+// safeChecker checks that an expression can be compiled in the safe mode.
+type safeChecker struct {
+	file   *parser.SourceFile
+	params map[string]bool
+	allow  map[string]bool
+}
+
+func checkSafeExpr(expr string, paramNames, allow []string) error {
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile("(main)", -1, len(expr))
+	p := parser.NewParser(srcFile, []byte(expr), nil)
+	file, err := p.ParseFile()
+	if err != nil {
+		return err
+	}
+
+	c := &safeChecker{
+		file:   srcFile,
+		params: make(map[string]bool, len(paramNames)),
+		allow:  make(map[string]bool, len(allow)),
+	}
+	for _, name := range paramNames {
+		c.params[name] = true
+	}
+	for _, name := range allow {
+		c.allow[name] = true
+	}
+
+	var exprs int
+	for _, stmt := range file.Stmts {
+		if _, ok := stmt.(*parser.EmptyStmt); ok {
+			continue
+		}
+		if err := c.checkStmt(stmt); err != nil {
+			return err
+		}
+		if exprs++; exprs > 1 {
+			return c.errorf(stmt, "only a single expression is allowed")
+		}
+	}
+	return nil
+}
+
+func (c *safeChecker) checkStmt(stmt parser.Stmt) error {
+	switch stmt := stmt.(type) {
+	case *parser.ExprStmt:
+		return c.checkExpr(stmt.Expr)
+	case *parser.ForStmt, *parser.ForInStmt:
+		return c.errorf(stmt, "for loops are not allowed")
+	case *parser.LabeledStmt:
+		switch stmt.Stmt.(type) {
+		case *parser.ForStmt, *parser.ForInStmt:
+			return c.errorf(stmt, "for loops are not allowed")
+		}
+	case *parser.AssignStmt, *parser.IncDecStmt, *parser.ConstStmt:
+		return c.errorf(stmt, "assignments are not allowed")
+	}
+	return c.errorf(stmt, "only a single expression is allowed")
+}
+
+func (c *safeChecker) checkExpr(expr parser.Expr) error {
+	switch expr := expr.(type) {
+	case *parser.FuncLit:
+		return c.errorf(expr, "function literals are not allowed")
+	case *parser.ImportExpr:
+		return c.errorf(expr, "import is not allowed")
+	case *parser.BoolLit, *parser.CharLit, *parser.FloatLit, *parser.IntLit,
+		*parser.StringLit, *parser.UndefinedLit:
+		return nil
+	case *parser.Ident:
+		if !c.params[expr.Name] && !c.allow[expr.Name] &&
+			isBuiltinFunc(expr.Name) {
+			return c.errorf(expr, "builtin function '%s' is not allowed",
+				expr.Name)
+		}
+		return nil
+	case *parser.CallExpr:
+		fn, ok := expr.Func.(*parser.Ident)
+		if !ok {
+			if err := c.checkExpr(expr.Func); err != nil {
+				return err
+			}
+			return c.errorf(expr, "only allowed functions can be called")
+		}
+		if !c.allow[fn.Name] {
+			return c.errorf(expr, "function '%s' is not allowed", fn.Name)
+		}
+		for _, arg := range expr.KeywordArgs {
+			if err := c.checkExpr(arg.Value); err != nil {
+				return err
+			}
+		}
+		return c.checkExprs(expr.Args...)
+	case *parser.ArrayLit:
+		return c.checkExprs(expr.Elements...)
+	case *parser.MapLit:
+		for _, elt := range expr.Elements {
+			if err := c.checkExpr(elt.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parser.BinaryExpr:
+		return c.checkExprs(expr.LHS, expr.RHS)
+	case *parser.CondExpr:
+		return c.checkExprs(expr.Cond, expr.True, expr.False)
+	case *parser.InterpStringLit:
+		return c.checkExprs(expr.Exprs...)
+	case *parser.IndexExpr:
+		return c.checkExprs(expr.Expr, expr.Index)
+	case *parser.SliceExpr:
+		return c.checkExprs(expr.Expr, expr.Low, expr.High, expr.Step)
+	case *parser.SelectorExpr:
+		return c.checkExprs(expr.Expr, expr.Sel)
+	case *parser.ErrorExpr:
+		return c.checkExpr(expr.Expr)
+	case *parser.ImmutableExpr:
+		return c.checkExpr(expr.Expr)
+	case *parser.OptionalChainExpr:
+		return c.checkExpr(expr.Expr)
+	case *parser.ParenExpr:
+		return c.checkExpr(expr.Expr)
+	case *parser.SpreadExpr:
+		return c.checkExpr(expr.Expr)
+	case *parser.UnaryExpr:
+		return c.checkExpr(expr.Expr)
+	}
+	// any other expression, including the ones added to the language
+	// later, is denied until it's known to be safe
+	return c.errorf(expr, "expression is not allowed")
+}
+
+func (c *safeChecker) checkExprs(exprs ...parser.Expr) error {
+	for _, expr := range exprs {
+		if expr == nil {
+			continue
+		}
+		if err := c.checkExpr(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *safeChecker) errorf(
+	node parser.Node,
+	format string,
+	args ...any,
+) error {
+	return &CompilerError{
+		FileSet: c.file.Set(),
+		Node:    node,
+		Err:     fmt.Errorf(format, args...),
+	}
+}
+
+func isBuiltinFunc(name string) bool {
+	for _, fn := range builtinFuncs {
+		if fn.Name == name {
+			return true
+		}
+	}
+	return false
+}
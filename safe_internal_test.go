@@ -0,0 +1,29 @@
+package tengo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shelepuginivan/tengo/parser"
+)
+
+func TestSafeChecker_UnlistedExpr(t *testing.T) {
+	expr := "price + x"
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile("(main)", -1, len(expr))
+	file, err := parser.NewParser(srcFile, []byte(expr), nil).ParseFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an expression that is not known to be safe is denied, e.g. one added
+	// to the parser later
+	bin := file.Stmts[0].(*parser.ExprStmt).Expr.(*parser.BinaryExpr)
+	bin.RHS = &parser.FuncType{FuncPos: bin.RHS.Pos()}
+	c := &safeChecker{file: srcFile}
+	err = c.checkStmt(file.Stmts[0])
+	if err == nil || !strings.Contains(err.Error(),
+		"expression is not allowed\n\tat (main):1:9") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package tengo_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shelepuginivan/tengo"
+	"github.com/shelepuginivan/tengo/require"
+)
+
+func TestEvalSafe(t *testing.T) {
+	ctx := context.Background()
+	params := map[string]any{
+		"price": 12.5,
+		"tags":  []any{"a", "b"},
+		"user":  map[string]any{"name": "ann", "age": 31},
+		"upper": tengo.Func1(strings.ToUpper),
+	}
+	expectEvalSafe := func(expr string, allow []string, expected any) {
+		res, err := tengo.EvalSafe(ctx, expr, params, allow)
+		require.NoError(t, err, expr)
+		require.Equal(t, expected, res, expr)
+	}
+
+	expectEvalSafe(`price > 10 && user.age >= 18`, nil, true)
+	expectEvalSafe(`user.name + ":" + tags[-1]`, nil, "ann:b")
+	expectEvalSafe(`{a: [price, ...tags]}.a[1:]`, nil, []any{"a", "b"})
+	expectEvalSafe(`len(tags) > 1 ? upper(user.name) : ""`,
+		[]string{"len", "upper"}, "ANN")
+	expectEvalSafe(`f"${user.name} is ${user.age}"`, nil, "ann is 31")
+
+	expectEvalSafeError(t, `len(tags)`, params, nil,
+		"function 'len' is not allowed", "(main):1:1")
+	expectEvalSafeError(t, `1 + upper(user.name)`, params, []string{"len"},
+		"function 'upper' is not allowed", "(main):1:5")
+	expectEvalSafeError(t, `[1, len]`, params, nil,
+		"builtin function 'len' is not allowed", "(main):1:5")
+	expectEvalSafeError(t, `user.name.upper()`, params, []string{"upper"},
+		"only allowed functions can be called", "(main):1:1")
+	expectEvalSafeError(t, `price + func() { return 1 }()`, params, nil,
+		"function literals are not allowed", "(main):1:9")
+	expectEvalSafeError(t, `[func() { for {} }]`, params, nil,
+		"function literals are not allowed", "(main):1:2")
+	expectEvalSafeError(t, `[(x) => x]`, params, nil,
+		"function literals are not allowed", "(main):1:2")
+	expectEvalSafeError(t, `f"${len(tags)}"`, params, nil,
+		"function 'len' is not allowed", "")
+	expectEvalSafeError(t, `import("os")`, params, nil,
+		"import is not allowed", "(main):1:1")
+	expectEvalSafeError(t, `for { }`, params, nil,
+		"for loops are not allowed", "(main):1:1")
+	expectEvalSafeError(t, `l: for x in tags { }`, params, nil,
+		"for loops are not allowed", "(main):1:1")
+	expectEvalSafeError(t, `price = 1`, params, nil,
+		"assignments are not allowed", "(main):1:1")
+	expectEvalSafeError(t, `price++`, params, nil,
+		"assignments are not allowed", "(main):1:1")
+	expectEvalSafeError(t, `price; tags`, params, nil,
+		"only a single expression is allowed", "(main):1:8")
+	expectEvalSafeError(t, `1); for {}; x := (1`, params, nil,
+		"", "(main):1:2")
+	expectEvalSafeError(t, `if price { 1 }`, params, nil,
+		"only a single expression is allowed", "(main):1:1")
+	expectEvalSafeError(t, `missing + 1`, params, nil,
+		"unresolved reference 'missing'", "")
+
+	// the same expression is checked with each allowlist
+	_, err := tengo.Eval(ctx, `len(tags)`, params)
+	require.NoError(t, err)
+	expectEvalSafeError(t, `len(tags)`, params, nil,
+		"function 'len' is not allowed", "(main):1:1")
+
+	e, err := tengo.CompileSafeExpr(`upper(s)`, []string{"s", "upper"},
+		[]string{"upper"})
+	require.NoError(t, err)
+	res, err := e.Eval(ctx, map[string]any{
+		"s":     "x",
+		"upper": tengo.Func1(strings.ToUpper),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "X", res)
+}
+
+func expectEvalSafeError(
+	t *testing.T,
+	expr string,
+	params map[string]any,
+	allow []string,
+	expected, pos string,
+) {
+	_, err := tengo.EvalSafe(context.Background(), expr, params, allow)
+	require.Error(t, err, expr)
+	require.True(t, strings.Contains(err.Error(), expected),
+		"expr %q: unexpected error: %v", expr, err)
+	if pos != "" {
+		require.True(t, strings.Contains(err.Error(), pos),
+			"expr %q: unexpected position: %v", expr, err)
+	}
+	if expected != "" && !strings.Contains(expected, "unresolved") {
+		var cerr *tengo.CompilerError
+		require.True(t, errors.As(err, &cerr), expr)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/shelepuginivan/tengo/parser"
 )
@@ -23,7 +25,20 @@ type Script struct {
 	variables map[string]*Variable
 	src       []byte
 	maxAllocs int64
+	maxInsts  int64
 	importDir string
+
+	intOverflowCheck bool
+	decimalPrecision int
+	decimalRounding  RoundingMode
+
+	// set by Engine
+	moduleCache *moduleCache
+	timeout     time.Duration
+	usage       *tenantUsage
+
+	// set by CompileExpr
+	resultVar string
 }
 
 // NewScript creates a Script object.
@@ -32,19 +47,36 @@ func NewScript(src []byte) *Script {
 		src:       src,
 		variables: make(map[string]*Variable),
 		maxAllocs: -1,
+		maxInsts:  -1,
+
+		decimalPrecision: DecimalPrecision,
+		decimalRounding:  DecimalRounding,
 	}
 }
 
+// Compile compiles the source script to bytecode without running it. Use
+// Compiled.Run or Compiled.RunWith to run the compiled script.
+func (s *Script) Compile() (*Compiled, error) {
+	return s.compile()
+}
+
 // CompileRun compiles the source script to bytecode and run it once to fill
 // global objects.
 func (s *Script) CompileRun() (*Compiled, error) {
-	return s.compile(context.Background())
+	return s.CompileRunContext(context.Background())
 }
 
 // CompileRunContext compiles the source script to bytecode and run it once to
 // fill global objects.
 func (s *Script) CompileRunContext(ctx context.Context) (*Compiled, error) {
-	return s.compile(ctx)
+	c, err := s.compile()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Run(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 // SetImportDir sets the initial import directory for script files.
@@ -57,7 +89,13 @@ func (s *Script) SetImportDir(dir string) error {
 	return nil
 }
 
-func (s *Script) compile(ctx context.Context) (*Compiled, error) {
+func (s *Script) compile() (c *Compiled, err error) {
+	if s.usage != nil {
+		defer func() {
+			s.usage.recordCompile(err)
+		}()
+	}
+
 	symbolTable, globals, err := s.prepCompile()
 	if err != nil {
 		return nil, err
@@ -70,6 +108,11 @@ func (s *Script) compile(ctx context.Context) (*Compiled, error) {
 	if err != nil {
 		return nil, err
 	}
+	if s.resultVar != "" {
+		if err := assignResult(srcFile, file, s.resultVar); err != nil {
+			return nil, err
+		}
+	}
 
 	out := symbolTable.Define(reservedVar)
 	globals[out.Index] = UndefinedValue
@@ -77,6 +120,7 @@ func (s *Script) compile(ctx context.Context) (*Compiled, error) {
 	cc := NewCompiler(srcFile, symbolTable, nil, s.modules, s.trace)
 	cc.EnableFileImport(true)
 	cc.SetImportDir(cc.importDir)
+	cc.moduleCache = s.moduleCache
 	if err := cc.Compile(file); err != nil {
 		return nil, err
 	}
@@ -92,27 +136,30 @@ func (s *Script) compile(ctx context.Context) (*Compiled, error) {
 		}
 	}
 
-	// run VM once and fill globals
-	if ctx == nil {
-		vm := NewVM(cc.Bytecode(), globals, s.maxAllocs)
-		if err := vm.Run(); err != nil {
-			return nil, err
-		}
-	} else {
-		vm := NewVM(cc.Bytecode(), globals, s.maxAllocs)
-		if err := runVMContext(ctx, vm); err != nil {
-			return nil, err
+	// initial values of the globals, for Compiled.RunWith
+	initGlobals := make([]Object, len(globals))
+	for idx, g := range globals {
+		if g != nil {
+			initGlobals[idx] = copyGlobal(g)
 		}
 	}
 
 	bc := cc.Bytecode()
 	bc.RemoveDuplicates()
 	return &Compiled{
-		bytecode:  bc,
-		globals:   globals,
-		indexes:   globalIndexes,
-		maxAllocs: s.maxAllocs,
-		outIdx:    out.Index,
+		bytecode:    bc,
+		globals:     globals,
+		initGlobals: initGlobals,
+		indexes:     globalIndexes,
+		maxAllocs:   s.maxAllocs,
+		maxInsts:    s.maxInsts,
+		timeout:     s.timeout,
+		usage:       s.usage,
+		outIdx:      out.Index,
+
+		intOverflowCheck: s.intOverflowCheck,
+		decimalPrecision: s.decimalPrecision,
+		decimalRounding:  s.decimalRounding,
 	}, nil
 }
 
@@ -131,7 +178,12 @@ func (s *Script) prepCompile() (
 	globals = make([]Object, GlobalsSize)
 
 	for idx, name := range names {
-		symbol := symbolTable.Define(name)
+		var symbol *Symbol
+		if s.variables[name].readOnly {
+			symbol = symbolTable.DefineConst(name, nil)
+		} else {
+			symbol = symbolTable.Define(name)
+		}
 		if symbol.Index != idx {
 			panic(fmt.Errorf("wrong symbol index: %d != %d",
 				idx, symbol.Index))
@@ -155,6 +207,46 @@ func (s *Script) Add(name string, value any) error {
 	return nil
 }
 
+// AddConst adds a new read-only variable or updates an existing variable to
+// the script. Assigning a read-only variable in the script is a compile
+// error.
+func (s *Script) AddConst(name string, value any) error {
+	if err := s.Add(name, value); err != nil {
+		return err
+	}
+	s.variables[name].readOnly = true
+	return nil
+}
+
+// AddLive adds a new live variable or updates an existing variable to the
+// script. Unlike with Add, the value of a live variable is not fixed at
+// compile time: get is called each time the script reads the variable, and
+// its result is converted as FromValue does. set is called each time the
+// script assigns to the variable, with the value converted as ToInterface
+// does; an assignment to an element or a field of the variable passes the
+// whole modified value to set. If set is nil, the variable is read-only, and
+// assigning it in the script is an error. An error returned by get or set
+// stops the script with a runtime error.
+func (s *Script) AddLive(
+	name string,
+	get func() (any, error),
+	set func(any) error,
+) error {
+	if name == reservedVar {
+		return errors.New("variable name must be different")
+	}
+	if get == nil {
+		return errors.New("getter must not be nil")
+	}
+
+	s.variables[name] = &Variable{
+		name:     name,
+		value:    &liveGlobal{name: name, getFn: get, setFn: set},
+		readOnly: set == nil,
+	}
+	return nil
+}
+
 // Remove removes (undefines) an existing variable for the script. It returns
 // false if the variable name is not defined.
 func (s *Script) Remove(name string) bool {
@@ -181,20 +273,50 @@ func (s *Script) SetMaxAllocs(n int64) {
 	s.maxAllocs = n
 }
 
+// SetMaxInstructions sets the maximum number of instructions executed in a
+// run. Compiled script will return ErrInstructionLimit error if it exceeds
+// this limit.
+func (s *Script) SetMaxInstructions(n int64) {
+	s.maxInsts = n
+}
+
+// EnableIntOverflowCheck enables or disables int overflow checks. When
+// enabled, int arithmetic that overflows int64 is a runtime error instead of
+// wrapping around silently.
+func (s *Script) EnableIntOverflowCheck(enable bool) {
+	s.intOverflowCheck = enable
+}
+
+// SetDecimalPrecision sets the number of digits after the decimal point kept
+// in the result of a decimal division, and its rounding mode. The defaults
+// are DecimalPrecision and DecimalRounding at the time the Script is created.
+func (s *Script) SetDecimalPrecision(places int, mode RoundingMode) {
+	s.decimalPrecision = places
+	s.decimalRounding = mode
+}
+
 // Trace set a tracer for compiler and VM for debugging purposes.
 func (s *Script) Trace(w io.Writer) {
 	s.trace = w
 }
 
-// Compiled is a compiled instance of the user script. Use Script.CompileRun()
-// to create Compiled object.
+// Compiled is a compiled instance of the user script. Use Script.Compile()
+// or Script.CompileRun() to create Compiled object.
 type Compiled struct {
-	mu        sync.RWMutex
-	bytecode  *Bytecode
-	indexes   map[string]int
-	globals   []Object
-	maxAllocs int64
-	outIdx    int
+	mu          sync.RWMutex
+	bytecode    *Bytecode
+	indexes     map[string]int
+	globals     []Object
+	initGlobals []Object
+	maxAllocs   int64
+	maxInsts    int64
+	timeout     time.Duration
+	usage       *tenantUsage
+	outIdx      int
+
+	intOverflowCheck bool
+	decimalPrecision int
+	decimalRounding  RoundingMode
 }
 
 // Clone creates a new copy of Compiled. Cloned copies are safe for concurrent
@@ -204,16 +326,24 @@ func (c *Compiled) Clone() *Compiled {
 	defer c.mu.RUnlock()
 
 	clone := &Compiled{
-		indexes:   c.indexes,
-		bytecode:  c.bytecode,
-		globals:   make([]Object, len(c.globals)),
-		maxAllocs: c.maxAllocs,
-		outIdx:    c.outIdx,
+		indexes:     c.indexes,
+		bytecode:    c.bytecode,
+		globals:     make([]Object, len(c.globals)),
+		initGlobals: c.initGlobals,
+		maxAllocs:   c.maxAllocs,
+		maxInsts:    c.maxInsts,
+		timeout:     c.timeout,
+		usage:       c.usage,
+		outIdx:      c.outIdx,
+
+		intOverflowCheck: c.intOverflowCheck,
+		decimalPrecision: c.decimalPrecision,
+		decimalRounding:  c.decimalRounding,
 	}
 	// copy global objects
 	for idx, g := range c.globals {
 		if g != nil {
-			clone.globals[idx] = g.Copy()
+			clone.globals[idx] = copyGlobal(g)
 		}
 	}
 	return clone
@@ -309,33 +439,98 @@ func (c *Compiled) callCompiled(ctx context.Context, fn Object,
 	inst = append(inst, MakeInstruction(parser.OpSetGlobal, c.outIdx)...)
 	inst = append(inst, MakeInstruction(parser.OpSuspend)...)
 
-	c.bytecode.Constants = append(c.bytecode.Constants, fn)
-	c.bytecode.Constants = append(c.bytecode.Constants, args...)
-
-	// orig := s.bytecode.MainFunction
-	c.bytecode.MainFunction = &CompiledFunction{
-		Instructions: inst,
+	// The call runs as the main function of its own bytecode, so that the
+	// bytecode shared with clones is not changed and the script can still
+	// be run again.
+	constants := append(c.bytecode.Constants[:constsOffset:constsOffset], fn)
+	bc := &Bytecode{
+		FileSet:      c.bytecode.FileSet,
+		MainFunction: &CompiledFunction{Instructions: inst},
+		Constants:    append(constants, args...),
 	}
+	err := c.runVM(ctx, bc)
 
-	var err error
-	if ctx == nil {
-		vm := NewVM(c.bytecode, c.globals, c.maxAllocs)
-		err = vm.Run()
-	} else {
-		vm := NewVM(c.bytecode, c.globals, c.maxAllocs)
-		err = runVMContext(ctx, vm)
+	// get symbol using index and return it
+	return c.globals[c.outIdx], err
+}
+
+// Run runs the main function of the compiled script again, with the current
+// values of the global variables, without compiling the script again.
+func (c *Compiled) Run(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.runVM(ctx, c.bytecode)
+}
+
+// RunWith runs the main function of the compiled script again, like Run,
+// but with the global variables reset to the values they had before the
+// first run (the values added with Script.Add) and then set to vars. An
+// error is returned if a name in vars was not defined during compilation,
+// in which case the global variables are left unchanged.
+func (c *Compiled) RunWith(ctx context.Context, vars map[string]any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the globals are replaced only if all of vars can be set
+	objs := make(map[int]Object, len(vars))
+	for name, value := range vars {
+		idx, obj, err := c.global(name, value)
+		if err != nil {
+			return err
+		}
+		objs[idx] = obj
+	}
+	globals := make([]Object, len(c.initGlobals))
+	for idx, g := range c.initGlobals {
+		if g != nil {
+			globals[idx] = copyGlobal(g)
+		}
+	}
+	for idx, obj := range objs {
+		if err := setGlobal(globals, idx, obj); err != nil {
+			return err
+		}
 	}
+	c.globals = globals
+	return c.runVM(ctx, c.bytecode)
+}
 
-	// TODO: go back to normal if required
-	// s.bytecode.MainFunction = orig
-	// avoid memory leak.
-	for i := constsOffset; i < len(c.bytecode.Constants); i++ {
-		c.bytecode.Constants[i] = nil
+// runVM runs bc with the global variables of the compiled script.
+func (c *Compiled) runVM(ctx context.Context, bc *Bytecode) (err error) {
+	vm := NewVM(bc, c.globals, c.maxAllocs)
+	vm.EnableIntOverflowCheck(c.intOverflowCheck)
+	// decimal division checks the VM setting only if it's set
+	if c.decimalPrecision != DecimalPrecision ||
+		c.decimalRounding != DecimalRounding {
+		vm.SetDecimalPrecision(c.decimalPrecision, c.decimalRounding)
+	}
+	vm.SetMaxInstructions(c.maxInsts)
+	// the instructions of a tenant are counted even without a limit
+	vm.countInsts = vm.countInsts || c.usage != nil
+
+	parent := ctx
+	if c.timeout > 0 {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	if c.usage != nil {
+		start := time.Now()
+		defer func() {
+			timedOut := errors.Is(err, context.DeadlineExceeded) &&
+				(parent == nil || parent.Err() == nil)
+			c.usage.recordRun(vm, time.Since(start), err, timedOut)
+		}()
 	}
-	c.bytecode.Constants = c.bytecode.Constants[:constsOffset]
 
-	// get symbol using index and return it
-	return c.globals[c.outIdx], err
+	if ctx == nil {
+		return vm.Run()
+	}
+	return runVMContext(ctx, vm)
 }
 
 // Get returns a variable identified by the name.
@@ -345,10 +540,7 @@ func (c *Compiled) Get(name string) *Variable {
 
 	value := UndefinedValue
 	if idx, ok := c.indexes[name]; ok {
-		value = c.globals[idx]
-		if value == nil {
-			value = UndefinedValue
-		}
+		value = globalValue(c.globals[idx])
 	}
 
 	v, err := NewVariable(name, value)
@@ -365,10 +557,7 @@ func (c *Compiled) Get(name string) *Variable {
 func (c *Compiled) GetAll() []*Variable {
 	vars := make([]*Variable, 0, len(c.indexes))
 	for name, idx := range c.indexes {
-		value := c.globals[idx]
-		if value == nil {
-			value = UndefinedValue
-		}
+		value := globalValue(c.globals[idx])
 		tv, err := NewVariable(name, value)
 		if err != nil {
 			// This should never happen, since the value will already be a tengo object
@@ -382,20 +571,90 @@ func (c *Compiled) GetAll() []*Variable {
 // Set replaces the value of a global variable identified by the name. An error
 // will be returned if the name was not defined during compilation.
 func (c *Compiled) Set(name string, value any) error {
-	obj, err := FromInterface(value)
+	return c.set(name, value)
+}
+
+func (c *Compiled) set(name string, value any) error {
+	idx, obj, err := c.global(name, value)
 	if err != nil {
 		return err
 	}
+	return setGlobal(c.globals, idx, obj)
+}
+
+// global returns the index of the global variable identified by the name,
+// and value converted to an Object.
+func (c *Compiled) global(name string, value any) (int, Object, error) {
+	obj, err := FromInterface(value)
+	if err != nil {
+		return 0, nil, err
+	}
 
 	idx, ok := c.indexes[name]
 	if !ok {
-		return fmt.Errorf("'%s' is not defined", name)
+		return 0, nil, fmt.Errorf("'%s' is not defined", name)
 	}
+	return idx, obj, nil
+}
 
-	c.globals[idx] = obj
+// setGlobal sets the global variable at idx of globals to obj, or calls its
+// setter if it's a live variable.
+func setGlobal(globals []Object, idx int, obj Object) error {
+	if live, ok := globals[idx].(*liveGlobal); ok {
+		return live.set(obj)
+	}
+	globals[idx] = obj
 	return nil
 }
 
+// Names returns the sorted names of the global variables of the compiled
+// script: the variables added to the script and the variables it defines.
+func (c *Compiled) Names() []string {
+	names := make([]string, 0, len(c.indexes))
+	for name := range c.indexes {
+		if name != reservedVar {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Encode writes the bytecode of the compiled script to the writer. It can be
+// read with Bytecode.Decode.
+func (c *Compiled) Encode(w io.Writer) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.bytecode.Encode(w)
+}
+
+// copyGlobal returns a copy of a global variable. Live variables and proxies
+// are kept by reference, so that they still refer to the Go values.
+func copyGlobal(g Object) Object {
+	if p, ok := g.(*Proxy); ok {
+		return p
+	}
+	return g.Copy()
+}
+
+// globalValue returns the value of a global variable: the current value of
+// a live variable, or undefined if the variable is not set or its getter
+// fails.
+func globalValue(value Object) Object {
+	if live, ok := value.(*liveGlobal); ok {
+		v, err := live.get()
+		if err != nil {
+			return UndefinedValue
+		}
+		return v
+	}
+	if value == nil {
+		return UndefinedValue
+	}
+	return value
+}
+
 // IsDefined returns true if the variable name is defined (has value)
 // after the execution.
 func (c *Compiled) IsDefined(name string) bool {
@@ -414,6 +673,7 @@ func (c *Compiled) IsDefined(name string) bool {
 }
 
 func runVMContext(ctx context.Context, vm *VM) (err error) {
+	vm.ctx = ctx
 	errch := make(chan error)
 	go func() {
 		errch <- vm.Run()
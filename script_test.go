@@ -1,12 +1,16 @@
 package tengo_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/shelepuginivan/tengo"
 	"github.com/shelepuginivan/tengo/require"
@@ -72,6 +76,131 @@ func TestScript_Add(t *testing.T) {
 	require.Equal(t, int64(6), c.Get("d").Value())
 }
 
+func TestScript_AddConst(t *testing.T) {
+	s := tengo.NewScript([]byte(`a := b * 2`))
+	require.NoError(t, s.AddConst("b", 5))
+	c, err := s.CompileRun()
+	require.NoError(t, err)
+	require.Equal(t, int64(10), c.Get("a").Value())
+	require.Equal(t, int64(5), c.Get("b").Value())
+
+	s = tengo.NewScript([]byte(`b = 3`))
+	require.NoError(t, s.AddConst("b", 5))
+	_, err = s.CompileRun()
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(),
+		"cannot assign to constant 'b'"))
+
+	// re-adding makes the variable writable again
+	require.NoError(t, s.Add("b", 5))
+	_, err = s.CompileRun()
+	require.NoError(t, err)
+}
+
+func TestScript_AddLive(t *testing.T) {
+	n := 0
+	s := tengo.NewScript([]byte(`
+a := counter
+b := counter + counter
+counter = 10
+c := counter
+counter += 1
+`))
+	require.NoError(t, s.AddLive("counter", func() (any, error) {
+		n++
+		return n, nil
+	}, func(v any) error {
+		n = int(v.(int64))
+		return nil
+	}))
+	c, err := s.CompileRun()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), c.Get("a").Value())
+	require.Equal(t, int64(5), c.Get("b").Value())
+	require.Equal(t, int64(11), c.Get("c").Value())
+	require.Equal(t, 13, n)
+
+	// Compiled.Get and Compiled.Set use the getter and the setter
+	require.Equal(t, int64(14), c.Get("counter").Value())
+	require.NoError(t, c.Set("counter", 0))
+	require.Equal(t, 0, n)
+
+	// values are read when the script reads them, not at compile time
+	user := map[string]any{"name": "a"}
+	s = tengo.NewScript([]byte(`
+f := func() { return user.name }
+`))
+	require.NoError(t, s.AddLive("user", func() (any, error) {
+		return user, nil
+	}, nil))
+	c, err = s.CompileRun()
+	require.NoError(t, err)
+	user = map[string]any{"name": "b"}
+	res, err := c.CallByName("f")
+	require.NoError(t, err)
+	require.Equal(t, "b", res)
+
+	// read-only live variables cannot be assigned
+	s = tengo.NewScript([]byte(`user = {}`))
+	require.NoError(t, s.AddLive("user", func() (any, error) {
+		return nil, nil
+	}, nil))
+	_, err = s.CompileRun()
+	require.True(t, err != nil &&
+		strings.Contains(err.Error(), "cannot assign to constant 'user'"))
+	s = tengo.NewScript([]byte(`user.name = "c"`))
+	require.NoError(t, s.AddLive("user", func() (any, error) {
+		return user, nil
+	}, nil))
+	_, err = s.CompileRun()
+	require.True(t, err != nil && strings.Contains(err.Error(),
+		"cannot assign to read-only variable 'user'"), err)
+	require.Equal(t, "b", user["name"])
+
+	// selector and index assignments pass the modified value to the setter
+	cfg := map[string]any{"a": 1, "list": []any{1, 2}}
+	s = tengo.NewScript([]byte(`
+cfg.a = 2
+cfg["b"] = "x"
+cfg.list[0] = 3
+`))
+	require.NoError(t, s.AddLive("cfg", func() (any, error) {
+		return cfg, nil
+	}, func(v any) error {
+		cfg = v.(map[string]any)
+		return nil
+	}))
+	_, err = s.CompileRun()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), cfg["a"])
+	require.Equal(t, "x", cfg["b"])
+	require.Equal(t, []any{int64(3), int64(2)}, cfg["list"])
+
+	// errors of the getter and the setter are runtime errors
+	s = tengo.NewScript([]byte(`a := 1
+b := x`))
+	require.NoError(t, s.AddLive("x", func() (any, error) {
+		return nil, errors.New("getter failed")
+	}, func(any) error {
+		return errors.New("setter failed")
+	}))
+	_, err = s.CompileRun()
+	require.True(t, err != nil &&
+		strings.Contains(err.Error(), "Runtime Error: getter failed") &&
+		strings.Contains(err.Error(), "(main):2:6"), err)
+	s = tengo.NewScript([]byte(`x = 1`))
+	require.NoError(t, s.AddLive("x", func() (any, error) {
+		return nil, nil
+	}, func(any) error {
+		return errors.New("setter failed")
+	}))
+	_, err = s.CompileRun()
+	require.True(t, err != nil &&
+		strings.Contains(err.Error(), "Runtime Error: setter failed"))
+
+	require.Error(t, s.AddLive("x", nil, nil))
+}
+
 func TestScript_Remove(t *testing.T) {
 	s := tengo.NewScript([]byte(`a := b`))
 	err := s.Add("b", 5)
@@ -91,6 +220,165 @@ func TestScript_CompileRun(t *testing.T) {
 	compiledGet(t, c, "a", int64(5))
 }
 
+func TestScript_Compile(t *testing.T) {
+	calls := 0
+	s := tengo.NewScript([]byte(`
+a := b + 1
+f := func() { return a }
+notify()`))
+	require.NoError(t, s.Add("b", 5))
+	require.NoError(t, s.Add("notify",
+		func(...tengo.Object) (tengo.Object, error) {
+			calls++
+			return tengo.UndefinedValue, nil
+		}))
+
+	// the script is not run
+	c, err := s.Compile()
+	require.NoError(t, err)
+	require.Equal(t, 0, calls)
+	require.Equal(t, []string{"a", "b", "f", "notify"}, c.Names())
+	require.False(t, c.IsDefined("a"))
+	compiledGet(t, c, "b", int64(5))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Encode(&buf))
+	bc := &tengo.Bytecode{}
+	require.NoError(t, bc.Decode(&buf, nil))
+	require.Equal(t, c.Clone().Names(), c.Names())
+
+	require.NoError(t, c.Run(context.Background()))
+	require.Equal(t, 1, calls)
+	compiledGet(t, c, "a", int64(6))
+	res, err := c.CallByName("f")
+	require.NoError(t, err)
+	require.Equal(t, int64(6), res)
+	require.NoError(t, c.RunWith(context.Background(), M{"b": 1}))
+	require.Equal(t, 2, calls)
+	compiledGet(t, c, "a", int64(2))
+
+	// syntax errors are returned by Compile, runtime errors by Run
+	s = tengo.NewScript([]byte(`a := 1; b := a +`))
+	_, err = s.Compile()
+	require.Error(t, err)
+	s = tengo.NewScript([]byte(`a := 1; a()`))
+	c, err = s.Compile()
+	require.NoError(t, err)
+	require.Error(t, c.Run(context.Background()))
+}
+
+func TestScript_IntOverflowCheck(t *testing.T) {
+	src := []byte(`
+	add := func(a, b) { return a + b }
+	x := add(9223372036854775807, 0)`)
+
+	// int arithmetic wraps around by default
+	s := tengo.NewScript(append(src, "\ny := x + 1"...))
+	c, err := s.CompileRun()
+	require.NoError(t, err)
+	compiledGet(t, c, "y", int64(math.MinInt64))
+
+	for _, expr := range []string{
+		"x + 1", "-x - 2", "x * 2", "-x - 1 - 1", "(-x - 1) / -1",
+		"x << 1", "-(-x - 1)", "x ** 2", "2 ** 63", "(-3) ** 40",
+	} {
+		s := tengo.NewScript(append(src, "\ny := "+expr...))
+		s.EnableIntOverflowCheck(true)
+		_, err := s.CompileRun()
+		require.Error(t, err, expr)
+		require.True(t, errors.Is(err, tengo.ErrIntOverflow), expr)
+	}
+
+	s = tengo.NewScript(append(src, "\ny := x - 1 + 1\nz := bigint(x) + 1\n"+
+		"p := (-2) ** 63"...))
+	s.EnableIntOverflowCheck(true)
+	c, err = s.CompileRun()
+	require.NoError(t, err)
+	compiledGet(t, c, "y", int64(math.MaxInt64))
+	compiledGet(t, c, "p", int64(math.MinInt64))
+	require.Equal(t, "9223372036854775808", c.Get("z").String())
+
+	// the check also applies to the calls made on the compiled script
+	r, err := c.CallByName("add", int64(1), int64(2))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), r)
+	_, err = c.Clone().CallByName("add", int64(math.MaxInt64), int64(1))
+	require.True(t, errors.Is(err, tengo.ErrIntOverflow))
+}
+
+func TestScript_SetDecimalPrecision(t *testing.T) {
+	src := []byte(`
+	div := func(a, b) { return a / b }
+	x := string(decimal(2) / 3)
+	y := string(1 / decimal(8))`)
+
+	s := tengo.NewScript(src)
+	c, err := s.CompileRun()
+	require.NoError(t, err)
+	compiledGet(t, c, "x", "0.6666666666666667")
+
+	s = tengo.NewScript(src)
+	s.SetDecimalPrecision(2, tengo.RoundDown)
+	c, err = s.CompileRun()
+	require.NoError(t, err)
+	compiledGet(t, c, "x", "0.66")
+	compiledGet(t, c, "y", "0.12")
+
+	// the setting also applies to the calls made on the compiled script, and
+	// doesn't change the process-wide default
+	r, err := c.Clone().CallByName("div",
+		&tengo.Decimal{Value: big.NewInt(1)}, int64(3))
+	require.NoError(t, err)
+	require.Equal(t, "0.33", r.(*tengo.Decimal).String())
+	require.Equal(t, 16, tengo.DecimalPrecision)
+}
+
+func TestScript_GeneratorFromGo(t *testing.T) {
+	// drain iterates a value from Go code, as builtins of modules do
+	drain := &tengo.UserFunction{
+		Value: func(args ...tengo.Object) (tengo.Object, error) {
+			it := args[0].Iterate()
+			n := int64(0)
+			for it.Next() {
+				n++
+			}
+			if g, ok := it.(*tengo.Generator); ok && g.Err() != nil {
+				return nil, g.Err()
+			}
+			return &tengo.Int{Value: n}, nil
+		},
+	}
+	newScript := func(src string) *tengo.Script {
+		s := tengo.NewScript([]byte(`
+		gen := func(n) { for i := 0; i < n; i++ { yield i } }
+		` + src))
+		s.Add("drain", drain)
+		return s
+	}
+
+	s := newScript(`out := drain(gen(100))`)
+	s.SetMaxInstructions(5000)
+	c, err := s.CompileRun()
+	require.NoError(t, err)
+	compiledGet(t, c, "out", int64(100))
+
+	// the generator counts towards the instruction limit of the script
+	s = newScript(`for i := 0; i < 10; i++ { drain(gen(100)) }`)
+	s.SetMaxInstructions(5000)
+	_, err = s.CompileRun()
+	require.True(t, errors.Is(err, tengo.ErrInstructionLimit),
+		"unexpected error: %v", err)
+
+	// and stops when the run of the script is aborted
+	s = newScript(`drain(func() { for { yield 1 } }())`)
+	ctx, cancel := context.WithTimeout(context.Background(),
+		50*time.Millisecond)
+	defer cancel()
+	_, err = s.CompileRunContext(ctx)
+	require.True(t, errors.Is(err, context.DeadlineExceeded),
+		"unexpected error: %v", err)
+}
+
 func TestScript_SourceModules(t *testing.T) {
 	s := tengo.NewScript([]byte(`a := import("srcmod").sum(1, 2, 3)`))
 
@@ -561,6 +849,119 @@ func TestCompiled_Set(t *testing.T) {
 	compiledGet(t, c, "a", int64(6))
 }
 
+func TestCompiled_Run(t *testing.T) {
+	c := scriptCompileRun(t, `
+count += 1
+data["b"] = count
+inc := func(x) { return x + count }
+`, M{"count": 10, "data": map[string]any{"a": 1}})
+	compiledGet(t, c, "count", int64(11))
+
+	require.NoError(t, c.Run(context.Background()))
+	compiledGet(t, c, "count", int64(12))
+	require.Equal(t, int64(12), c.Get("data").Map()["b"])
+
+	// calls do not replace the main function
+	res, err := c.CallByName("inc", 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(13), res)
+	require.NoError(t, c.Run(context.Background()))
+	compiledGet(t, c, "count", int64(13))
+
+	require.NoError(t, c.Set("count", 0))
+	require.NoError(t, c.Run(context.Background()))
+	compiledGet(t, c, "count", int64(1))
+
+	// clones run with their own globals
+	clone := c.Clone()
+	require.NoError(t, clone.Run(context.Background()))
+	compiledGet(t, clone, "count", int64(2))
+	compiledGet(t, c, "count", int64(1))
+
+	// context
+	c = scriptCompileRun(t, `n := 0; if loop { for { n++ } }`,
+		M{"loop": false})
+	require.NoError(t, c.Set("loop", true))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, c.Run(ctx))
+}
+
+func TestCompiled_RunWith(t *testing.T) {
+	c := scriptCompileRun(t, `
+count += 1
+data["b"] = count
+out := [count, a]
+`, M{"count": 10, "data": map[string]any{"a": 1}, "a": "x"})
+	compiledGet(t, c, "out", []any{int64(11), "x"})
+
+	// globals are reset to the values added to the script
+	for i := 0; i < 2; i++ {
+		require.NoError(t, c.RunWith(context.Background(), nil))
+		compiledGet(t, c, "out", []any{int64(11), "x"})
+		require.Equal(t, int64(11), c.Get("data").Map()["b"])
+	}
+
+	require.NoError(t, c.RunWith(context.Background(), map[string]any{
+		"count": 1,
+		"a":     "y",
+	}))
+	compiledGet(t, c, "out", []any{int64(2), "y"})
+
+	// a failed RunWith keeps the globals of the previous run
+	err := c.RunWith(context.Background(), map[string]any{"a": "z", "b": 1})
+	require.Error(t, err)
+	require.Equal(t, "'b' is not defined", err.Error())
+	compiledGet(t, c, "out", []any{int64(2), "y"})
+	compiledGet(t, c, "a", "y")
+	compiledGet(t, c, "count", int64(2))
+
+	// live variables are kept
+	n := 0
+	s := tengo.NewScript([]byte(`n += 1`))
+	require.NoError(t, s.AddLive("n",
+		func() (any, error) { return n, nil },
+		func(v any) error { n = int(v.(int64)); return nil }))
+	c, err = s.CompileRun()
+	require.NoError(t, err)
+	require.NoError(t, c.RunWith(context.Background(), nil))
+	require.Equal(t, 2, n)
+
+	// so are the globals, if a setter of a live variable fails
+	s = tengo.NewScript([]byte(`out := [a, n]`))
+	s.Add("a", 1)
+	require.NoError(t, s.AddLive("n",
+		func() (any, error) { return n, nil },
+		func(v any) error { return errors.New("read-only") }))
+	c, err = s.CompileRun()
+	require.NoError(t, err)
+	err = c.RunWith(context.Background(), map[string]any{"a": 5, "n": 1})
+	require.Error(t, err)
+	require.Equal(t, "read-only", err.Error())
+	compiledGet(t, c, "a", int64(1))
+	compiledGet(t, c, "out", []any{int64(1), int64(2)})
+}
+
+func TestCompiled_RunWithProxy(t *testing.T) {
+	m := map[string]int{"a": 1}
+	p, err := tengo.NewProxy(m)
+	require.NoError(t, err)
+	s := tengo.NewScript([]byte(`m.a += 1; m.b = 5`))
+	require.NoError(t, s.Add("m", p))
+	c, err := s.CompileRun()
+	require.NoError(t, err)
+	require.True(t, len(m) == 2 && m["a"] == 2 && m["b"] == 5,
+		"unexpected map: %v", m)
+
+	// RunWith and clones keep the proxy, not a copy of the map
+	require.NoError(t, c.RunWith(context.Background(), nil))
+	require.True(t, len(m) == 2 && m["a"] == 3 && m["b"] == 5,
+		"unexpected map: %v", m)
+	require.NoError(t, c.Clone().Run(context.Background()))
+	require.True(t, len(m) == 2 && m["a"] == 4 && m["b"] == 5,
+		"unexpected map: %v", m)
+}
+
 func TestCompiled_CustomObject(t *testing.T) {
 	c := scriptCompileRun(t, `r := (t<130)`, M{"t": &customNumber{value: 123}})
 	compiledGet(t, c, "r", true)
@@ -0,0 +1,345 @@
+package tengo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shelepuginivan/tengo/token"
+)
+
+// Set represents a set of objects. Elements can be ints, bigints,
+// decimals, floats, chars, strings, bools, bytes and undefined; elements
+// that are equal (e.g. 3 and bigint(3)) are stored once.
+type Set struct {
+	ObjectImpl
+
+	// Value maps the hash key of each element to the element.
+	Value map[any]Object
+}
+
+// NewSet returns a set of the given elements.
+func NewSet(elems ...Object) (*Set, error) {
+	s := &Set{Value: make(map[any]Object, len(elems))}
+	for _, elem := range elems {
+		if err := s.Add(elem); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add adds elem to the set.
+func (o *Set) Add(elem Object) error {
+	k, err := setKey(elem)
+	if err != nil {
+		return err
+	}
+	if _, ok := o.Value[k]; !ok {
+		o.Value[k] = elem
+	}
+	return nil
+}
+
+// Remove removes elem from the set.
+func (o *Set) Remove(elem Object) {
+	if k, err := setKey(elem); err == nil {
+		delete(o.Value, k)
+	}
+}
+
+// Contains returns true if elem is in the set.
+func (o *Set) Contains(elem Object) (bool, error) {
+	return setContains(o.Value, elem), nil
+}
+
+// TypeName returns the name of the type.
+func (o *Set) TypeName() string {
+	return "set"
+}
+
+func (o *Set) String() string {
+	return setString(o.Value)
+}
+
+// BinaryOp returns another object that is the result of a given binary
+// operator and a right-hand side set: union (|), intersection (&),
+// difference (-) or symmetric difference (^).
+func (o *Set) BinaryOp(op token.Token, rhs Object) (Object, error) {
+	return setBinaryOp(o.Value, op, rhs)
+}
+
+// Copy returns a copy of the type.
+func (o *Set) Copy() Object {
+	return setCopy(o.Value)
+}
+
+// IsFalsy returns true if the value of the type is falsy.
+func (o *Set) IsFalsy() bool {
+	return len(o.Value) == 0
+}
+
+// Equals returns true if the value of the type is equal to the value of
+// another object.
+func (o *Set) Equals(x Object) bool {
+	return setEquals(o.Value, x)
+}
+
+// IndexGet returns true if the index is an element of the set.
+func (o *Set) IndexGet(index Object) (Object, error) {
+	if setContains(o.Value, index) {
+		return TrueValue, nil
+	}
+	return FalseValue, nil
+}
+
+// IndexSet adds the index to the set if the value is truthy, and removes
+// it otherwise.
+func (o *Set) IndexSet(index, value Object) error {
+	if value.IsFalsy() {
+		o.Remove(index)
+		return nil
+	}
+	return o.Add(index)
+}
+
+// Iterate creates a set iterator.
+func (o *Set) Iterate() Iterator {
+	return setIterator(o.Value)
+}
+
+// CanIterate returns whether the Object can be Iterated.
+func (o *Set) CanIterate() bool {
+	return true
+}
+
+// ImmutableSet represents an immutable set of objects.
+type ImmutableSet struct {
+	ObjectImpl
+	Value map[any]Object
+}
+
+// Contains returns true if elem is in the set.
+func (o *ImmutableSet) Contains(elem Object) (bool, error) {
+	return setContains(o.Value, elem), nil
+}
+
+// TypeName returns the name of the type.
+func (o *ImmutableSet) TypeName() string {
+	return "immutable-set"
+}
+
+func (o *ImmutableSet) String() string {
+	return setString(o.Value)
+}
+
+// BinaryOp returns another object that is the result of a given binary
+// operator and a right-hand side set. The result is a mutable set.
+func (o *ImmutableSet) BinaryOp(op token.Token, rhs Object) (Object, error) {
+	return setBinaryOp(o.Value, op, rhs)
+}
+
+// Copy returns a copy of the type.
+func (o *ImmutableSet) Copy() Object {
+	return setCopy(o.Value)
+}
+
+// IsFalsy returns true if the value of the type is falsy.
+func (o *ImmutableSet) IsFalsy() bool {
+	return len(o.Value) == 0
+}
+
+// Equals returns true if the value of the type is equal to the value of
+// another object.
+func (o *ImmutableSet) Equals(x Object) bool {
+	return setEquals(o.Value, x)
+}
+
+// IndexGet returns true if the index is an element of the set.
+func (o *ImmutableSet) IndexGet(index Object) (Object, error) {
+	if setContains(o.Value, index) {
+		return TrueValue, nil
+	}
+	return FalseValue, nil
+}
+
+// Iterate creates a set iterator.
+func (o *ImmutableSet) Iterate() Iterator {
+	return setIterator(o.Value)
+}
+
+// CanIterate returns whether the Object can be Iterated.
+func (o *ImmutableSet) CanIterate() bool {
+	return true
+}
+
+type (
+	bigIntKey    string
+	decimalKey   string
+	bytesKey     string
+	undefinedKey struct{}
+)
+
+// setKey returns the hash key of a set element. Equal objects of different
+// types (e.g. 3, bigint(3) and decimal("3.0")) share the same key.
+func setKey(o Object) (any, error) {
+	switch o := o.(type) {
+	case *Int:
+		return o.Value, nil
+	case *BigInt:
+		if o.Value.IsInt64() {
+			return o.Value.Int64(), nil
+		}
+		return bigIntKey(o.Value.String()), nil
+	case *Decimal:
+		d := o.Copy().(*Decimal).trim()
+		if d.Scale == 0 {
+			return setKey(&BigInt{Value: d.Value})
+		}
+		return decimalKey(d.String()), nil
+	case *Float:
+		return o.Value, nil
+	case *Char:
+		return o.Value, nil
+	case *String:
+		return o.Value, nil
+	case *Bool:
+		return o == TrueValue, nil
+	case *Bytes:
+		return bytesKey(o.Value), nil
+	case *Undefined:
+		return undefinedKey{}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotHashable, o.TypeName())
+}
+
+// toSetValue returns the elements of a set object.
+func toSetValue(o Object) (map[any]Object, bool) {
+	switch o := o.(type) {
+	case *Set:
+		return o.Value, true
+	case *ImmutableSet:
+		return o.Value, true
+	}
+	return nil, false
+}
+
+func setContains(s map[any]Object, elem Object) bool {
+	k, err := setKey(elem)
+	if err != nil {
+		return false
+	}
+	_, ok := s[k]
+	return ok
+}
+
+func setString(s map[any]Object) string {
+	elements := make([]string, 0, len(s))
+	for _, e := range s {
+		elements = append(elements, e.String())
+	}
+	sort.Strings(elements)
+	return fmt.Sprintf("set(%s)", strings.Join(elements, ", "))
+}
+
+func setBinaryOp(s map[any]Object, op token.Token, rhs Object) (Object, error) {
+	t, ok := toSetValue(rhs)
+	if !ok {
+		return nil, ErrInvalidOperator
+	}
+	r := make(map[any]Object)
+	switch op {
+	case token.Or:
+		for k, v := range s {
+			r[k] = v
+		}
+		for k, v := range t {
+			if _, ok := r[k]; !ok {
+				r[k] = v
+			}
+		}
+	case token.And:
+		for k, v := range s {
+			if _, ok := t[k]; ok {
+				r[k] = v
+			}
+		}
+	case token.Sub:
+		for k, v := range s {
+			if _, ok := t[k]; !ok {
+				r[k] = v
+			}
+		}
+	case token.Xor:
+		for k, v := range s {
+			if _, ok := t[k]; !ok {
+				r[k] = v
+			}
+		}
+		for k, v := range t {
+			if _, ok := s[k]; !ok {
+				r[k] = v
+			}
+		}
+	default:
+		return nil, ErrInvalidOperator
+	}
+	return &Set{Value: r}, nil
+}
+
+func setCopy(s map[any]Object) *Set {
+	c := make(map[any]Object, len(s))
+	for k, v := range s {
+		c[k] = v.Copy()
+	}
+	return &Set{Value: c}
+}
+
+func setEquals(s map[any]Object, x Object) bool {
+	t, ok := toSetValue(x)
+	if !ok || len(s) != len(t) {
+		return false
+	}
+	for k := range s {
+		if _, ok := t[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func setIterator(s map[any]Object) Iterator {
+	elems := make([]Object, 0, len(s))
+	for _, e := range s {
+		elems = append(elems, e)
+	}
+	return &ArrayIterator{v: elems, l: len(elems)}
+}
+
+// setInterface converts set elements to a Go set. Bytes elements become
+// strings so they can be used as map keys.
+func setInterface(s map[any]Object) map[any]struct{} {
+	res := make(map[any]struct{}, len(s))
+	for _, e := range s {
+		if b, ok := e.(*Bytes); ok {
+			res[string(b.Value)] = struct{}{}
+			continue
+		}
+		res[ToInterface(e)] = struct{}{}
+	}
+	return res
+}
+
+func setFromKeys[K comparable](m map[K]struct{}) (Object, error) {
+	s := &Set{Value: make(map[any]Object, len(m))}
+	for k := range m {
+		o, err := FromInterface(k)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Add(o); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
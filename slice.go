@@ -0,0 +1,213 @@
+package tengo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// sliceObject returns x[low:high] or, if step is not nil, x[low:high:step].
+// Negative bounds count from the end of x. Strings are sliced by characters,
+// like they are indexed.
+func sliceObject(x, low, high, step Object) (Object, error) {
+	var n int
+	switch x := x.(type) {
+	case *Array:
+		n = len(x.Value)
+	case *ImmutableArray:
+		n = len(x.Value)
+	case *String:
+		if x.runeStr == nil {
+			x.runeStr = []rune(x.Value)
+		}
+		n = len(x.runeStr)
+	case *Bytes:
+		n = len(x.Value)
+	default:
+		return nil, fmt.Errorf("not indexable: %s", x.TypeName())
+	}
+
+	if step == nil {
+		lo, hi, err := sliceBounds(n, low, high)
+		if err != nil {
+			return nil, err
+		}
+		switch x := x.(type) {
+		case *Array:
+			return &Array{Value: x.Value[lo:hi]}, nil
+		case *ImmutableArray:
+			return &Array{Value: x.Value[lo:hi]}, nil
+		case *String:
+			if n == len(x.Value) {
+				// only single-byte characters
+				return &String{Value: x.Value[lo:hi]}, nil
+			}
+			return &String{Value: string(x.runeStr[lo:hi])}, nil
+		default:
+			return &Bytes{Value: x.(*Bytes).Value[lo:hi]}, nil
+		}
+	}
+
+	indexes, err := sliceIndexes(n, low, high, step)
+	if err != nil {
+		return nil, err
+	}
+	switch x := x.(type) {
+	case *Array:
+		return &Array{Value: pickElements(x.Value, indexes)}, nil
+	case *ImmutableArray:
+		return &Array{Value: pickElements(x.Value, indexes)}, nil
+	case *String:
+		return &String{Value: string(pickElements(x.runeStr, indexes))}, nil
+	default:
+		return &Bytes{Value: pickElements(x.(*Bytes).Value, indexes)}, nil
+	}
+}
+
+// sliceAssign replaces the elements x[low:high] (or x[low:high:step]) of an
+// array with the elements of value. Without a step (or with a step of 1), the
+// number of elements can change; otherwise, it must match the number of
+// replaced elements.
+func sliceAssign(x, low, high, step, value Object) error {
+	arr, ok := x.(*Array)
+	if !ok {
+		return fmt.Errorf("not index-assignable: %s", x.TypeName())
+	}
+	var elements []Object
+	switch value := value.(type) {
+	case *Array:
+		elements = value.Value
+	case *ImmutableArray:
+		elements = value.Value
+	default:
+		return fmt.Errorf("invalid slice value type: %s", value.TypeName())
+	}
+
+	if v, ok := step.(*Int); ok && v.Value == 1 {
+		step = nil
+	}
+	if step == nil {
+		lo, hi, err := sliceBounds(len(arr.Value), low, high)
+		if err != nil {
+			return err
+		}
+		res := make([]Object, 0, len(arr.Value)-(hi-lo)+len(elements))
+		res = append(res, arr.Value[:lo]...)
+		res = append(res, elements...)
+		arr.Value = append(res, arr.Value[hi:]...)
+		return nil
+	}
+
+	indexes, err := sliceIndexes(len(arr.Value), low, high, step)
+	if err != nil {
+		return err
+	}
+	if len(elements) != len(indexes) {
+		return fmt.Errorf("slice assignment size mismatch: %d != %d",
+			len(elements), len(indexes))
+	}
+	// value can be the array itself
+	elements = append([]Object(nil), elements...)
+	for i, idx := range indexes {
+		arr.Value[idx] = elements[i]
+	}
+	return nil
+}
+
+// sliceBounds returns the bounds of a slice without a step of a sequence of
+// length n. Negative bounds count from the end, and the bounds are clamped to
+// the sequence.
+func sliceBounds(n int, low, high Object) (lo, hi int, err error) {
+	l, h := int64(0), int64(n)
+	if low != UndefinedValue {
+		if l, err = sliceBound(n, low); err != nil {
+			return
+		}
+	}
+	if high != UndefinedValue {
+		if h, err = sliceBound(n, high); err != nil {
+			return
+		}
+	}
+	if l > h {
+		err = fmt.Errorf("invalid slice index: %d > %d", l, h)
+		return
+	}
+	return clampIndex(l, 0, n), clampIndex(h, 0, n), nil
+}
+
+// sliceIndexes returns the indexes selected by a slice with a step of a
+// sequence of length n. Like in Python, a negative step walks the sequence
+// backwards and an empty range selects nothing.
+func sliceIndexes(n int, low, high, step Object) ([]int, error) {
+	s := 1
+	if step != UndefinedValue {
+		v, ok := step.(*Int)
+		if !ok {
+			return nil, fmt.Errorf("invalid slice index type: %s",
+				step.TypeName())
+		}
+		if v.Value == 0 {
+			return nil, errors.New("invalid slice step: 0")
+		}
+		// any step longer than the sequence selects at most one element
+		s = clampIndex(v.Value, -n-1, n+1)
+	}
+
+	// for a negative step, the walk starts at the end and -1 is the
+	// position before the start
+	lo, hi, minIdx, maxIdx := 0, n, 0, n
+	if s < 0 {
+		lo, hi, minIdx, maxIdx = n-1, -1, -1, n-1
+	}
+	if low != UndefinedValue {
+		l, err := sliceBound(n, low)
+		if err != nil {
+			return nil, err
+		}
+		lo = clampIndex(l, minIdx, maxIdx)
+	}
+	if high != UndefinedValue {
+		h, err := sliceBound(n, high)
+		if err != nil {
+			return nil, err
+		}
+		hi = clampIndex(h, minIdx, maxIdx)
+	}
+
+	var indexes []int
+	for i := lo; s > 0 && i < hi || s < 0 && i > hi; i += s {
+		indexes = append(indexes, i)
+	}
+	return indexes, nil
+}
+
+// sliceBound returns the value of a slice bound; negative bounds count from
+// the end of a sequence of length n.
+func sliceBound(n int, bound Object) (int64, error) {
+	v, ok := bound.(*Int)
+	if !ok {
+		return 0, fmt.Errorf("invalid slice index type: %s", bound.TypeName())
+	}
+	if v.Value < 0 {
+		return v.Value + int64(n), nil
+	}
+	return v.Value, nil
+}
+
+func clampIndex(i int64, min, max int) int {
+	if i < int64(min) {
+		return min
+	}
+	if i > int64(max) {
+		return max
+	}
+	return int(i)
+}
+
+func pickElements[T any](s []T, indexes []int) []T {
+	res := make([]T, len(indexes))
+	for i, idx := range indexes {
+		res[i] = s[idx]
+	}
+	return res
+}
@@ -28,27 +28,21 @@ var jsonModule = map[string]tengo.Object{
 }
 
 func jsonDecode(args ...tengo.Object) (ret tengo.Object, err error) {
-	if len(args) != 1 {
+	if len(args) != 1 && len(args) != 2 {
 		return nil, tengo.ErrWrongNumArguments
 	}
 
+	decode := json.Decode
+	if len(args) == 2 && !args[1].IsFalsy() {
+		decode = json.DecodeDecimal
+	}
+
+	var data []byte
 	switch o := args[0].(type) {
 	case *tengo.Bytes:
-		v, err := json.Decode(o.Value)
-		if err != nil {
-			return &tengo.Error{
-				Value: &tengo.String{Value: err.Error()},
-			}, nil
-		}
-		return v, nil
+		data = o.Value
 	case *tengo.String:
-		v, err := json.Decode([]byte(o.Value))
-		if err != nil {
-			return &tengo.Error{
-				Value: &tengo.String{Value: err.Error()},
-			}, nil
-		}
-		return v, nil
+		data = []byte(o.Value)
 	default:
 		return nil, tengo.ErrInvalidArgumentType{
 			Name:     "first",
@@ -56,6 +50,14 @@ func jsonDecode(args ...tengo.Object) (ret tengo.Object, err error) {
 			Found:    args[0].TypeName(),
 		}
 	}
+
+	v, err := decode(data)
+	if err != nil {
+		return &tengo.Error{
+			Value: &tengo.String{Value: err.Error()},
+		}, nil
+	}
+	return v, nil
 }
 
 func jsonEncode(args ...tengo.Object) (ret tengo.Object, err error) {
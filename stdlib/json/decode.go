@@ -7,6 +7,7 @@
 package json
 
 import (
+	"math/big"
 	"strconv"
 	"unicode"
 	"unicode/utf16"
@@ -18,6 +19,17 @@ import (
 // Decode parses the JSON-encoded data and returns the result object.
 func Decode(data []byte) (tengo.Object, error) {
 	var d decodeState
+	return d.decode(data)
+}
+
+// DecodeDecimal is like Decode, but numbers with a fraction or an exponent
+// are decoded as Decimal values, keeping all of their digits.
+func DecodeDecimal(data []byte) (tengo.Object, error) {
+	d := decodeState{useDecimal: true}
+	return d.decode(data)
+}
+
+func (d *decodeState) decode(data []byte) (tengo.Object, error) {
 	err := checkValid(data, &d.scan)
 	if err != nil {
 		return nil, err
@@ -34,6 +46,8 @@ type decodeState struct {
 	off    int // next read offset in data
 	opcode int // last read result
 	scan   scanner
+
+	useDecimal bool // decode non-integer numbers as tengo.Decimal
 }
 
 // readIndex returns the position of the last byte read.
@@ -214,11 +228,26 @@ func (d *decodeState) literal() (tengo.Object, error) {
 		if c != '-' && (c < '0' || c > '9') {
 			panic(phasePanicMsg)
 		}
+		if isFloat && d.useDecimal {
+			v, err := tengo.ParseDecimal(string(item))
+			if err == nil {
+				return v, nil
+			}
+			if err == tengo.ErrDecimalLimit {
+				return nil, err
+			}
+		}
 		if isFloat {
 			n, _ := strconv.ParseFloat(string(item), 64)
 			return &tengo.Float{Value: n}, nil
 		}
-		n, _ := strconv.ParseInt(string(item), 10, 64)
+		n, err := strconv.ParseInt(string(item), 10, 64)
+		if err != nil {
+			// integers that do not fit int64 are kept exactly
+			if v, ok := new(big.Int).SetString(string(item), 10); ok {
+				return &tengo.BigInt{Value: v}, nil
+			}
+		}
 		return &tengo.Int{Value: n}, nil
 	}
 }
@@ -238,6 +238,10 @@ func Encode(o tengo.Object) ([]byte, error) {
 		b = append(b, y...)
 	case *tengo.Int:
 		b = strconv.AppendInt(b, o.Value, 10)
+	case *tengo.BigInt:
+		b = o.Value.Append(b, 10)
+	case *tengo.Decimal:
+		b = append(b, o.String()...)
 	case *tengo.String:
 		// string encoding bug is fixed with newly introduced function
 		// encodeString(). See: https://github.com/d5/tengo/issues/268
@@ -2,6 +2,7 @@ package json_test
 
 import (
 	gojson "encoding/json"
+	"math/big"
 	"testing"
 
 	"github.com/shelepuginivan/tengo"
@@ -60,6 +61,10 @@ func TestJSON(t *testing.T) {
 
 	testJSONEncodeDecode(t, MAP{"id1": 7075984636689534001, "id2": 7075984636689534002})
 	testJSONEncodeDecode(t, ARR{1e3, 1e7})
+
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	testJSONEncodeDecode(t, huge)
+	testJSONEncodeDecode(t, MAP{"total": huge, "small": big.NewInt(42)})
 }
 
 func TestDecode(t *testing.T) {
@@ -91,6 +96,32 @@ func testDecodeError(t *testing.T, input string) {
 	require.Error(t, err)
 }
 
+func TestDecimal(t *testing.T) {
+	d, err := tengo.ParseDecimal("-12345678901234567890.123456789012345")
+	require.NoError(t, err)
+	b, err := json.Encode(&tengo.Map{Value: map[string]tengo.Object{"d": d}})
+	require.NoError(t, err)
+	require.Equal(t, `{"d":-12345678901234567890.123456789012345}`, string(b))
+
+	o, err := json.DecodeDecimal(b)
+	require.NoError(t, err)
+	require.Equal(t, d.String(), o.(*tengo.Map).Value["d"].String())
+
+	o, err = json.DecodeDecimal([]byte(`[19.90, 1e-3, 7]`))
+	require.NoError(t, err)
+	arr := o.(*tengo.Array).Value
+	require.Equal(t, "19.90", arr[0].(*tengo.Decimal).String())
+	require.Equal(t, "0.001", arr[1].(*tengo.Decimal).String())
+	require.Equal(t, int64(7), arr[2].(*tengo.Int).Value)
+
+	o, err = json.Decode([]byte(`19.90`))
+	require.NoError(t, err)
+	require.Equal(t, 19.9, o.(*tengo.Float).Value)
+
+	_, err = json.DecodeDecimal([]byte(`[1e200000000]`))
+	require.Equal(t, tengo.ErrDecimalLimit, err)
+}
+
 func testJSONEncodeDecode(t *testing.T, v any) {
 	o, err := tengo.FromInterface(v)
 	require.NoError(t, err)
@@ -167,6 +167,36 @@ v3 := floordiv(9, 3)
 	})
 }
 
+func TestPow(t *testing.T) {
+	compiled, err := runWith(`
+pow := import("operator").pow
+
+v1 := pow(2, 10)
+v2 := pow(2, -1)
+v3 := pow(9, 0.5)
+`, "operator")
+
+	require.NoError(t, err)
+	require.Equal(t, int64(1024), compiled.Get("v1").Value())
+	require.Equal(t, 0.5, compiled.Get("v2").Value())
+	require.Equal(t, 3.0, compiled.Get("v3").Value())
+
+	t.Run("Signature", func(t *testing.T) {
+		tests := []string{
+			`import("operator").pow()`,
+			`import("operator").pow("a", 2)`,
+			`import("operator").pow([], {})`,
+			`import("operator").pow(undefined, 3)`,
+		}
+		for _, tt := range tests {
+			t.Run("", func(t *testing.T) {
+				_, err := runWith(tt, "operator")
+				require.Error(t, err)
+			})
+		}
+	})
+}
+
 func TestNeg(t *testing.T) {
 	compiled, err := runWith(`
 neg := import("operator").neg
@@ -416,6 +446,19 @@ v2 := shift_right(16, 2)
 	})
 }
 
+func TestContains(t *testing.T) {
+	compiled, err := runWith(`
+contains := import("operator").contains
+
+v1 := contains([1, 2, 3], 2)
+v2 := contains("abc", "d")
+`, "operator")
+
+	require.NoError(t, err)
+	require.Equal(t, true, compiled.Get("v1").Value())
+	require.Equal(t, false, compiled.Get("v2").Value())
+}
+
 func TestItemgetter(t *testing.T) {
 	compiled, err := runWith(`
 itemgetter := import("operator").itemgetter
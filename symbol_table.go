@@ -16,7 +16,9 @@ type Symbol struct {
 	Name          string
 	Scope         SymbolScope
 	Index         int
-	LocalAssigned bool // if the local symbol is assigned at least once
+	LocalAssigned bool   // if the local symbol is assigned at least once
+	Constant      bool   // if the symbol cannot be reassigned
+	Value         Object // folded literal value of a constant, or nil
 }
 
 // SymbolTable represents a symbol table.
@@ -63,6 +65,16 @@ func (t *SymbolTable) Define(name string) *Symbol {
 	return symbol
 }
 
+// DefineConst adds a new read-only symbol in the current scope. If value is
+// not nil, it is the literal value of the constant and references to the
+// symbol are compiled to that value directly.
+func (t *SymbolTable) DefineConst(name string, value Object) *Symbol {
+	symbol := t.Define(name)
+	symbol.Constant = true
+	symbol.Value = value
+	return symbol
+}
+
 // DefineBuiltin adds a symbol for builtin function.
 func (t *SymbolTable) DefineBuiltin(index int, name string) *Symbol {
 	if t.parent != nil {
@@ -108,7 +120,8 @@ func (t *SymbolTable) Resolve(
 	// then it's free variable.
 	if !t.block && depth > 0 &&
 		symbol.Scope != ScopeGlobal &&
-		symbol.Scope != ScopeBuiltin {
+		symbol.Scope != ScopeBuiltin &&
+		symbol.Value == nil {
 		return t.defineFree(symbol), depth, true
 	}
 	return symbol, depth, true
@@ -178,9 +191,10 @@ func (t *SymbolTable) defineFree(original *Symbol) *Symbol {
 	// TODO: should we check duplicates?
 	t.freeSymbols = append(t.freeSymbols, original)
 	symbol := &Symbol{
-		Name:  original.Name,
-		Index: len(t.freeSymbols) - 1,
-		Scope: ScopeFree,
+		Name:     original.Name,
+		Index:    len(t.freeSymbols) - 1,
+		Scope:    ScopeFree,
+		Constant: original.Constant,
 	}
 	t.store[original.Name] = symbol
 	return symbol
@@ -91,6 +91,31 @@ func TestSymbolTable(t *testing.T) {
 	resolveExpect(t, local2Block2, "b", globalSymbol("b", 1), 3)
 }
 
+func TestSymbolTable_DefineConst(t *testing.T) {
+	global := symbolTable()
+	a := global.DefineConst("a", nil)
+	require.True(t, a.Constant)
+	require.True(t, a.Scope == tengo.ScopeGlobal)
+
+	local1 := global.Fork(false)
+	local1.DefineConst("b", nil)
+	local1.DefineConst("c", &tengo.Int{Value: 1})
+
+	// folded constants are not captured as free variables
+	local2 := local1.Fork(false)
+	symbol, depth, ok := local2.Resolve("c", false)
+	require.True(t, ok)
+	require.True(t, symbol.Scope == tengo.ScopeLocal)
+	require.Equal(t, 1, depth)
+	require.Equal(t, &tengo.Int{Value: 1}, symbol.Value)
+	require.Equal(t, 0, len(local2.FreeSymbols()))
+
+	symbol, _, ok = local2.Resolve("b", false)
+	require.True(t, ok)
+	require.True(t, symbol.Scope == tengo.ScopeFree)
+	require.True(t, symbol.Constant)
+}
+
 func symbol(
 	name string,
 	scope tengo.SymbolScope,
@@ -1,8 +1,10 @@
 package tengo
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 )
 
@@ -14,6 +16,17 @@ var (
 	// MaxBytesLen is the maximum length for bytes value. Note this limit
 	// applies to all compiler/VM instances in the process.
 	MaxBytesLen = 2147483647
+
+	// MaxBigIntBits is the maximum number of bits of a bigint value. It
+	// bounds the time and memory a single bigint operation can use. Note
+	// this limit applies to all compiler/VM instances in the process.
+	MaxBigIntBits = 1 << 20
+
+	// MaxDecimalDigits is the maximum number of digits of a decimal value,
+	// and the maximum number of its digits after the decimal point. It
+	// bounds the time and memory a single decimal operation can use. Note
+	// this limit applies to all compiler/VM instances in the process.
+	MaxDecimalDigits = 100000
 )
 
 const (
@@ -33,6 +46,13 @@ const (
 // CallableFunc is a function signature for the callable functions.
 type CallableFunc = func(args ...Object) (ret Object, err error)
 
+// ContextCallableFunc is a function signature for the callable functions
+// that receive the context of the VM that calls them.
+type ContextCallableFunc = func(
+	ctx context.Context,
+	args ...Object,
+) (ret Object, err error)
+
 // CountObjects returns the number of objects that a given object o contains.
 // For scalar value types, it will always be 1. For compound value types,
 // this will include its elements and all of their elements recursively.
@@ -84,6 +104,16 @@ func ToInt(o Object) (v int, ok bool) {
 	case *Float:
 		v = int(o.Value)
 		ok = true
+	case *BigInt:
+		if o.Value.IsInt64() {
+			v = int(o.Value.Int64())
+			ok = true
+		}
+	case *Decimal:
+		if i := o.BigInt(); i.IsInt64() {
+			v = int(i.Int64())
+			ok = true
+		}
 	case *Char:
 		v = int(o.Value)
 		ok = true
@@ -111,6 +141,16 @@ func ToInt64(o Object) (v int64, ok bool) {
 	case *Float:
 		v = int64(o.Value)
 		ok = true
+	case *BigInt:
+		if o.Value.IsInt64() {
+			v = o.Value.Int64()
+			ok = true
+		}
+	case *Decimal:
+		if i := o.BigInt(); i.IsInt64() {
+			v = i.Int64()
+			ok = true
+		}
 	case *Char:
 		v = int64(o.Value)
 		ok = true
@@ -138,6 +178,12 @@ func ToFloat64(o Object) (v float64, ok bool) {
 	case *Float:
 		v = o.Value
 		ok = true
+	case *BigInt:
+		v, _ = new(big.Float).SetInt(o.Value).Float64()
+		ok = true
+	case *Decimal:
+		v = o.Float64()
+		ok = true
 	case *String:
 		c, err := strconv.ParseFloat(o.Value, 64)
 		if err == nil {
@@ -181,11 +227,86 @@ func ToByteSlice(o Object) (v []byte, ok bool) {
 	return
 }
 
+// ToBigInt will try to convert object o to *big.Int value. Strings are
+// parsed with base prefixes ("0x", "0o", "0b") allowed, floats are
+// truncated.
+func ToBigInt(o Object) (v *big.Int, ok bool) {
+	switch o := o.(type) {
+	case *BigInt:
+		v = new(big.Int).Set(o.Value)
+		ok = true
+	case *Decimal:
+		v = o.BigInt()
+		ok = true
+	case *Int:
+		v = big.NewInt(o.Value)
+		ok = true
+	case *Float:
+		f := big.NewFloat(o.Value)
+		if !f.IsInf() {
+			v, _ = f.Int(nil)
+			ok = true
+		}
+	case *Char:
+		v = big.NewInt(int64(o.Value))
+		ok = true
+	case *Bool:
+		v = new(big.Int)
+		if o == TrueValue {
+			v.SetInt64(1)
+		}
+		ok = true
+	case *String:
+		v, ok = new(big.Int).SetString(o.Value, 0)
+	}
+	return
+}
+
+// ToDecimal will try to convert object o to a Decimal value. Floats are
+// converted using their shortest decimal representation.
+func ToDecimal(o Object) (v *Decimal, ok bool) {
+	switch o := o.(type) {
+	case *Decimal:
+		v = o.Copy().(*Decimal)
+		ok = true
+	case *BigInt:
+		v = &Decimal{Value: new(big.Int).Set(o.Value)}
+		ok = true
+	case *Int:
+		v = &Decimal{Value: big.NewInt(o.Value)}
+		ok = true
+	case *Float:
+		d, err := ParseDecimal(strconv.FormatFloat(o.Value, 'g', -1, 64))
+		if err == nil {
+			v = d
+			ok = true
+		}
+	case *Char:
+		v = &Decimal{Value: big.NewInt(int64(o.Value))}
+		ok = true
+	case *Bool:
+		v = &Decimal{Value: new(big.Int)}
+		if o == TrueValue {
+			v.Value.SetInt64(1)
+		}
+		ok = true
+	case *String:
+		d, err := ParseDecimal(o.Value)
+		if err == nil {
+			v = d
+			ok = true
+		}
+	}
+	return
+}
+
 // ToInterface attempts to convert an object o to an any value
 func ToInterface(o Object) (res any) {
 	switch o := o.(type) {
 	case *Int:
 		res = o.Value
+	case *BigInt:
+		res = new(big.Int).Set(o.Value)
 	case *String:
 		res = o.Value
 	case *Float:
@@ -216,10 +337,16 @@ func ToInterface(o Object) (res any) {
 		for key, v := range o.Value {
 			res.(map[string]any)[key] = ToInterface(v)
 		}
+	case *Set:
+		res = setInterface(o.Value)
+	case *ImmutableSet:
+		res = setInterface(o.Value)
 	case *Error:
 		res = errors.New(o.String())
 	case *Undefined:
 		res = nil
+	case *Proxy:
+		res = o.Value()
 	case Object:
 		return o
 	}
@@ -251,6 +378,11 @@ func FromInterface(v any) (Object, error) {
 		return &Char{Value: rune(v)}, nil
 	case float64:
 		return &Float{Value: v}, nil
+	case *big.Int:
+		if v == nil {
+			return UndefinedValue, nil
+		}
+		return &BigInt{Value: new(big.Int).Set(v)}, nil
 	case []byte:
 		if len(v) > MaxBytesLen {
 			return nil, ErrBytesLimit
@@ -270,6 +402,14 @@ func FromInterface(v any) (Object, error) {
 			kv[vk] = vo
 		}
 		return &Map{Value: kv}, nil
+	case map[any]struct{}:
+		return setFromKeys(v)
+	case map[string]struct{}:
+		return setFromKeys(v)
+	case map[int]struct{}:
+		return setFromKeys(v)
+	case map[int64]struct{}:
+		return setFromKeys(v)
 	case []Object:
 		return &Array{Value: v}, nil
 	case []any:
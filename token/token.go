@@ -20,53 +20,59 @@ const (
 	String
 	_literalEnd
 	_operatorBeg
-	Add          // +
-	Sub          // -
-	Mul          // *
-	Quo          // /
-	Rem          // %
-	And          // &
-	Or           // |
-	Xor          // ^
-	Shl          // <<
-	Shr          // >>
-	AndNot       // &^
-	AddAssign    // +=
-	SubAssign    // -=
-	MulAssign    // *=
-	QuoAssign    // /=
-	RemAssign    // %=
-	AndAssign    // &=
-	OrAssign     // |=
-	XorAssign    // ^=
-	ShlAssign    // <<=
-	ShrAssign    // >>=
-	AndNotAssign // &^=
-	LAnd         // &&
-	LOr          // ||
-	Inc          // ++
-	Dec          // --
-	Equal        // ==
-	Less         // <
-	Greater      // >
-	Assign       // =
-	Not          // !
-	NotEqual     // !=
-	LessEq       // <=
-	GreaterEq    // >=
-	Define       // :=
-	Ellipsis     // ...
-	LParen       // (
-	LBrack       // [
-	LBrace       // {
-	Comma        // ,
-	Period       // .
-	RParen       // )
-	RBrack       // ]
-	RBrace       // }
-	Semicolon    // ;
-	Colon        // :
-	Question     // ?
+	Add            // +
+	Sub            // -
+	Mul            // *
+	Quo            // /
+	Rem            // %
+	And            // &
+	Or             // |
+	Xor            // ^
+	Shl            // <<
+	Shr            // >>
+	AndNot         // &^
+	AddAssign      // +=
+	SubAssign      // -=
+	MulAssign      // *=
+	QuoAssign      // /=
+	RemAssign      // %=
+	AndAssign      // &=
+	OrAssign       // |=
+	XorAssign      // ^=
+	ShlAssign      // <<=
+	ShrAssign      // >>=
+	AndNotAssign   // &^=
+	LAnd           // &&
+	LOr            // ||
+	Inc            // ++
+	Dec            // --
+	Equal          // ==
+	Less           // <
+	Greater        // >
+	Assign         // =
+	Not            // !
+	NotEqual       // !=
+	LessEq         // <=
+	GreaterEq      // >=
+	Define         // :=
+	Ellipsis       // ...
+	LParen         // (
+	LBrack         // [
+	LBrace         // {
+	Comma          // ,
+	Period         // .
+	RParen         // )
+	RBrack         // ]
+	RBrace         // }
+	Semicolon      // ;
+	Colon          // :
+	Question       // ?
+	Coalesce       // ??
+	QuestionPeriod // ?.
+	NotIn          // not in
+	Arrow          // =>
+	Pow            // **
+	PowAssign      // **=
 	_operatorEnd
 	_keywordBeg
 	Break
@@ -84,80 +90,96 @@ const (
 	In
 	Undefined
 	Import
+	Defer
+	Yield
+	Const
 	_keywordEnd
+	InterpBegin // f"...${ or f"..."
+	InterpCont  // }...${ or }..."
 )
 
 var tokens = [...]string{
-	Illegal:      "ILLEGAL",
-	EOF:          "EOF",
-	Comment:      "COMMENT",
-	Ident:        "IDENT",
-	Int:          "INT",
-	Float:        "FLOAT",
-	Char:         "CHAR",
-	String:       "STRING",
-	Add:          "+",
-	Sub:          "-",
-	Mul:          "*",
-	Quo:          "/",
-	Rem:          "%",
-	And:          "&",
-	Or:           "|",
-	Xor:          "^",
-	Shl:          "<<",
-	Shr:          ">>",
-	AndNot:       "&^",
-	AddAssign:    "+=",
-	SubAssign:    "-=",
-	MulAssign:    "*=",
-	QuoAssign:    "/=",
-	RemAssign:    "%=",
-	AndAssign:    "&=",
-	OrAssign:     "|=",
-	XorAssign:    "^=",
-	ShlAssign:    "<<=",
-	ShrAssign:    ">>=",
-	AndNotAssign: "&^=",
-	LAnd:         "&&",
-	LOr:          "||",
-	Inc:          "++",
-	Dec:          "--",
-	Equal:        "==",
-	Less:         "<",
-	Greater:      ">",
-	Assign:       "=",
-	Not:          "!",
-	NotEqual:     "!=",
-	LessEq:       "<=",
-	GreaterEq:    ">=",
-	Define:       ":=",
-	Ellipsis:     "...",
-	LParen:       "(",
-	LBrack:       "[",
-	LBrace:       "{",
-	Comma:        ",",
-	Period:       ".",
-	RParen:       ")",
-	RBrack:       "]",
-	RBrace:       "}",
-	Semicolon:    ";",
-	Colon:        ":",
-	Question:     "?",
-	Break:        "break",
-	Continue:     "continue",
-	Else:         "else",
-	For:          "for",
-	Func:         "func",
-	Error:        "error",
-	Immutable:    "immutable",
-	If:           "if",
-	Return:       "return",
-	Export:       "export",
-	True:         "true",
-	False:        "false",
-	In:           "in",
-	Undefined:    "undefined",
-	Import:       "import",
+	Illegal:        "ILLEGAL",
+	EOF:            "EOF",
+	Comment:        "COMMENT",
+	Ident:          "IDENT",
+	Int:            "INT",
+	Float:          "FLOAT",
+	Char:           "CHAR",
+	String:         "STRING",
+	Add:            "+",
+	Sub:            "-",
+	Mul:            "*",
+	Quo:            "/",
+	Rem:            "%",
+	And:            "&",
+	Or:             "|",
+	Xor:            "^",
+	Shl:            "<<",
+	Shr:            ">>",
+	AndNot:         "&^",
+	AddAssign:      "+=",
+	SubAssign:      "-=",
+	MulAssign:      "*=",
+	QuoAssign:      "/=",
+	RemAssign:      "%=",
+	AndAssign:      "&=",
+	OrAssign:       "|=",
+	XorAssign:      "^=",
+	ShlAssign:      "<<=",
+	ShrAssign:      ">>=",
+	AndNotAssign:   "&^=",
+	LAnd:           "&&",
+	LOr:            "||",
+	Inc:            "++",
+	Dec:            "--",
+	Equal:          "==",
+	Less:           "<",
+	Greater:        ">",
+	Assign:         "=",
+	Not:            "!",
+	NotEqual:       "!=",
+	LessEq:         "<=",
+	GreaterEq:      ">=",
+	Define:         ":=",
+	Ellipsis:       "...",
+	LParen:         "(",
+	LBrack:         "[",
+	LBrace:         "{",
+	Comma:          ",",
+	Period:         ".",
+	RParen:         ")",
+	RBrack:         "]",
+	RBrace:         "}",
+	Semicolon:      ";",
+	Colon:          ":",
+	Question:       "?",
+	Coalesce:       "??",
+	QuestionPeriod: "?.",
+	NotIn:          "not in",
+	Arrow:          "=>",
+	Pow:            "**",
+	PowAssign:      "**=",
+	Break:          "break",
+	Continue:       "continue",
+	Else:           "else",
+	For:            "for",
+	Func:           "func",
+	Error:          "error",
+	Immutable:      "immutable",
+	If:             "if",
+	Return:         "return",
+	Export:         "export",
+	True:           "true",
+	False:          "false",
+	In:             "in",
+	Undefined:      "undefined",
+	Import:         "import",
+	Defer:          "defer",
+	Yield:          "yield",
+	Const:          "const",
+	InterpBegin:    "INTERP_BEGIN",
+	InterpCont:     "INTERP_CONT",
 }
 
 func (tok Token) String() string {
@@ -180,23 +202,28 @@ const LowestPrec = 0
 // Precedence returns the precedence for the operator token.
 func (tok Token) Precedence() int {
 	switch tok {
-	case LOr:
+	case Coalesce:
 		return 1
-	case LAnd:
+	case LOr:
 		return 2
-	case Equal, NotEqual, Less, LessEq, Greater, GreaterEq:
+	case LAnd:
 		return 3
-	case Add, Sub, Or, Xor:
+	case Equal, NotEqual, Less, LessEq, Greater, GreaterEq, In, NotIn:
 		return 4
-	case Mul, Quo, Rem, Shl, Shr, And, AndNot:
+	case Add, Sub, Or, Xor:
 		return 5
+	case Mul, Quo, Rem, Shl, Shr, And, AndNot:
+		return 6
+	case Pow:
+		return 7
 	}
 	return LowestPrec
 }
 
 // IsLiteral returns true if the token is a literal.
 func (tok Token) IsLiteral() bool {
-	return _literalBeg < tok && tok < _literalEnd
+	return _literalBeg < tok && tok < _literalEnd ||
+		tok == InterpBegin || tok == InterpCont
 }
 
 // IsOperator returns true if the token is an operator.
@@ -0,0 +1,652 @@
+package tengo
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	bigIntType = reflect.TypeOf((*big.Int)(nil))
+	timeType   = reflect.TypeOf(time.Time{})
+	emptyType  = reflect.TypeOf(struct{}{})
+)
+
+// goTimer is implemented by objects that hold a time, such as datetime.Time
+// of the standard library.
+type goTimer interface {
+	GoTime() time.Time
+}
+
+// FromValue converts a Go value v to a Tengo Object using reflection. Unlike
+// FromInterface, it accepts values of any numeric type, typed slices, arrays
+// and maps, pointers and structs:
+//
+//   - numbers become ints or floats; unsigned values above MaxInt64 become
+//     bigints. Note that rune and byte are the same types as int32 and uint8,
+//     so they become ints too.
+//   - slices and arrays become arrays, except byte slices that become bytes.
+//   - maps with string or integer keys become maps, and maps with struct{}
+//     values become sets.
+//   - structs become maps of their exported fields (see below).
+//   - nil pointers and interfaces become undefined; others are dereferenced.
+//   - time.Time becomes a string in RFC 3339 format with nanoseconds.
+//   - Objects, *big.Int, errors and CallableFunc are converted like
+//     FromInterface does.
+//
+// A struct field is stored under its name, unless it has a tag
+// `tengo:"name"`. The "omitempty" option, as in `tengo:"name,omitempty"`,
+// omits the field if it has a zero value or is an empty slice or map, and
+// the tag `tengo:"-"` omits the field always. Fields of embedded structs are
+// stored as if they were fields of the outer struct.
+//
+// A value that contains itself through pointers, maps or slices cannot be
+// converted and is an error.
+func FromValue(v any) (Object, error) {
+	if v == nil {
+		return UndefinedValue, nil
+	}
+	return fromValue(reflect.ValueOf(v))
+}
+
+func fromValue(v reflect.Value) (Object, error) {
+	return fromValuePath(v, nil)
+}
+
+// visit identifies a pointer, map or slice being converted, so that a value
+// that refers to itself is reported instead of recursing forever.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// fromValuePath converts v, where path holds the pointers, maps and slices
+// that are being converted and contain v.
+func fromValuePath(v reflect.Value, path map[visit]bool) (Object, error) {
+	if !v.IsValid() {
+		return UndefinedValue, nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return UndefinedValue, nil
+		}
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			break
+		}
+		key := visit{ptr: v.Pointer(), typ: v.Type()}
+		if v.Kind() == reflect.Slice {
+			key.len = v.Len()
+		}
+		if path[key] {
+			return nil, fmt.Errorf("cannot convert cyclic value: %s",
+				v.Type())
+		}
+		if path == nil {
+			path = make(map[visit]bool)
+		}
+		path[key] = true
+		defer delete(path, key)
+	}
+	if v.CanInterface() {
+		switch x := v.Interface().(type) {
+		case Object:
+			return x, nil
+		case *big.Int, error, CallableFunc:
+			return FromInterface(x)
+		case time.Time:
+			return &String{Value: x.Format(time.RFC3339Nano)}, nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return TrueValue, nil
+		}
+		return FalseValue, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return &Int{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return &BigInt{Value: new(big.Int).SetUint64(u)}, nil
+		}
+		return &Int{Value: int64(u)}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: v.Float()}, nil
+	case reflect.String:
+		if v.Len() > MaxStringLen {
+			return nil, ErrStringLimit
+		}
+		return &String{Value: v.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Len() > MaxBytesLen {
+				return nil, ErrBytesLimit
+			}
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return &Bytes{Value: b}, nil
+		}
+		arr := make([]Object, v.Len())
+		for i := range arr {
+			o, err := fromValuePath(v.Index(i), path)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			arr[i] = o
+		}
+		return &Array{Value: arr}, nil
+	case reflect.Map:
+		if v.Type().Elem() == emptyType {
+			return setFromValue(v, path)
+		}
+		kv := make(map[string]Object, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k, err := mapKeyString(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			o, err := fromValuePath(iter.Value(), path)
+			if err != nil {
+				return nil, fmt.Errorf("key '%s': %w", k, err)
+			}
+			kv[k] = o
+		}
+		return &Map{Value: kv}, nil
+	case reflect.Pointer, reflect.Interface:
+		return fromValuePath(v.Elem(), path)
+	case reflect.Struct:
+		kv := make(map[string]Object)
+		for _, f := range structFields(v.Type()) {
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok || f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			o, err := fromValuePath(fv, path)
+			if err != nil {
+				return nil, fmt.Errorf("field '%s': %w", f.name, err)
+			}
+			kv[f.name] = o
+		}
+		return &Map{Value: kv}, nil
+	}
+	return nil, fmt.Errorf("cannot convert to object: %s", v.Type())
+}
+
+func setFromValue(v reflect.Value, path map[visit]bool) (Object, error) {
+	s := &Set{Value: make(map[any]Object, v.Len())}
+	iter := v.MapRange()
+	for iter.Next() {
+		o, err := fromValuePath(iter.Key(), path)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Add(o); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func mapKeyString(k reflect.Value) (string, error) {
+	switch k.Kind() {
+	case reflect.String:
+		return k.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("cannot convert map key to string: %s", k.Type())
+}
+
+// ToValue converts v to a Go value of type T. v can be an Object or a Go
+// value, such as the result of Compiled.CallByName or Variable.Value, which
+// is converted with FromValue first. It is the reverse of FromValue:
+//
+//   - ints, chars and integral bigints convert to integer types, and ints
+//     and floats convert to float types; a value that does not fit the type
+//     is an error.
+//   - arrays and immutable arrays convert to slices and arrays, and bytes
+//     and strings convert to byte slices.
+//   - maps and immutable maps convert to maps and structs; struct fields are
+//     matched by the same names as FromValue uses, and map entries without a
+//     field are ignored. Sets convert to maps with struct{} values.
+//   - undefined converts to the zero value of any type.
+//   - time.Time is converted from a string in RFC 3339 format or an object
+//     that has a GoTime() time.Time method, such as datetime.Time.
+//   - Object types receive the object itself, and the empty interface
+//     receives the result of ToInterface.
+//   - a Proxy converts to its Go value, which is not copied, or to a copy
+//     converted from it.
+func ToValue[T any](v any) (res T, err error) {
+	o, ok := v.(Object)
+	if !ok {
+		if o, err = FromValue(v); err != nil {
+			return
+		}
+	}
+	err = toValue(o, reflect.ValueOf(&res).Elem())
+	return
+}
+
+func toValue(o Object, v reflect.Value) error {
+	t := v.Type()
+	if o == nil {
+		o = UndefinedValue
+	}
+	// Object types, but not the empty interface
+	if t.NumMethod() > 0 && reflect.TypeOf(o).AssignableTo(t) {
+		v.Set(reflect.ValueOf(o))
+		return nil
+	}
+	if o == UndefinedValue {
+		v.Set(reflect.Zero(t))
+		return nil
+	}
+	if p, ok := o.(*Proxy); ok {
+		// pass the Go value by reference if possible, or copy it otherwise
+		if pv := reflect.ValueOf(p.Value()); pv.Type().AssignableTo(t) {
+			v.Set(pv)
+			return nil
+		}
+		if p.value.Type().AssignableTo(t) {
+			v.Set(p.value)
+			return nil
+		}
+		o = p.Copy()
+	}
+
+	switch t {
+	case bigIntType:
+		var b *big.Int
+		switch o := o.(type) {
+		case *BigInt:
+			b = new(big.Int).Set(o.Value)
+		case *Int:
+			b = big.NewInt(o.Value)
+		case *Char:
+			b = big.NewInt(int64(o.Value))
+		default:
+			return convertError(o, t)
+		}
+		v.Set(reflect.ValueOf(b))
+		return nil
+	case timeType:
+		switch o := o.(type) {
+		case goTimer:
+			v.Set(reflect.ValueOf(o.GoTime()))
+			return nil
+		case *String:
+			tm, err := time.Parse(time.RFC3339Nano, o.Value)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(tm))
+			return nil
+		}
+		return convertError(o, t)
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		if o != TrueValue && o != FalseValue {
+			return convertError(o, t)
+		}
+		v.SetBool(o == TrueValue)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		i, ok := objectInt64(o)
+		if !ok {
+			return convertError(o, t)
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows %s", i, t)
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		if b, ok := o.(*BigInt); ok && b.Value.IsUint64() {
+			u = b.Value.Uint64()
+		} else {
+			i, ok := objectInt64(o)
+			if !ok {
+				return convertError(o, t)
+			}
+			if i < 0 {
+				return fmt.Errorf("value %d overflows %s", i, t)
+			}
+			u = uint64(i)
+		}
+		if v.OverflowUint(u) {
+			return fmt.Errorf("value %d overflows %s", u, t)
+		}
+		v.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch o := o.(type) {
+		case *Float:
+			f = o.Value
+		case *Int:
+			f = float64(o.Value)
+		default:
+			return convertError(o, t)
+		}
+		if v.OverflowFloat(f) {
+			return fmt.Errorf("value %g overflows %s", f, t)
+		}
+		v.SetFloat(f)
+		return nil
+	case reflect.String:
+		s, ok := o.(*String)
+		if !ok {
+			return convertError(o, t)
+		}
+		v.SetString(s.Value)
+		return nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			var b []byte
+			switch o := o.(type) {
+			case *Bytes:
+				b = o.Value
+			case *String:
+				b = []byte(o.Value)
+			}
+			if b != nil {
+				if t.Kind() == reflect.Slice {
+					v.Set(reflect.MakeSlice(t, len(b), len(b)))
+				}
+				reflect.Copy(v, reflect.ValueOf(b))
+				return nil
+			}
+		}
+		elems, ok := objectElements(o)
+		if !ok {
+			return convertError(o, t)
+		}
+		if t.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(t, len(elems), len(elems)))
+		} else {
+			v.Set(reflect.Zero(t))
+		}
+		for i, e := range elems {
+			if i == v.Len() {
+				break
+			}
+			if err := toValue(e, v.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		return nil
+	case reflect.Map:
+		if t.Elem() == emptyType {
+			elems, ok := toSetValue(o)
+			if !ok {
+				return convertError(o, t)
+			}
+			m := reflect.MakeMapWithSize(t, len(elems))
+			for _, e := range elems {
+				k := reflect.New(t.Key()).Elem()
+				if err := toValue(e, k); err != nil {
+					return err
+				}
+				m.SetMapIndex(k, reflect.Zero(emptyType))
+			}
+			v.Set(m)
+			return nil
+		}
+		kv, ok := objectMap(o)
+		if !ok {
+			return convertError(o, t)
+		}
+		m := reflect.MakeMapWithSize(t, len(kv))
+		for key, e := range kv {
+			k := reflect.New(t.Key()).Elem()
+			if err := setMapKey(key, k); err != nil {
+				return err
+			}
+			ev := reflect.New(t.Elem()).Elem()
+			if err := toValue(e, ev); err != nil {
+				return fmt.Errorf("key '%s': %w", key, err)
+			}
+			m.SetMapIndex(k, ev)
+		}
+		v.Set(m)
+		return nil
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return toValue(o, v.Elem())
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return convertError(o, t)
+		}
+		if res := ToInterface(o); res != nil {
+			v.Set(reflect.ValueOf(res))
+		} else {
+			v.Set(reflect.Zero(t))
+		}
+		return nil
+	case reflect.Struct:
+		kv, ok := objectMap(o)
+		if !ok {
+			return convertError(o, t)
+		}
+		for _, f := range structFields(t) {
+			e, ok := kv[f.name]
+			if !ok {
+				continue
+			}
+			if err := toValue(e, fieldByIndexAlloc(v, f.index)); err != nil {
+				return fmt.Errorf("field '%s': %w", f.name, err)
+			}
+		}
+		return nil
+	}
+	return convertError(o, t)
+}
+
+func convertError(o Object, t reflect.Type) error {
+	return fmt.Errorf("cannot convert %s to %s", o.TypeName(), t)
+}
+
+func objectInt64(o Object) (int64, bool) {
+	switch o := o.(type) {
+	case *Int:
+		return o.Value, true
+	case *Char:
+		return int64(o.Value), true
+	case *BigInt:
+		if o.Value.IsInt64() {
+			return o.Value.Int64(), true
+		}
+	}
+	return 0, false
+}
+
+func objectElements(o Object) ([]Object, bool) {
+	switch o := o.(type) {
+	case *Array:
+		return o.Value, true
+	case *ImmutableArray:
+		return o.Value, true
+	}
+	return nil, false
+}
+
+func objectMap(o Object) (map[string]Object, bool) {
+	switch o := o.(type) {
+	case *Map:
+		return o.Value, true
+	case *ImmutableMap:
+		return o.Value, true
+	}
+	return nil, false
+}
+
+func setMapKey(key string, k reflect.Value) error {
+	switch k.Kind() {
+	case reflect.String:
+		k.SetString(key)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		i, err := strconv.ParseInt(key, 10, k.Type().Bits())
+		if err != nil {
+			return err
+		}
+		k.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(key, 10, k.Type().Bits())
+		if err != nil {
+			return err
+		}
+		k.SetUint(u)
+		return nil
+	}
+	return fmt.Errorf("cannot convert string to map key: %s", k.Type())
+}
+
+// structField describes a struct field converted by FromValue and ToValue.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var structFieldsCache sync.Map // map[reflect.Type][]structField
+
+// structFields returns the converted fields of a struct type t, including
+// the fields of embedded structs. A field of the outer struct hides fields
+// of the same name of embedded structs.
+func structFields(t reflect.Type) []structField {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.([]structField)
+	}
+
+	var fields []structField
+	seen := make(map[string]bool)
+	// embedded structs are walked breadth-first, so that shallower fields
+	// hide deeper ones
+	type embedded struct {
+		t     reflect.Type
+		index []int
+	}
+	current := []embedded{{t: t}}
+	visited := map[reflect.Type]bool{t: true}
+	for len(current) > 0 {
+		var next []embedded
+		names := make(map[string]bool)
+		for _, e := range current {
+			for i := 0; i < e.t.NumField(); i++ {
+				sf := e.t.Field(i)
+				index := append(append([]int(nil), e.index...), i)
+				tag := sf.Tag.Get("tengo")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				if !sf.IsExported() && sf.Type.Kind() == reflect.Pointer {
+					// cannot be allocated by ToValue
+					continue
+				}
+				if sf.Anonymous && name == "" {
+					ft := sf.Type
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct {
+						if !visited[ft] {
+							visited[ft] = true
+							next = append(next, embedded{ft, index})
+						}
+						continue
+					}
+				}
+				if !sf.IsExported() {
+					continue
+				}
+				if name == "" {
+					name = sf.Name
+				}
+				if seen[name] {
+					continue
+				}
+				names[name] = true
+				fields = append(fields, structField{
+					name:      name,
+					index:     index,
+					omitEmpty: opts == "omitempty",
+				})
+			}
+		}
+		for name := range names {
+			seen[name] = true
+		}
+		current = next
+	}
+
+	structFieldsCache.Store(t, fields)
+	return fields
+}
+
+// fieldByIndex returns the field of v at index; ok is false if the field
+// is in an embedded struct through a nil pointer.
+func fieldByIndex(v reflect.Value, index []int) (f reflect.Value, ok bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// fieldByIndexAlloc returns the field of v at index, allocating embedded
+// structs through nil pointers.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,213 @@
+package tengo_test
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shelepuginivan/tengo"
+	"github.com/shelepuginivan/tengo/require"
+)
+
+type valueAddress struct {
+	City string `tengo:"city"`
+	Zip  *int   `tengo:"zip,omitempty"`
+}
+
+type valueBase struct {
+	ID   int64  `tengo:"id"`
+	Name string `tengo:"name"`
+}
+
+type valuePerson struct {
+	valueBase
+	Name    string            `tengo:"name"`
+	Age     uint8             `tengo:"age"`
+	Score   float32           `tengo:"score"`
+	Tags    []string          `tengo:"tags,omitempty"`
+	Counts  map[string]int32  `tengo:"counts"`
+	Address *valueAddress     `tengo:"address"`
+	Labels  map[string]string `tengo:"-"`
+	Born    time.Time         `tengo:"born"`
+	secret  string
+}
+
+func TestFromValue(t *testing.T) {
+	expectFromValue(t, nil, tengo.UndefinedValue)
+	expectFromValue(t, int32(-5), &tengo.Int{Value: -5})
+	expectFromValue(t, uint(7), &tengo.Int{Value: 7})
+	expectFromValue(t, uint64(math.MaxUint64),
+		&tengo.BigInt{Value: new(big.Int).SetUint64(math.MaxUint64)})
+	expectFromValue(t, float32(1.5), &tengo.Float{Value: 1.5})
+	expectFromValue(t, true, tengo.TrueValue)
+	expectFromValue(t, []string{"a", "b"}, ARR{"a", "b"})
+	expectFromValue(t, [2]int16{1, 2}, ARR{1, 2})
+	expectFromValue(t, []byte("abc"), []byte("abc"))
+	expectFromValue(t, map[string]int{"a": 1}, MAP{"a": 1})
+	expectFromValue(t, map[int][]float64{1: {2}}, MAP{"1": ARR{2.0}})
+	expectFromValue(t, (*int)(nil), tengo.UndefinedValue)
+	expectFromValue(t, &[]any{1, "a", nil}, ARR{1, "a", tengo.UndefinedValue})
+	expectFromValue(t, time.Date(2024, 5, 1, 10, 0, 0, 5, time.UTC),
+		"2024-05-01T10:00:00.000000005Z")
+
+	o, err := tengo.FromValue(map[string]struct{}{"a": {}})
+	require.NoError(t, err)
+	require.Equal(t, "set(\"a\")", o.String())
+
+	zip := 12345
+	expectFromValue(t, valuePerson{
+		valueBase: valueBase{ID: 3, Name: "hidden"},
+		Name:      "Ann",
+		Age:       31,
+		Score:     0.5,
+		Counts:    map[string]int32{"x": 1},
+		Address:   &valueAddress{City: "Oslo", Zip: &zip},
+		Labels:    map[string]string{"a": "b"},
+		Born:      time.Date(1993, 1, 2, 0, 0, 0, 0, time.UTC),
+		secret:    "s",
+	}, MAP{
+		"id":      3,
+		"name":    "Ann",
+		"age":     31,
+		"score":   0.5,
+		"counts":  MAP{"x": 1},
+		"address": MAP{"city": "Oslo", "zip": 12345},
+		"born":    "1993-01-02T00:00:00Z",
+	})
+	expectFromValue(t, &valueAddress{City: "Rome"}, MAP{"city": "Rome"})
+
+	_, err = tengo.FromValue(make(chan int))
+	require.Error(t, err)
+	_, err = tengo.FromValue(map[string]any{"a": []any{func() {}}})
+	require.True(t, err != nil &&
+		strings.HasPrefix(err.Error(), "key 'a': index 0: cannot convert"))
+
+	// cyclic values are an error, while shared values are not
+	type node struct {
+		Next *node `tengo:"next"`
+	}
+	n := &node{}
+	n.Next = n
+	_, err = tengo.FromValue(n)
+	require.True(t, err != nil && strings.HasPrefix(err.Error(),
+		"field 'next': cannot convert cyclic value: *tengo_test.node"),
+		"unexpected error: %v", err)
+	m := map[string]any{}
+	m["self"] = m
+	_, err = tengo.FromValue(m)
+	require.True(t, err != nil && strings.Contains(err.Error(),
+		"cannot convert cyclic value"), "unexpected error: %v", err)
+	arr := []any{nil}
+	arr[0] = arr
+	_, err = tengo.FromValue(arr)
+	require.True(t, err != nil && strings.Contains(err.Error(),
+		"cannot convert cyclic value"), "unexpected error: %v", err)
+	shared := &valueAddress{City: "Oslo"}
+	expectFromValue(t, []*valueAddress{shared, shared},
+		ARR{MAP{"city": "Oslo"}, MAP{"city": "Oslo"}})
+}
+
+func TestToValue(t *testing.T) {
+	i, err := tengo.ToValue[int32](&tengo.Int{Value: 5})
+	require.NoError(t, err)
+	require.True(t, i == 5)
+
+	u, err := tengo.ToValue[uint64](&tengo.BigInt{
+		Value: new(big.Int).SetUint64(math.MaxUint64)})
+	require.NoError(t, err)
+	require.True(t, u == math.MaxUint64)
+
+	f, err := tengo.ToValue[float32](&tengo.Int{Value: 2})
+	require.NoError(t, err)
+	require.True(t, f == 2)
+
+	s, err := tengo.ToValue[[]string](ARR{"a", "b"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, s)
+
+	b, err := tengo.ToValue[[]byte](&tengo.String{Value: "ab"})
+	require.NoError(t, err)
+	require.Equal(t, []byte("ab"), b)
+
+	m, err := tengo.ToValue[map[string][]int]([]any{1})
+	require.Error(t, err)
+	require.Nil(t, m)
+
+	set, err := tengo.ToValue[map[string]struct{}](
+		map[string]struct{}{"a": {}, "b": {}})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(set))
+
+	ptr, err := tengo.ToValue[*int](tengo.UndefinedValue)
+	require.NoError(t, err)
+	require.Nil(t, ptr)
+
+	obj, err := tengo.ToValue[tengo.Object](tengo.UndefinedValue)
+	require.NoError(t, err)
+	require.Equal(t, tengo.UndefinedValue, obj)
+
+	iface, err := tengo.ToValue[any](ARR{1, "a"})
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(1), "a"}, iface)
+
+	p, err := tengo.ToValue[valuePerson](MAP{
+		"id":      3,
+		"name":    "Ann",
+		"age":     31,
+		"score":   0.5,
+		"tags":    ARR{"x"},
+		"counts":  MAP{"x": 1},
+		"address": MAP{"city": "Oslo", "zip": 12345},
+		"born":    "1993-01-02T00:00:00Z",
+		"other":   "ignored",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), p.ID)
+	require.Equal(t, "Ann", p.Name)
+	require.True(t, p.Age == 31)
+	require.True(t, p.Score == 0.5)
+	require.Equal(t, []string{"x"}, p.Tags)
+	require.True(t, p.Counts["x"] == 1)
+	require.Equal(t, "Oslo", p.Address.City)
+	require.Equal(t, 12345, *p.Address.Zip)
+	require.Equal(t, time.Date(1993, 1, 2, 0, 0, 0, 0, time.UTC), p.Born)
+
+	_, err = tengo.ToValue[uint8](&tengo.Int{Value: 256})
+	require.Error(t, err)
+	require.Equal(t, "value 256 overflows uint8", err.Error())
+	_, err = tengo.ToValue[uint](&tengo.Int{Value: -1})
+	require.Error(t, err)
+	_, err = tengo.ToValue[int](&tengo.String{Value: "1"})
+	require.Error(t, err)
+	require.Equal(t, "cannot convert string to int", err.Error())
+	_, err = tengo.ToValue[valuePerson](MAP{"address": MAP{"zip": "x"}})
+	require.Error(t, err)
+	require.Equal(t, "field 'address': field 'zip': cannot convert string to int",
+		err.Error())
+}
+
+func TestToValue_CallByName(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+	s := tengo.NewScript([]byte(`
+move := func(p, d) { return {X: p.X + d, Y: p.Y - d} }`))
+	c, err := s.CompileRun()
+	require.NoError(t, err)
+
+	arg, err := tengo.FromValue(point{X: 1, Y: 2})
+	require.NoError(t, err)
+	res, err := c.CallByName("move", arg, 3)
+	require.NoError(t, err)
+	p, err := tengo.ToValue[point](res)
+	require.NoError(t, err)
+	require.True(t, p == point{X: 4, Y: -1})
+}
+
+func expectFromValue(t *testing.T, v any, expected any) {
+	o, err := tengo.FromValue(v)
+	require.NoError(t, err)
+	require.Equal(t, toObject(expected), o)
+}
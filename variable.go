@@ -2,12 +2,14 @@ package tengo
 
 import (
 	"errors"
+	"fmt"
 )
 
 // Variable is a user-defined variable for the script.
 type Variable struct {
-	name  string
-	value Object
+	name     string
+	value    Object
+	readOnly bool
 }
 
 // NewVariable creates a Variable.
@@ -134,3 +136,43 @@ func (v *Variable) Object() Object {
 func (v *Variable) IsUndefined() bool {
 	return v.value == UndefinedValue
 }
+
+// liveGlobal is the value of a global variable added by Script.AddLive. The
+// VM calls get each time the script reads the variable, and set each time
+// the script assigns to it.
+type liveGlobal struct {
+	ObjectImpl
+	name  string
+	getFn func() (any, error)
+	setFn func(any) error
+}
+
+// TypeName returns the name of the type.
+func (o *liveGlobal) TypeName() string {
+	return "live-variable"
+}
+
+func (o *liveGlobal) String() string {
+	return "<live-variable>"
+}
+
+// Copy returns the live variable itself, so that clones of a compiled
+// script share the getter and the setter.
+func (o *liveGlobal) Copy() Object {
+	return o
+}
+
+func (o *liveGlobal) get() (Object, error) {
+	v, err := o.getFn()
+	if err != nil {
+		return nil, err
+	}
+	return FromValue(v)
+}
+
+func (o *liveGlobal) set(value Object) error {
+	if o.setFn == nil {
+		return fmt.Errorf("cannot assign to read-only variable '%s'", o.name)
+	}
+	return o.setFn(ToInterface(value))
+}
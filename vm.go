@@ -1,7 +1,11 @@
 package tengo
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"sync/atomic"
 
 	"github.com/shelepuginivan/tengo/parser"
@@ -14,6 +18,21 @@ type frame struct {
 	freeVars    []*ObjectPtr
 	ip          int
 	basePointer int
+	defers      []*deferredCall
+	retVal      Object // return value kept while deferred calls run
+}
+
+// deferredCall represents a function call deferred until the frame that
+// declared it returns.
+type deferredCall struct {
+	fn   Object
+	args []Object
+}
+
+// suspendFn is used as the caller frame of deferred calls made while
+// unwinding the frames after a runtime error.
+var suspendFn = &CompiledFunction{
+	Instructions: []byte{parser.OpSuspend},
 }
 
 // VM is a virtual machine that executes the bytecode compiled by Compiler.
@@ -29,9 +48,19 @@ type VM struct {
 	curInsts    []byte
 	ip          int
 	aborting    int64
+	nested      atomic.Pointer[VM] // VM run by runNested
 	maxAllocs   int64
 	allocs      int64
+	maxInsts    int64
+	insts       int64
+	countInsts  bool // whether insts is counted
 	err         error
+	ctx         context.Context
+
+	intOverflowCheck bool
+	decimalQuo       bool // whether decimalPrecision is set
+	decimalPrecision int
+	decimalRounding  RoundingMode
 }
 
 // NewVM creates a VM.
@@ -51,6 +80,8 @@ func NewVM(
 		framesIndex: 1,
 		ip:          -1,
 		maxAllocs:   maxAllocs,
+		maxInsts:    -1,
+		ctx:         context.Background(),
 	}
 	v.frames[0].fn = bytecode.MainFunction
 	v.frames[0].ip = -1
@@ -59,45 +90,128 @@ func NewVM(
 	return v
 }
 
+// EnableIntOverflowCheck enables or disables int overflow checks. When
+// enabled, int arithmetic that overflows int64 is a runtime error instead
+// of wrapping around.
+func (v *VM) EnableIntOverflowCheck(enable bool) {
+	v.intOverflowCheck = enable
+}
+
+// SetDecimalPrecision sets the number of digits after the decimal point kept
+// in the result of a decimal division, and its rounding mode. Unless it's
+// set, decimal division uses DecimalPrecision and DecimalRounding.
+func (v *VM) SetDecimalPrecision(places int, mode RoundingMode) {
+	v.decimalQuo = true
+	v.decimalPrecision = places
+	v.decimalRounding = mode
+}
+
+// SetMaxInstructions sets the maximum number of instructions that the VM
+// executes in a run. The run fails with ErrInstructionLimit if it executes
+// more instructions. A negative n means no limit, which is the default;
+// instructions are not counted then.
+func (v *VM) SetMaxInstructions(n int64) {
+	v.maxInsts = n
+	v.countInsts = n >= 0
+}
+
 // Abort aborts the execution.
 func (v *VM) Abort() {
 	atomic.StoreInt64(&v.aborting, 1)
+	if nested := v.nested.Load(); nested != nil {
+		nested.Abort()
+	}
 }
 
 // Run starts the execution.
 func (v *VM) Run() (err error) {
+	v.allocs = v.maxAllocs + 1
+	v.insts = v.maxInsts + 1
+	err = v.execute()
+	atomic.StoreInt64(&v.aborting, 0)
+	return err
+}
+
+// runNested runs v on behalf of parent, e.g. to resume a generator of parent
+// from Go code. v uses the context of parent and is aborted with it, and the
+// allocations and instructions of v count towards the limits of parent.
+func (v *VM) runNested(parent *VM) (err error) {
+	parent.nested.Store(v)
+	defer parent.nested.Store(nil)
+	if parent.aborted() {
+		v.Abort()
+		return nil
+	}
+	v.ctx = parent.ctx
+	v.countInsts = parent.countInsts
+	v.allocs, v.insts = parent.allocs, parent.insts
+	err = v.execute()
+	parent.allocs, parent.insts = v.allocs, v.insts
+	// a limit reached by v also stops parent at its next allocation or
+	// instruction
+	if parent.allocs == 0 {
+		parent.allocs = 1
+	}
+	if parent.insts == 0 {
+		parent.insts = 1
+	}
+	return err
+}
+
+// aborted returns whether the execution is being aborted.
+func (v *VM) aborted() bool {
+	return atomic.LoadInt64(&v.aborting) != 0
+}
+
+func (v *VM) execute() (err error) {
 	// reset VM states
 	v.sp = 0
 	v.curFrame = &(v.frames[0])
 	v.curInsts = v.curFrame.fn.Instructions
 	v.framesIndex = 1
 	v.ip = -1
-	v.allocs = v.maxAllocs + 1
 
 	v.run()
-	atomic.StoreInt64(&v.aborting, 0)
 	err = v.err
 	if err != nil {
 		filePos := v.fileSet.Position(
 			v.curFrame.fn.SourcePos(v.ip - 1))
 		err = fmt.Errorf("Runtime Error: %w\n\tat %s",
 			err, filePos)
-		for v.framesIndex > 1 {
-			v.framesIndex--
-			v.curFrame = &v.frames[v.framesIndex-1]
-			filePos = v.fileSet.Position(
-				v.curFrame.fn.SourcePos(v.curFrame.ip - 1))
+		for i := v.framesIndex - 1; i > 0; i-- {
+			f := &v.frames[i-1]
+			filePos = v.fileSet.Position(f.fn.SourcePos(f.ip - 1))
 			err = fmt.Errorf("%w\n\tat %s", err, filePos)
 		}
+
+		// the frames are unwound: run their pending deferred calls
+		vmErr := v.err
+		v.unwindDefers(1)
+		v.err = vmErr
+		v.curFrame = &v.frames[0]
 		return err
 	}
+	if v.framesIndex > 1 {
+		// aborted: drop the pending deferred calls without running them
+		for i := 1; i < v.framesIndex; i++ {
+			v.frames[i].defers, v.frames[i].retVal = nil, nil
+		}
+	}
 	return nil
 }
 
 func (v *VM) run() {
-	for atomic.LoadInt64(&v.aborting) == 0 {
+	for !v.aborted() {
 		v.ip++
 
+		if v.countInsts {
+			v.insts--
+			if v.insts == 0 {
+				v.err = ErrInstructionLimit
+				return
+			}
+		}
+
 		switch v.curInsts[v.ip] {
 		case parser.OpConstant:
 			v.ip += 2
@@ -113,7 +227,23 @@ func (v *VM) run() {
 			right := v.stack[v.sp-1]
 			left := v.stack[v.sp-2]
 			tok := token.Token(v.curInsts[v.ip])
-			res, e := left.BinaryOp(tok, right)
+			if v.intOverflowCheck {
+				if x, ok := left.(*Int); ok {
+					if y, ok := right.(*Int); ok &&
+						intOverflows(tok, x.Value, y.Value) {
+						v.err = fmt.Errorf("%w: %d %s %d",
+							ErrIntOverflow, x.Value, tok.String(), y.Value)
+						return
+					}
+				}
+			}
+			var res Object
+			var e error
+			if v.decimalQuo && tok == token.Quo {
+				res, e = v.quo(left, right)
+			} else {
+				res, e = left.BinaryOp(tok, right)
+			}
 			if e != nil {
 				v.sp -= 2
 				if e == ErrInvalidOperator {
@@ -143,6 +273,29 @@ func (v *VM) run() {
 				v.stack[v.sp] = FalseValue
 			}
 			v.sp++
+		case parser.OpContains:
+			right := v.stack[v.sp-1]
+			left := v.stack[v.sp-2]
+			v.sp -= 2
+			found, e := false, ErrInvalidOperator
+			if c, ok := right.(Container); ok {
+				found, e = c.Contains(left)
+			}
+			if e != nil {
+				if e == ErrInvalidOperator {
+					v.err = fmt.Errorf("invalid operation: %s in %s",
+						left.TypeName(), right.TypeName())
+					return
+				}
+				v.err = e
+				return
+			}
+			if found {
+				v.stack[v.sp] = TrueValue
+			} else {
+				v.stack[v.sp] = FalseValue
+			}
+			v.sp++
 		case parser.OpNotEqual:
 			right := v.stack[v.sp-1]
 			left := v.stack[v.sp-2]
@@ -184,6 +337,15 @@ func (v *VM) run() {
 				}
 				v.stack[v.sp] = res
 				v.sp++
+			case *BigInt:
+				var res Object = &BigInt{Value: new(big.Int).Not(x.Value)}
+				v.allocs--
+				if v.allocs == 0 {
+					v.err = ErrObjectAllocLimit
+					return
+				}
+				v.stack[v.sp] = res
+				v.sp++
 			default:
 				v.err = fmt.Errorf("invalid operation: ^%s",
 					operand.TypeName())
@@ -195,6 +357,10 @@ func (v *VM) run() {
 
 			switch x := operand.(type) {
 			case *Int:
+				if v.intOverflowCheck && x.Value == math.MinInt64 {
+					v.err = fmt.Errorf("%w: -%d", ErrIntOverflow, x.Value)
+					return
+				}
 				var res Object = &Int{Value: -x.Value}
 				v.allocs--
 				if v.allocs == 0 {
@@ -212,6 +378,27 @@ func (v *VM) run() {
 				}
 				v.stack[v.sp] = res
 				v.sp++
+			case *BigInt:
+				var res Object = &BigInt{Value: new(big.Int).Neg(x.Value)}
+				v.allocs--
+				if v.allocs == 0 {
+					v.err = ErrObjectAllocLimit
+					return
+				}
+				v.stack[v.sp] = res
+				v.sp++
+			case *Decimal:
+				var res Object = &Decimal{
+					Value: new(big.Int).Neg(x.Value),
+					Scale: x.Scale,
+				}
+				v.allocs--
+				if v.allocs == 0 {
+					v.err = ErrObjectAllocLimit
+					return
+				}
+				v.stack[v.sp] = res
+				v.sp++
 			default:
 				v.err = fmt.Errorf("invalid operation: -%s",
 					operand.TypeName())
@@ -240,6 +427,20 @@ func (v *VM) run() {
 				pos := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8 | int(v.curInsts[v.ip-2])<<16 | int(v.curInsts[v.ip-3])<<24
 				v.ip = pos - 1
 			}
+		case parser.OpOptionalJump:
+			v.ip += 4
+			if v.stack[v.sp-1] == UndefinedValue {
+				pos := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8 | int(v.curInsts[v.ip-2])<<16 | int(v.curInsts[v.ip-3])<<24
+				v.ip = pos - 1
+			}
+		case parser.OpCoalesceJump:
+			v.ip += 4
+			if v.stack[v.sp-1] == UndefinedValue {
+				v.sp--
+			} else {
+				pos := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8 | int(v.curInsts[v.ip-2])<<16 | int(v.curInsts[v.ip-3])<<24
+				v.ip = pos - 1
+			}
 		case parser.OpJump:
 			pos := int(v.curInsts[v.ip+4]) | int(v.curInsts[v.ip+3])<<8 | int(v.curInsts[v.ip+2])<<16 | int(v.curInsts[v.ip+1])<<24
 			v.ip = pos - 1
@@ -247,7 +448,14 @@ func (v *VM) run() {
 			v.ip += 2
 			v.sp--
 			globalIndex := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
-			v.globals[globalIndex] = v.stack[v.sp]
+			if live, ok := v.globals[globalIndex].(*liveGlobal); ok {
+				if e := live.set(v.stack[v.sp]); e != nil {
+					v.err = e
+					return
+				}
+			} else {
+				v.globals[globalIndex] = v.stack[v.sp]
+			}
 		case parser.OpSetSelGlobal:
 			v.ip += 3
 			globalIndex := int(v.curInsts[v.ip-1]) | int(v.curInsts[v.ip-2])<<8
@@ -260,15 +468,39 @@ func (v *VM) run() {
 			}
 			val := v.stack[v.sp-numSelectors-1]
 			v.sp -= numSelectors + 1
-			e := indexAssign(v.globals[globalIndex], val, selectors)
+			dst := v.globals[globalIndex]
+			live, isLive := dst.(*liveGlobal)
+			if isLive {
+				var e error
+				if dst, e = live.get(); e != nil {
+					v.err = e
+					return
+				}
+			}
+			e := indexAssign(dst, val, selectors)
 			if e != nil {
 				v.err = e
 				return
 			}
+			if isLive {
+				// the getter returns a converted copy of the value, so
+				// the modified copy is passed back to the setter
+				if e := live.set(dst); e != nil {
+					v.err = e
+					return
+				}
+			}
 		case parser.OpGetGlobal:
 			v.ip += 2
 			globalIndex := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
 			val := v.globals[globalIndex]
+			if live, ok := val.(*liveGlobal); ok {
+				var e error
+				if val, e = live.get(); e != nil {
+					v.err = e
+					return
+				}
+			}
 			v.stack[v.sp] = val
 			v.sp++
 		case parser.OpArray:
@@ -301,6 +533,67 @@ func (v *VM) run() {
 			}
 			v.sp -= numElements
 
+			var m Object = &Map{Value: kv}
+			v.allocs--
+			if v.allocs == 0 {
+				v.err = ErrObjectAllocLimit
+				return
+			}
+			v.stack[v.sp] = m
+			v.sp++
+		case parser.OpArrayConcat:
+			v.ip += 2
+			numParts := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
+
+			var elements []Object
+			for i := v.sp - numParts; i < v.sp; i++ {
+				switch part := v.stack[i].(type) {
+				case *Array:
+					elements = append(elements, part.Value...)
+				case *ImmutableArray:
+					elements = append(elements, part.Value...)
+				case *Set, *ImmutableSet:
+					it := part.Iterate()
+					for it.Next() {
+						elements = append(elements, it.Value())
+					}
+				default:
+					v.err = fmt.Errorf("not an array: %s", part.TypeName())
+					return
+				}
+			}
+			v.sp -= numParts
+
+			var arr Object = &Array{Value: elements}
+			v.allocs--
+			if v.allocs == 0 {
+				v.err = ErrObjectAllocLimit
+				return
+			}
+			v.stack[v.sp] = arr
+			v.sp++
+		case parser.OpMapMerge:
+			v.ip += 2
+			numParts := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8
+
+			kv := make(map[string]Object)
+			for i := v.sp - numParts; i < v.sp; i++ {
+				var part map[string]Object
+				switch m := v.stack[i].(type) {
+				case *Map:
+					part = m.Value
+				case *ImmutableMap:
+					part = m.Value
+				default:
+					v.err = fmt.Errorf("not a map: %s", m.TypeName())
+					return
+				}
+				for key, value := range part {
+					kv[key] = value
+				}
+			}
+			v.sp -= numParts
+
 			var m Object = &Map{Value: kv}
 			v.allocs--
 			if v.allocs == 0 {
@@ -343,13 +636,33 @@ func (v *VM) run() {
 					return
 				}
 				v.stack[v.sp-1] = immutableMap
+			case *Set:
+				var immutableSet Object = &ImmutableSet{
+					Value: value.Value,
+				}
+				v.allocs--
+				if v.allocs == 0 {
+					v.err = ErrObjectAllocLimit
+					return
+				}
+				v.stack[v.sp-1] = immutableSet
+			}
+		case parser.OpIndex, parser.OpOptionalIndex:
+			optional := v.curInsts[v.ip] == parser.OpOptionalIndex
+			if optional {
+				v.ip += 4
 			}
-		case parser.OpIndex:
 			index := v.stack[v.sp-1]
 			left := v.stack[v.sp-2]
 			v.sp -= 2
 
 			val, err := left.IndexGet(index)
+			if err == ErrNotIndexable && optional {
+				// the optional chain ends with undefined
+				pos := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8 | int(v.curInsts[v.ip-2])<<16 | int(v.curInsts[v.ip-3])<<24
+				v.ip = pos - 1
+				val, err = UndefinedValue, nil
+			}
 			if err != nil {
 				if err == ErrNotIndexable {
 					v.err = fmt.Errorf("not indexable: %s", index.TypeName())
@@ -368,174 +681,42 @@ func (v *VM) run() {
 			}
 			v.stack[v.sp] = val
 			v.sp++
-		case parser.OpSliceIndex:
+		case parser.OpSliceIndex, parser.OpSliceStep:
+			var step Object
+			if v.curInsts[v.ip] == parser.OpSliceStep {
+				step = v.stack[v.sp-1]
+				v.sp--
+			}
 			high := v.stack[v.sp-1]
 			low := v.stack[v.sp-2]
 			left := v.stack[v.sp-3]
 			v.sp -= 3
 
-			var lowIdx int64
-			if low != UndefinedValue {
-				if lowInt, ok := low.(*Int); ok {
-					lowIdx = lowInt.Value
-				} else {
-					v.err = fmt.Errorf("invalid slice index type: %s",
-						low.TypeName())
-					return
-				}
+			val, err := sliceObject(left, low, high, step)
+			if err != nil {
+				v.err = err
+				return
 			}
+			v.allocs--
+			if v.allocs == 0 {
+				v.err = ErrObjectAllocLimit
+				return
+			}
+			v.stack[v.sp] = val
+			v.sp++
+		case parser.OpSetSlice:
+			step := v.stack[v.sp-1]
+			high := v.stack[v.sp-2]
+			low := v.stack[v.sp-3]
+			left := v.stack[v.sp-4]
+			val := v.stack[v.sp-5]
+			v.sp -= 5
 
-			switch left := left.(type) {
-			case *Array:
-				numElements := int64(len(left.Value))
-				var highIdx int64
-				if high == UndefinedValue {
-					highIdx = numElements
-				} else if highInt, ok := high.(*Int); ok {
-					highIdx = highInt.Value
-				} else {
-					v.err = fmt.Errorf("invalid slice index type: %s",
-						high.TypeName())
-					return
-				}
-				if lowIdx > highIdx {
-					v.err = fmt.Errorf("invalid slice index: %d > %d",
-						lowIdx, highIdx)
-					return
-				}
-				if lowIdx < 0 {
-					lowIdx = 0
-				} else if lowIdx > numElements {
-					lowIdx = numElements
-				}
-				if highIdx < 0 {
-					highIdx = 0
-				} else if highIdx > numElements {
-					highIdx = numElements
-				}
-				var val Object = &Array{
-					Value: left.Value[lowIdx:highIdx],
-				}
-				v.allocs--
-				if v.allocs == 0 {
-					v.err = ErrObjectAllocLimit
-					return
-				}
-				v.stack[v.sp] = val
-				v.sp++
-			case *ImmutableArray:
-				numElements := int64(len(left.Value))
-				var highIdx int64
-				if high == UndefinedValue {
-					highIdx = numElements
-				} else if highInt, ok := high.(*Int); ok {
-					highIdx = highInt.Value
-				} else {
-					v.err = fmt.Errorf("invalid slice index type: %s",
-						high.TypeName())
-					return
-				}
-				if lowIdx > highIdx {
-					v.err = fmt.Errorf("invalid slice index: %d > %d",
-						lowIdx, highIdx)
-					return
-				}
-				if lowIdx < 0 {
-					lowIdx = 0
-				} else if lowIdx > numElements {
-					lowIdx = numElements
-				}
-				if highIdx < 0 {
-					highIdx = 0
-				} else if highIdx > numElements {
-					highIdx = numElements
-				}
-				var val Object = &Array{
-					Value: left.Value[lowIdx:highIdx],
-				}
-				v.allocs--
-				if v.allocs == 0 {
-					v.err = ErrObjectAllocLimit
-					return
-				}
-				v.stack[v.sp] = val
-				v.sp++
-			case *String:
-				numElements := int64(len(left.Value))
-				var highIdx int64
-				if high == UndefinedValue {
-					highIdx = numElements
-				} else if highInt, ok := high.(*Int); ok {
-					highIdx = highInt.Value
-				} else {
-					v.err = fmt.Errorf("invalid slice index type: %s",
-						high.TypeName())
-					return
-				}
-				if lowIdx > highIdx {
-					v.err = fmt.Errorf("invalid slice index: %d > %d",
-						lowIdx, highIdx)
-					return
-				}
-				if lowIdx < 0 {
-					lowIdx = 0
-				} else if lowIdx > numElements {
-					lowIdx = numElements
-				}
-				if highIdx < 0 {
-					highIdx = 0
-				} else if highIdx > numElements {
-					highIdx = numElements
-				}
-				var val Object = &String{
-					Value: left.Value[lowIdx:highIdx],
-				}
-				v.allocs--
-				if v.allocs == 0 {
-					v.err = ErrObjectAllocLimit
-					return
-				}
-				v.stack[v.sp] = val
-				v.sp++
-			case *Bytes:
-				numElements := int64(len(left.Value))
-				var highIdx int64
-				if high == UndefinedValue {
-					highIdx = numElements
-				} else if highInt, ok := high.(*Int); ok {
-					highIdx = highInt.Value
-				} else {
-					v.err = fmt.Errorf("invalid slice index type: %s",
-						high.TypeName())
-					return
-				}
-				if lowIdx > highIdx {
-					v.err = fmt.Errorf("invalid slice index: %d > %d",
-						lowIdx, highIdx)
-					return
-				}
-				if lowIdx < 0 {
-					lowIdx = 0
-				} else if lowIdx > numElements {
-					lowIdx = numElements
-				}
-				if highIdx < 0 {
-					highIdx = 0
-				} else if highIdx > numElements {
-					highIdx = numElements
-				}
-				var val Object = &Bytes{
-					Value: left.Value[lowIdx:highIdx],
-				}
-				v.allocs--
-				if v.allocs == 0 {
-					v.err = ErrObjectAllocLimit
-					return
-				}
-				v.stack[v.sp] = val
-				v.sp++
-			default:
-				v.err = fmt.Errorf("not indexable: %s", left.TypeName())
+			if step == UndefinedValue {
+				step = nil
+			}
+			if err := sliceAssign(left, low, high, step, val); err != nil {
+				v.err = err
 				return
 			}
 		case parser.OpCall:
@@ -587,21 +768,16 @@ func (v *VM) run() {
 						v.sp = spStart + 1
 					}
 				}
-				if numArgs != callee.NumParameters {
-					if callee.VarArgs {
-						v.err = fmt.Errorf(
-							"wrong number of arguments: want>=%d, got=%d",
-							callee.NumParameters-1, numArgs)
-					} else {
-						v.err = fmt.Errorf(
-							"wrong number of arguments: want=%d, got=%d",
-							callee.NumParameters, numArgs)
-					}
+				if numArgs != callee.NumParameters &&
+					!v.fillDefaults(callee, numArgs) {
+					v.err = callee.wrongNumArgs(numArgs)
 					return
 				}
+				numArgs = callee.NumParameters
 
 				// test if it's tail-call
-				if callee == v.curFrame.fn { // recursion
+				if callee == v.curFrame.fn &&
+					len(v.curFrame.defers) == 0 { // recursion
 					nextOp := v.curInsts[v.ip+1]
 					if nextOp == parser.OpReturn ||
 						(nextOp == parser.OpPop &&
@@ -633,25 +809,12 @@ func (v *VM) run() {
 			} else {
 				var args []Object
 				args = append(args, v.stack[v.sp-numArgs:v.sp]...)
-				ret, e := value.Call(args...)
+				ret, e := v.callObject(value, args)
 				v.sp -= numArgs + 1
 
 				// runtime error
 				if e != nil {
-					if e == ErrWrongNumArguments {
-						v.err = fmt.Errorf(
-							"wrong number of arguments in call to '%s'",
-							value.TypeName())
-						return
-					}
-					if e, ok := e.(ErrInvalidArgumentType); ok {
-						v.err = fmt.Errorf(
-							"invalid type for argument '%s' in call to '%s': "+
-								"expected %s, found %s",
-							e.Name, value.TypeName(), e.Expected, e.Found)
-						return
-					}
-					v.err = e
+					v.err = callError(value, e)
 					return
 				}
 
@@ -667,14 +830,71 @@ func (v *VM) run() {
 				v.stack[v.sp] = ret
 				v.sp++
 			}
+		case parser.OpCallKw:
+			numArgs := int(v.curInsts[v.ip+1])
+			numKwargs := int(v.curInsts[v.ip+2])
+			v.ip += 2
+
+			value := v.stack[v.sp-1-numArgs-2*numKwargs]
+			callee, ok := value.(*CompiledFunction)
+			if !ok {
+				if !value.CanCall() {
+					v.err = fmt.Errorf("not callable: %s", value.TypeName())
+				} else {
+					v.err = fmt.Errorf(
+						"keyword arguments not supported in call to '%s'",
+						value.TypeName())
+				}
+				return
+			}
+			if v.err = v.bindKeywordArgs(callee, numArgs, numKwargs); v.err != nil {
+				return
+			}
+			numArgs = callee.NumParameters
+
+			if v.framesIndex >= MaxFrames {
+				v.err = ErrStackOverflow
+				return
+			}
+
+			// update call frame
+			v.curFrame.ip = v.ip // store current ip before call
+			v.curFrame = &(v.frames[v.framesIndex])
+			v.curFrame.fn = callee
+			v.curFrame.freeVars = callee.Free
+			v.curFrame.basePointer = v.sp - numArgs
+			v.curInsts = callee.Instructions
+			v.ip = -1
+			v.framesIndex++
+			v.sp = v.sp - numArgs + callee.NumLocals
+		case parser.OpDefaultJump:
+			localIndex := int(v.curInsts[v.ip+1])
+			v.ip += 5
+			if v.stack[v.curFrame.basePointer+localIndex] != nil {
+				pos := int(v.curInsts[v.ip]) | int(v.curInsts[v.ip-1])<<8 | int(v.curInsts[v.ip-2])<<16 | int(v.curInsts[v.ip-3])<<24
+				v.ip = pos - 1
+			}
 		case parser.OpReturn:
 			v.ip++
 			var retVal Object
-			if int(v.curInsts[v.ip]) == 1 {
+			if v.curFrame.retVal != nil {
+				// back from a deferred call: drop its return value
+				retVal = v.curFrame.retVal
+				v.curFrame.retVal = nil
+				v.sp--
+			} else if int(v.curInsts[v.ip]) == 1 {
 				retVal = v.stack[v.sp-1]
 			} else {
 				retVal = UndefinedValue
 			}
+			if len(v.curFrame.defers) > 0 {
+				if v.runDefers(retVal) {
+					continue
+				}
+				if v.err != nil {
+					return
+				}
+			}
 			//v.sp--
 			v.framesIndex--
 			v.curFrame = &v.frames[v.framesIndex-1]
@@ -685,6 +905,88 @@ func (v *VM) run() {
 			// skip stack overflow check because (newSP) <= (oldSP)
 			v.stack[v.sp-1] = retVal
 			//v.sp++
+		case parser.OpDefer:
+			numArgs := int(v.curInsts[v.ip+1])
+			spread := int(v.curInsts[v.ip+2])
+			v.ip += 2
+
+			value := v.stack[v.sp-1-numArgs]
+			if !value.CanCall() {
+				v.err = fmt.Errorf("not callable: %s", value.TypeName())
+				return
+			}
+			args := make([]Object, numArgs)
+			copy(args, v.stack[v.sp-numArgs:v.sp])
+			v.sp -= numArgs + 1
+
+			if spread == 1 {
+				last := args[numArgs-1]
+				args = args[:numArgs-1]
+				switch arr := last.(type) {
+				case *Array:
+					args = append(args, arr.Value...)
+				case *ImmutableArray:
+					args = append(args, arr.Value...)
+				default:
+					v.err = fmt.Errorf("not an array: %s", arr.TypeName())
+					return
+				}
+			}
+			if callee, ok := value.(*CompiledFunction); ok {
+				if args, v.err = callee.deferredArgs(args); v.err != nil {
+					return
+				}
+			}
+			v.curFrame.defers = append(v.curFrame.defers,
+				&deferredCall{fn: value, args: args})
+		case parser.OpGenerator:
+			f := v.curFrame
+			var retVal Object = UndefinedValue
+			if _, ok := v.stack[f.basePointer-1].(*Generator); !ok {
+				v.allocs--
+				if v.allocs == 0 {
+					v.err = ErrObjectAllocLimit
+					return
+				}
+				retVal = &Generator{
+					fn:       f.fn,
+					freeVars: f.freeVars,
+					locals: append([]Object(nil),
+						v.stack[f.basePointer:f.basePointer+f.fn.NumLocals]...),
+					ip: v.ip,
+					vm: v,
+				}
+			}
+			// otherwise a resumed generator made a tail call to itself,
+			// which ends it like the return that follows the call.
+			v.framesIndex--
+			v.curFrame = &v.frames[v.framesIndex-1]
+			v.curInsts = v.curFrame.fn.Instructions
+			v.ip = v.curFrame.ip
+			v.sp = f.basePointer
+			v.stack[v.sp-1] = retVal
+		case parser.OpYield:
+			f := v.curFrame
+			gen, ok := v.stack[f.basePointer-1].(*Generator)
+			if !ok {
+				v.err = errors.New("yield outside generator")
+				return
+			}
+			gen.value = v.stack[v.sp-1]
+			gen.n++
+			gen.done = false
+			gen.ip = v.ip
+			gen.locals = append(gen.locals[:0],
+				v.stack[f.basePointer:f.basePointer+f.fn.NumLocals]...)
+			gen.defers, f.defers = f.defers, nil
+
+			// suspend the frame and hand the value to the caller
+			v.framesIndex--
+			v.curFrame = &v.frames[v.framesIndex-1]
+			v.curInsts = v.curFrame.fn.Instructions
+			v.ip = v.curFrame.ip
+			v.sp = f.basePointer
+			v.stack[v.sp-1] = TrueValue
 		case parser.OpDefineLocal:
 			v.ip++
 			localIndex := int(v.curInsts[v.ip])
@@ -769,6 +1071,8 @@ func (v *VM) run() {
 				Instructions:  fn.Instructions,
 				NumLocals:     fn.NumLocals,
 				NumParameters: fn.NumParameters,
+				NumDefaults:   fn.NumDefaults,
+				ParamNames:    fn.ParamNames,
 				VarArgs:       fn.VarArgs,
 				SourceMap:     fn.SourceMap,
 				Free:          free,
@@ -848,6 +1152,14 @@ func (v *VM) run() {
 		case parser.OpIteratorNext:
 			iterator := v.stack[v.sp-1]
 			v.sp--
+			if gen, ok := iterator.(*Generator); ok && !gen.done {
+				// the result is pushed when the generator frame yields
+				// or returns
+				if !v.resumeGenerator(gen) {
+					return
+				}
+				continue
+			}
 			hasMore := iterator.(Iterator).Next()
 			if hasMore {
 				v.stack[v.sp] = TrueValue
@@ -909,3 +1221,285 @@ func indexAssign(dst, src Object, selectors []Object) error {
 	}
 	return nil
 }
+
+// fillDefaults pushes a placeholder for each parameter with a default value
+// that was not passed by the caller, so that the function prologue evaluates
+// its default. It returns false if numArgs is not accepted by fn.
+func (v *VM) fillDefaults(fn *CompiledFunction, numArgs int) bool {
+	if fn.NumDefaults == 0 || numArgs < fn.minArgs() {
+		return false
+	}
+	numParams := fn.NumParameters
+	if fn.VarArgs {
+		numParams--
+	}
+	if numArgs > numParams {
+		return false
+	}
+	for ; numArgs < numParams; numArgs++ {
+		v.stack[v.sp] = nil
+		v.sp++
+	}
+	if fn.VarArgs {
+		v.stack[v.sp] = &Array{Value: []Object{}}
+		v.sp++
+	}
+	return true
+}
+
+// bindKeywordArgs rewrites numArgs positional arguments followed by
+// numKwargs name/value pairs on the stack into the positional layout
+// expected by fn.
+func (v *VM) bindKeywordArgs(
+	fn *CompiledFunction,
+	numArgs, numKwargs int,
+) error {
+	numParams := fn.NumParameters
+	if fn.VarArgs {
+		numParams--
+	}
+	base := v.sp - numArgs - 2*numKwargs
+	kwBase := base + numArgs
+	args := make([]Object, fn.NumParameters)
+
+	var rest []Object
+	if numArgs > numParams {
+		if !fn.VarArgs {
+			return fmt.Errorf(
+				"wrong number of arguments: want<=%d, got=%d",
+				numParams, numArgs)
+		}
+		rest = append(rest, v.stack[base+numParams:base+numArgs]...)
+		numArgs = numParams
+	}
+	copy(args, v.stack[base:base+numArgs])
+
+	for i := 0; i < numKwargs; i++ {
+		name := v.stack[kwBase+2*i].(*String).Value
+		idx := -1
+		for j := 0; j < len(fn.ParamNames) && j < numParams; j++ {
+			if fn.ParamNames[j] == name {
+				idx = j
+				break
+			}
+		}
+		if idx < 0 {
+			return fmt.Errorf("unexpected keyword argument '%s'", name)
+		}
+		if args[idx] != nil {
+			return fmt.Errorf("multiple values for argument '%s'", name)
+		}
+		args[idx] = v.stack[kwBase+2*i+1]
+	}
+	for i := 0; i < numParams-fn.NumDefaults; i++ {
+		if args[i] == nil {
+			return fmt.Errorf("missing argument '%s'", fn.ParamNames[i])
+		}
+	}
+	if fn.VarArgs {
+		if rest == nil {
+			rest = []Object{}
+		}
+		args[numParams] = &Array{Value: rest}
+	}
+
+	v.sp = base + copy(v.stack[base:], args)
+	return nil
+}
+
+// runDefers runs the deferred calls of the current frame, which is returning
+// retVal, in LIFO order. If a deferred compiled function is called, its frame
+// is pushed and runDefers returns true; the OpReturn of the current frame is
+// executed again once that call returns.
+func (v *VM) runDefers(retVal Object) bool {
+	for n := len(v.curFrame.defers); n > 0; n-- {
+		d := v.curFrame.defers[n-1]
+		v.curFrame.defers = v.curFrame.defers[:n-1]
+
+		if fn, ok := d.fn.(*CompiledFunction); ok {
+			v.curFrame.retVal = retVal
+			v.curFrame.ip = v.ip - 2 // re-execute OpReturn
+			return v.pushDeferredFrame(fn, d.args)
+		}
+		if _, e := v.callObject(d.fn, d.args); e != nil {
+			v.err = callError(d.fn, e)
+			return false
+		}
+	}
+	return false
+}
+
+// pushDeferredFrame pushes the call frame of a deferred call to fn. The
+// arguments were already checked when the call was deferred.
+func (v *VM) pushDeferredFrame(fn *CompiledFunction, args []Object) bool {
+	if v.framesIndex >= MaxFrames {
+		v.err = ErrStackOverflow
+		return false
+	}
+	v.stack[v.sp] = fn
+	v.sp++
+	for _, arg := range args {
+		v.stack[v.sp] = arg
+		v.sp++
+	}
+	v.curFrame = &(v.frames[v.framesIndex])
+	v.curFrame.fn = fn
+	v.curFrame.freeVars = fn.Free
+	v.curFrame.basePointer = v.sp - len(args)
+	v.curInsts = fn.Instructions
+	v.ip = -1
+	v.framesIndex++
+	v.sp = v.sp - len(args) + fn.NumLocals
+	return true
+}
+
+// resumeGenerator pushes the suspended frame of gen. The generator is done
+// unless the frame yields again.
+func (v *VM) resumeGenerator(gen *Generator) bool {
+	if v.framesIndex >= MaxFrames {
+		v.err = ErrStackOverflow
+		return false
+	}
+	gen.done = true
+	v.stack[v.sp] = gen
+	v.sp++
+	copy(v.stack[v.sp:], gen.locals)
+
+	v.curFrame.ip = v.ip // store current ip before resuming
+	v.curFrame = &(v.frames[v.framesIndex])
+	v.curFrame.fn = gen.fn
+	v.curFrame.freeVars = gen.freeVars
+	v.curFrame.basePointer = v.sp
+	v.curFrame.defers, gen.defers = gen.defers, nil
+	v.curInsts = gen.fn.Instructions
+	v.ip = gen.ip
+	v.framesIndex++
+	v.sp += len(gen.locals)
+	return true
+}
+
+// unwindDefers runs the pending deferred calls of the frames from the
+// innermost one down to the frame at index bottom after a runtime error.
+// Errors raised by the deferred calls themselves are discarded.
+func (v *VM) unwindDefers(bottom int) {
+	for i := v.framesIndex - 1; i >= bottom; i-- {
+		f := &v.frames[i]
+		defers := f.defers
+		sp := f.basePointer + f.fn.NumLocals
+		f.defers, f.retVal = nil, nil
+
+		for j := len(defers) - 1; j >= 0; j-- {
+			d := defers[j]
+			fn, ok := d.fn.(*CompiledFunction)
+			if !ok {
+				_, _ = v.callObject(d.fn, d.args)
+				continue
+			}
+
+			// call fn from a frame that suspends the VM once it returns
+			v.frames[i] = frame{fn: suspendFn, ip: -1}
+			v.curFrame = &v.frames[i]
+			v.framesIndex = i + 1
+			v.sp = sp
+			v.err = nil
+			if v.pushDeferredFrame(fn, d.args) {
+				v.run()
+			}
+			if v.err != nil {
+				v.unwindDefers(i + 1)
+			}
+		}
+	}
+	v.framesIndex = bottom
+}
+
+// intOverflows reports whether the int operation x op y overflows int64.
+func intOverflows(op token.Token, x, y int64) bool {
+	switch op {
+	case token.Add:
+		r := x + y
+		return (x >= 0) == (y >= 0) && (r >= 0) != (x >= 0)
+	case token.Sub:
+		r := x - y
+		return (x >= 0) != (y >= 0) && (r >= 0) != (x >= 0)
+	case token.Mul:
+		if x == 0 || y == 0 {
+			return false
+		}
+		if (x == -1 && y == math.MinInt64) || (y == -1 && x == math.MinInt64) {
+			return true
+		}
+		return x*y/y != x
+	case token.Quo:
+		return x == math.MinInt64 && y == -1
+	case token.Shl:
+		if x == 0 || y < 0 {
+			return false
+		}
+		return y >= 64 || (x<<uint64(y))>>uint64(y) != x
+	case token.Pow:
+		if y < 0 {
+			return false // the result is a float
+		}
+		_, overflow := intPow(x, y)
+		return overflow
+	}
+	return false
+}
+
+// intPow returns x**y for y >= 0, wrapping around on overflow like the other
+// int operators, and reports whether the result overflowed.
+func intPow(x, y int64) (r int64, overflow bool) {
+	r = 1
+	for y > 0 {
+		if y&1 == 1 {
+			overflow = overflow || intOverflows(token.Mul, r, x)
+			r *= x
+		}
+		y >>= 1
+		if y > 0 {
+			// a squared base is always used by a later step, so its
+			// overflow is the result's overflow
+			overflow = overflow || intOverflows(token.Mul, x, x)
+			x *= x
+		}
+	}
+	return r, overflow
+}
+
+// quo returns left / right. Division of decimals keeps the precision set
+// with SetDecimalPrecision.
+func (v *VM) quo(left, right Object) (Object, error) {
+	x, ok := left.(*Decimal)
+	if _, isDecimal := right.(*Decimal); !ok && isDecimal {
+		x, ok = decimalOperand(left)
+	}
+	if !ok {
+		return left.BinaryOp(token.Quo, right)
+	}
+	return x.QuoRound(right, v.decimalPrecision, v.decimalRounding)
+}
+
+// callObject calls a non-compiled callable value. A UserFunction receives
+// the context the VM runs with.
+func (v *VM) callObject(fn Object, args []Object) (Object, error) {
+	if fn, ok := fn.(*UserFunction); ok {
+		return fn.CallContext(v.ctx, args...)
+	}
+	return fn.Call(args...)
+}
+
+// callError converts the error returned by a call to a non-compiled
+// callable value into a runtime error.
+func callError(value Object, e error) error {
+	if e == ErrWrongNumArguments {
+		return fmt.Errorf("wrong number of arguments in call to '%s'",
+			value.TypeName())
+	}
+	if e, ok := e.(ErrInvalidArgumentType); ok {
+		return fmt.Errorf("invalid type for argument '%s' in call to '%s': "+
+			"expected %s, found %s",
+			e.Name, value.TypeName(), e.Expected, e.Found)
+	}
+	return e
+}
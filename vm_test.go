@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"math/rand"
 	"reflect"
 	_runtime "runtime"
@@ -132,9 +133,13 @@ func TestArray(t *testing.T) {
 			nil, arr[idx])
 	}
 
-	expectRun(t, fmt.Sprintf("%s[%d]", arrStr, -1),
+	expectRun(t, fmt.Sprintf("out = %s[%d]", arrStr, -1),
+		nil, arr[arrLen-1])
+	expectRun(t, fmt.Sprintf("out = %s[%d]", arrStr, -arrLen),
+		nil, arr[0])
+	expectRun(t, fmt.Sprintf("out = %s[%d]", arrStr, -arrLen-1),
 		nil, tengo.UndefinedValue)
-	expectRun(t, fmt.Sprintf("%s[%d]", arrStr, arrLen),
+	expectRun(t, fmt.Sprintf("out = %s[%d]", arrStr, arrLen),
 		nil, tengo.UndefinedValue)
 
 	// slice operator
@@ -158,20 +163,26 @@ func TestArray(t *testing.T) {
 	expectRun(t, fmt.Sprintf("out = %s[:]", arrStr),
 		nil, arr)
 	expectRun(t, fmt.Sprintf("out = %s[%d:]", arrStr, -1),
+		nil, arr[arrLen-1:])
+	expectRun(t, fmt.Sprintf("out = %s[%d:]", arrStr, -arrLen-1),
 		nil, arr)
+	expectRun(t, fmt.Sprintf("out = %s[:%d]", arrStr, -1),
+		nil, arr[:arrLen-1])
+	expectRun(t, fmt.Sprintf("out = %s[%d:%d]", arrStr, -3, -1),
+		nil, arr[arrLen-3:arrLen-1])
 	expectRun(t, fmt.Sprintf("out = %s[:%d]", arrStr, arrLen+1),
 		nil, arr)
 	expectRun(t, fmt.Sprintf("out = %s[%d:%d]", arrStr, 2, 2),
 		nil, ARR{})
 
-	expectError(t, fmt.Sprintf("%s[:%d]", arrStr, -1),
-		nil, "invalid slice index")
 	expectError(t, fmt.Sprintf("%s[%d:]", arrStr, arrLen+1),
 		nil, "invalid slice index")
-	expectError(t, fmt.Sprintf("%s[%d:%d]", arrStr, 0, -1),
+	expectError(t, fmt.Sprintf("%s[%d:%d]", arrStr, 0, -arrLen-1),
 		nil, "invalid slice index")
 	expectError(t, fmt.Sprintf("%s[%d:%d]", arrStr, 2, 1),
 		nil, "invalid slice index")
+	expectError(t, fmt.Sprintf("%s[%d:%d]", arrStr, -1, -2),
+		nil, "invalid slice index")
 }
 
 func TestAssignment(t *testing.T) {
@@ -1223,6 +1234,98 @@ func TestForIn(t *testing.T) {
 		nil, "abde")
 }
 
+func TestPow(t *testing.T) {
+	expectRun(t, `out = 2 ** 10`, nil, 1024)
+	expectRun(t, `out = 2 ** 3 ** 2`, nil, 512)
+	expectRun(t, `out = 3 * 2 ** 2`, nil, 12)
+	expectRun(t, `out = -2 ** 2`, nil, 4)
+	expectRun(t, `out = 2 ** -1`, nil, 0.5)
+	expectRun(t, `out = 2.0 ** 3`, nil, 8.0)
+	expectRun(t, `out = 4 ** 0.5`, nil, 2.0)
+	expectRun(t, `out = 3; out **= 2`, nil, 9)
+	expectRun(t, `out = string(bigint(3) ** 40)`, nil, "12157665459056928801")
+	expectRun(t, `out = string(decimal("1.5") ** 2)`, nil, "2.25")
+
+	expectError(t, `x := "a" ** 2`, nil, "invalid operation: string ** int")
+
+	// bigint and decimal powers are limited in size
+	expectError(t, `x := bigint(2) ** 100000000`, nil,
+		"exceeding bigint size limit")
+	expectError(t, `x := decimal("1.5") ** 100000000`, nil,
+		"exceeding decimal size limit")
+	expectError(t, `x := decimal("2") ** 1000000`, nil,
+		"exceeding decimal size limit")
+	expectRun(t, `out = [string(bigint(1) ** 100000000), string(decimal(-1) ** 100000001)]`,
+		nil, ARR{"1", "-1"})
+
+	// int powers never wrap around
+	expectRun(t, `out = 3 ** 39`, nil, 4052555153018976267)
+	expectRun(t, `out = (-2) ** 63`, nil, math.MinInt64)
+	expectError(t, `x := 10 ** 30`, nil, "integer overflow: 10 ** 30")
+	expectError(t, `x := 2 ** 63`, nil, "integer overflow: 2 ** 63")
+	expectError(t, `x := 3; x **= 40`, nil, "integer overflow: 3 ** 40")
+}
+
+func TestLabeledBranch(t *testing.T) {
+	expectRun(t, `
+	out = []
+	outer: for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if j == 1 { continue outer }
+			if i == 2 { break outer }
+			out = append(out, [i, j])
+		}
+	}`, nil, ARR{ARR{0, 0}, ARR{1, 0}})
+
+	expectRun(t, `
+	out = []
+	rows: for r in [[1, 2], [3, -1], [5]] {
+		for v in r {
+			if v < 0 { break rows }
+			out = append(out, v)
+		}
+	}`, nil, ARR{1, 2, 3})
+
+	expectRun(t, `
+	f := func() {
+		n := 0
+		a: for {
+			b: for {
+				n++
+				if n > 5 { break a }
+				continue b
+			}
+		}
+		return n
+	}
+	out = f()`, nil, 6)
+
+	// labels of the outer function are not visible in a function literal
+	expectRun(t, `
+	out = 0
+	a: for i in [1, 2, 3] {
+		f := func() { a: for { break a }; return i }
+		out += f()
+	}`, nil, 6)
+
+	// a labeled loop with yield makes a generator
+	expectRun(t, `
+	g := func() { outer: for i := 0; i < 3; i++ { yield i } }
+	out = []
+	for x in g() { out = append(out, x) }`, nil, ARR{0, 1, 2})
+	expectRun(t, `
+	g := func() { outer: for i := 0; i < 3; i++ { yield i } }; for x in g() {}
+	out = 1`, nil, 1)
+
+	expectError(t, `for { break nope }`, nil, "unknown label 'nope'")
+	expectError(t, `for { continue nope }`, nil, "unknown label 'nope'")
+	expectError(t, `a: for { func() { break a } }`, nil, "unknown label 'a'")
+	expectError(t, `for { func() { break } }`, nil,
+		"break not allowed outside loop")
+	expectError(t, `a: for { a: for {} }`, nil, "label 'a' already defined")
+	expectError(t, `a: x := 1`, nil, "label 'a' not followed by a loop")
+}
+
 func TestFor(t *testing.T) {
 	expectRun(t, `
 	out = 0
@@ -3171,17 +3274,19 @@ func TestString(t *testing.T) {
 	expectRun(t, fmt.Sprintf("out = %s[:]", strStr),
 		nil, str)
 	expectRun(t, fmt.Sprintf("out = %s[%d:]", strStr, -1),
+		nil, str[strLen-1:])
+	expectRun(t, fmt.Sprintf("out = %s[%d:]", strStr, -strLen-1),
 		nil, str)
+	expectRun(t, fmt.Sprintf("out = %s[:%d]", strStr, -1),
+		nil, str[:strLen-1])
 	expectRun(t, fmt.Sprintf("out = %s[:%d]", strStr, strLen+1),
 		nil, str)
 	expectRun(t, fmt.Sprintf("out = %s[%d:%d]", strStr, 2, 2),
 		nil, "")
 
-	expectError(t, fmt.Sprintf("%s[:%d]", strStr, -1),
-		nil, "invalid slice index")
 	expectError(t, fmt.Sprintf("%s[%d:]", strStr, strLen+1),
 		nil, "invalid slice index")
-	expectError(t, fmt.Sprintf("%s[%d:%d]", strStr, 0, -1),
+	expectError(t, fmt.Sprintf("%s[%d:%d]", strStr, 0, -strLen-1),
 		nil, "invalid slice index")
 	expectError(t, fmt.Sprintf("%s[%d:%d]", strStr, 2, 1),
 		nil, "invalid slice index")
@@ -3377,6 +3482,61 @@ func TestSliceIndex(t *testing.T) {
 	expectError(t, `a := 123[-1:2] ; a += 1`, nil, "Runtime Error: not indexable")
 }
 
+func TestSliceStep(t *testing.T) {
+	expectRun(t, `out = [1, 2, 3, 4, 5][::2]`, nil, ARR{1, 3, 5})
+	expectRun(t, `out = [1, 2, 3, 4, 5][1::2]`, nil, ARR{2, 4})
+	expectRun(t, `out = [1, 2, 3, 4, 5][::-1]`, nil, ARR{5, 4, 3, 2, 1})
+	expectRun(t, `out = [1, 2, 3, 4, 5][-2::-2]`, nil, ARR{4, 2})
+	expectRun(t, `out = [1, 2, 3, 4, 5][3:0:-1]`, nil, ARR{4, 3, 2})
+	expectRun(t, `out = [1, 2, 3, 4, 5][1:4:1]`, nil, ARR{2, 3, 4})
+	expectRun(t, `out = [1, 2, 3, 4, 5][0:10:100]`, nil, ARR{1})
+	expectRun(t, `out = [1, 2, 3, 4, 5][2:1:1]`, nil, ARR{})
+	expectRun(t, `out = [1, 2, 3, 4, 5][1:2:-1]`, nil, ARR{})
+	expectRun(t, `out = immutable([1, 2, 3])[::-1]`, nil, ARR{3, 2, 1})
+	expectRun(t, `out = "hello"[::-1]`, nil, "olleh")
+	expectRun(t, `out = "héllo"[::2]`, nil, "hlo")
+	// strings are sliced by characters, with or without a step
+	expectRun(t, `out = "héllo"[0:2]`, nil, "hé")
+	expectRun(t, `out = "héllo"[0:2:1]`, nil, "hé")
+	expectRun(t, `out = "héllo"[-3:]`, nil, "llo")
+	expectRun(t, `s := "héllo"; out = [s[1], s[1:2]]`, nil, ARR{'é', "é"})
+	expectRun(t, `out = bytes("abc")[::-1]`, nil, []byte("cba"))
+
+	expectError(t, `[1, 2, 3][::0]`, nil, "invalid slice step: 0")
+	expectError(t, `[1, 2, 3][::"a"]`, nil, "invalid slice index type")
+	expectError(t, `{}[::1]`, nil, "not indexable")
+}
+
+func TestSliceAssign(t *testing.T) {
+	expectRun(t, `a := [1, 2, 3]; a[1:2] = [4, 5, 6]; out = a`,
+		nil, ARR{1, 4, 5, 6, 3})
+	expectRun(t, `a := [1, 2, 3]; a[:2] = []; out = a`, nil, ARR{3})
+	expectRun(t, `a := [1, 2, 3]; a[-1:] = [4, 5]; out = a`,
+		nil, ARR{1, 2, 4, 5})
+	expectRun(t, `a := [1, 2, 3]; a[3:] = immutable([4]); out = a`,
+		nil, ARR{1, 2, 3, 4})
+	expectRun(t, `a := [1, 2, 3]; a[:] = a; out = a`, nil, ARR{1, 2, 3})
+	expectRun(t, `a := [1, 2, 3, 4]; a[::2] = [0, 0]; out = a`,
+		nil, ARR{0, 2, 0, 4})
+	expectRun(t, `a := [1, 2, 3]; a[::-1] = a; out = a`, nil, ARR{3, 2, 1})
+	expectRun(t, `a := [1, 2, 3]; a[1:2:1] = [4, 5]; out = a`,
+		nil, ARR{1, 4, 5, 3})
+	expectRun(t, `a := {b: [1, 2]}; a.b[1:] = [3]; out = a.b`, nil, ARR{1, 3})
+	expectRun(t, `a := [1, 2, 3]; f := func() { a[:1] = [] }; f(); out = a`,
+		nil, ARR{2, 3})
+
+	expectError(t, `a := [1, 2, 3]; a[::2] = [0]`,
+		nil, "slice assignment size mismatch: 1 != 2")
+	expectError(t, `a := [1, 2, 3]; a[:1] = 1`,
+		nil, "invalid slice value type: int")
+	expectError(t, `a := [1, 2, 3]; a[2:1] = []`,
+		nil, "invalid slice index: 2 > 1")
+	expectError(t, `a := immutable([1, 2, 3]); a[:1] = []`,
+		nil, "not index-assignable: immutable-array")
+	expectError(t, `a := "abc"; a[:1] = []`,
+		nil, "not index-assignable: string")
+}
+
 func expectRun(
 	t *testing.T,
 	input string,
@@ -3706,6 +3866,10 @@ func objectZeroCopy(o tengo.Object) tengo.Object {
 	switch o.(type) {
 	case *tengo.Int:
 		return &tengo.Int{}
+	case *tengo.BigInt:
+		return &tengo.BigInt{Value: new(big.Int)}
+	case *tengo.Decimal:
+		return &tengo.Decimal{Value: new(big.Int)}
 	case *tengo.Float:
 		return &tengo.Float{}
 	case *tengo.Bool:
@@ -3728,9 +3892,860 @@ func objectZeroCopy(o tengo.Object) tengo.Object {
 		return &tengo.ImmutableArray{}
 	case *tengo.ImmutableMap:
 		return &tengo.ImmutableMap{}
+	case *tengo.Set:
+		return &tengo.Set{}
+	case *tengo.ImmutableSet:
+		return &tengo.ImmutableSet{}
 	case nil:
 		panic("nil")
 	default:
 		panic(fmt.Errorf("unknown object type: %s", o.TypeName()))
 	}
 }
+
+func TestDefaultParams(t *testing.T) {
+	expectRun(t, `
+	f := func(a, b = 10) { return a + b }
+	out = [f(1), f(1, 2)]
+	`, nil, ARR{11, 3})
+
+	// defaults are evaluated at call time and can refer to earlier params
+	expectRun(t, `
+	n := 0
+	f := func(a, b = a * 2, c = n) { return [a, b, c] }
+	n = 5
+	out = f(1)
+	`, nil, ARR{1, 2, 5})
+
+	expectRun(t, `
+	f := func(a, b = []) { b = append(b, a); return b }
+	out = [f(1), f(2)]
+	`, nil, ARR{ARR{1}, ARR{2}})
+
+	// passing undefined explicitly does not trigger the default
+	expectRun(t, `
+	f := func(a = 1) { return a }
+	out = f(undefined)
+	`, nil, tengo.UndefinedValue)
+
+	expectRun(t, `
+	f := func(a, b = 2, ...c) { return [a, b, c] }
+	out = [f(1), f(1, 3), f(1, 3, 4, 5)]
+	`, nil, ARR{ARR{1, 2, ARR{}}, ARR{1, 3, ARR{}}, ARR{1, 3, ARR{4, 5}}})
+
+	expectRun(t, `
+	f := func(a, b = 2) { return a + b }
+	out = f([5]...)
+	`, nil, 7)
+
+	expectRun(t, `
+	f := func(x = 3) {
+		if x == 0 { return 0 }
+		return f(x - 1) + x
+	}
+	out = f()
+	`, nil, 6)
+
+	expectRun(t, `
+	f := func() {
+		y := 4
+		return func(x = y) { return x }
+	}
+	out = f()()
+	`, nil, 4)
+
+	expectRun(t, `out = func(a = 1) {}()`, nil, tengo.UndefinedValue)
+
+	expectError(t, `func(a, b = 1) {}()`, nil,
+		"Runtime Error: wrong number of arguments: want=1..2, got=0")
+	expectError(t, `func(a, b = 1) {}(1, 2, 3)`, nil,
+		"Runtime Error: wrong number of arguments: want=1..2, got=3")
+	expectError(t, `func(a, b = 1, ...c) {}()`, nil,
+		"Runtime Error: wrong number of arguments: want>=1, got=0")
+}
+
+func TestKeywordArgs(t *testing.T) {
+	expectRun(t, `
+	f := func(a, b) { return [a, b] }
+	out = f(b: 3, a: 1)
+	`, nil, ARR{1, 3})
+
+	expectRun(t, `
+	f := func(a, b = 2, c = 3) { return [a, b, c] }
+	out = [f(1, c: 4), f(c: 5, a: 0), f(1, 2, c: 6)]
+	`, nil, ARR{ARR{1, 2, 4}, ARR{0, 2, 5}, ARR{1, 2, 6}})
+
+	expectRun(t, `
+	f := func(a, b = 2, ...c) { return [a, b, c] }
+	out = [f(1, 2, 3, 4), f(a: 1), f(1, b: 5)]
+	`, nil, ARR{ARR{1, 2, ARR{3, 4}}, ARR{1, 2, ARR{}}, ARR{1, 5, ARR{}}})
+
+	expectRun(t, `
+	x := 10
+	f := func(a, b) { return a - b + x }
+	out = f(b: 1, a: 2)
+	`, nil, 11)
+
+	expectError(t, `func(a) {}(b: 1)`, nil,
+		"Runtime Error: unexpected keyword argument 'b'")
+	expectError(t, `func(a) {}(1, a: 2)`, nil,
+		"Runtime Error: multiple values for argument 'a'")
+	expectError(t, `func(a, b) {}(b: 2)`, nil,
+		"Runtime Error: missing argument 'a'")
+	expectError(t, `func(a) {}(1, 2, a: 3)`, nil,
+		"Runtime Error: wrong number of arguments: want<=1, got=2")
+	expectError(t, `len(x: 1)`, nil,
+		"Runtime Error: keyword arguments not supported in call to 'builtin-function:len'")
+	expectError(t, `func(a) {}(a: 1, a: 2)`, nil,
+		"Compile Error: duplicate keyword argument 'a'")
+}
+
+func TestInterpString(t *testing.T) {
+	expectRun(t, `
+	user := {name: "bob"}
+	items := [1, 2, 3]
+	out = f"Hello ${user.name}, you have ${len(items)} items"
+	`, nil, "Hello bob, you have 3 items")
+
+	expectRun(t, `out = f"plain"`, nil, "plain")
+	expectRun(t, `out = f"${1}${2.5}${true}${'c'}${[1, "a"]}"`,
+		nil, `12.5truec[1, "a"]`)
+	expectRun(t, `out = f"\${a} \t${ {a: 1}.a }"`, nil, "${a} \t1")
+	expectRun(t, `x := 2; out = f"x=${f"${x * 2}!"}"`, nil, "x=4!")
+	expectRun(t, `out = f"${bytes("ab")}"`, nil, "ab")
+	expectRun(t, `out = f"${undefined}"`, nil, "<undefined>")
+
+	expectError(t, `x := f"${a}"`, nil,
+		"Compile Error: unresolved reference 'a'\n\tat test:1:10")
+}
+
+func TestOptionalChain(t *testing.T) {
+	expectRun(t, `a := {b: {c: 1}}; out = a?.b?.c`, nil, 1)
+	expectRun(t, `a := undefined; out = a?.b.c.d`, nil, tengo.UndefinedValue)
+	expectRun(t, `a := {}; out = a.b?.c()`, nil, tengo.UndefinedValue)
+	expectRun(t, `a := undefined; out = a?.[0][1]`, nil, tengo.UndefinedValue)
+	expectRun(t, `a := [[1, 2]]; out = a?.[0]?.[1]`, nil, 2)
+	expectRun(t, `a := undefined; out = a?.[1:]`, nil, tengo.UndefinedValue)
+	expectRun(t, `a := [1, 2, 3]; out = a?.[1:]`, nil, ARR{2, 3})
+	// `?[` is a ternary operator followed by an array literal
+	expectRun(t, `c := true; out = c ?[1] : [2]`, nil, ARR{1})
+	expectRun(t, `c := false; out = c?[1]:[2]`, nil, ARR{2})
+	expectRun(t, `a := [1, 2]; out = a?[0] : [1]`, nil, ARR{0})
+	expectRun(t, `f := undefined; out = f?.(1)`, nil, tengo.UndefinedValue)
+	expectRun(t, `f := func(x) { return x * 2 }; out = f?.(2)`, nil, 4)
+	expectRun(t, `m := {f: func() { return 3 }}; out = m?.f?.()`, nil, 3)
+	expectRun(t, `
+	get := func(a) { return a?.b?.c }
+	out = [get({b: {c: 1}}), get({}), get(undefined)]
+	`, nil, ARR{1, tengo.UndefinedValue, tengo.UndefinedValue})
+
+	// arguments are not evaluated if the chain short-circuits
+	expectRun(t, `
+	n := 0
+	inc := func() { n++ }
+	f := undefined
+	f?.(inc())
+	out = n
+	`, nil, 0)
+
+	// nested chains inside arguments
+	expectRun(t, `
+	f := func(x) { return x ?? "none" }
+	a := {}
+	out = [f(a?.b?.c), a?.f?.(a?.b)]
+	`, nil, ARR{"none", tengo.UndefinedValue})
+
+	// values that are not indexable short-circuit the chain
+	expectRun(t, `a := 1; out = a?.[0]`, nil, tengo.UndefinedValue)
+	expectRun(t, `a := 1; out = a?.b()`, nil, tengo.UndefinedValue)
+	expectRun(t, `a := {b: 1}; out = a.b?.c.d()`, nil, tengo.UndefinedValue)
+	expectRun(t, `a := [1]; out = [a?.[0], 2?.[0]]`, nil,
+		ARR{1, tengo.UndefinedValue})
+	expectError(t, `a := [1]; x := a?.["x"]`, nil,
+		"Runtime Error: invalid index type: string")
+	expectError(t, `a := 1; x := a.b?.c`, nil,
+		"Runtime Error: not indexable")
+	expectError(t, `f := 1; x := f?.()`, nil,
+		"Runtime Error: not callable: int")
+	expectError(t, `a := 1; x := a?.[1:]`, nil,
+		"Runtime Error: not indexable: int")
+
+	expectError(t, `a := {}; a?.b = 1`, nil,
+		"Compile Error: optional chaining not allowed in assignment")
+	expectError(t, `a := {}; a?.b += 1`, nil,
+		"Compile Error: optional chaining not allowed in assignment")
+}
+
+func TestCoalesce(t *testing.T) {
+	expectRun(t, `out = undefined ?? 1`, nil, 1)
+	expectRun(t, `out = 0 ?? 1`, nil, 0)
+	expectRun(t, `out = false ?? 1`, nil, false)
+	expectRun(t, `out = "" ?? 1`, nil, "")
+	expectRun(t, `out = [] ?? 1`, nil, ARR{})
+	expectRun(t, `out = undefined ?? undefined ?? 3`, nil, 3)
+	expectRun(t, `a := {}; out = a.b ?? a.c ?? "x"`, nil, "x")
+	expectRun(t, `out = undefined ?? false || true`, nil, true)
+	expectRun(t, `
+	n := 0
+	f := func() { n++; return n }
+	x := 5 ?? f()
+	out = [x, n]
+	`, nil, ARR{5, 0})
+	expectRun(t, `
+	f := func(a) { return a ?? "default" }
+	out = [f(undefined), f(1)]
+	`, nil, ARR{"default", 1})
+}
+
+func TestConst(t *testing.T) {
+	expectRun(t, `const a = 1; const b = -2.5; const c = "x"; const d = a; out = [a, b, c, d]`,
+		nil, ARR{1, -2.5, "x", 1})
+	expectRun(t, `const t = true; const u = undefined; out = [t, !t, u]`,
+		nil, ARR{true, false, tengo.UndefinedValue})
+	expectRun(t, `const a = [1]; a[0] = 5; out = a`, nil, ARR{5})
+	expectRun(t, `const a = 1; if true { a := 2; a = 3; out = a }`, nil, 3)
+	expectRun(t, `f := func() { const k = 10; const m = {x: 1}; return func() { return [k, m] } }; out = f()()`,
+		nil, ARR{10, MAP{"x": 1}})
+	expectRun(t, `const fib = func(n) { return n < 2 ? n : fib(n-1) + fib(n-2) }; out = fib(10)`,
+		nil, 55)
+	expectRun(t, `f := func() { const s = "x"; for i := 0; i < 3; i++ { const s = i; out = s } }; f()`,
+		nil, 2)
+
+	expectError(t, `const a = 1; a = 2`, nil, "cannot assign to constant 'a'")
+	expectError(t, `const a = len([1]); a += 2`, nil, "cannot assign to constant 'a'")
+	expectError(t, `const a = 1; func() { a-- }`, nil,
+		"cannot assign to constant 'a'")
+	expectError(t, `const a = 1; const a = 2`, nil, "'a' redeclared in this block")
+}
+
+func TestArrowFunction(t *testing.T) {
+	expectRun(t, `f := x => x * 2; out = f(21)`, nil, 42)
+	expectRun(t, `f := (a, b) => a + b; out = f(1, 2)`, nil, 3)
+	expectRun(t, `f := () => 7; out = f()`, nil, 7)
+	expectRun(t, `f := (a, b = 10) => a + b; out = [f(1), f(1, 2)]`,
+		nil, ARR{11, 3})
+	expectRun(t, `f := (a, ...b) => [a, b]; out = f(1, 2, 3)`,
+		nil, ARR{1, ARR{2, 3}})
+	expectRun(t, `f := x => { y := x + 1; return y * y }; out = f(2)`,
+		nil, 9)
+	expectRun(t, `add := a => b => a + b; out = add(3)(4)`, nil, 7)
+	expectRun(t, `
+n := 0
+inc := () => { n++; return n }
+inc(); inc()
+out = [n, inc()]`, nil, ARR{2, 3})
+	expectRun(t, `
+map := func(a, fn) { r := []; for v in a { r = append(r, fn(v)) }; return r }
+k := 10
+out = map([1, 2, 3], x => x * k)`, nil, ARR{10, 20, 30})
+	expectRun(t, `out = type_name(x => x)`, nil, "compiled-function")
+	expectRun(t, `out = (x => x + 1)(1)`, nil, 2)
+
+	expectError(t, `f := (a, b) => a; f(1)`, nil,
+		"wrong number of arguments")
+}
+
+func TestDefer(t *testing.T) {
+	expectRun(t, `
+	log := []
+	f := func() {
+		defer func() { log = append(log, 1) }()
+		defer func() { log = append(log, 2) }()
+		log = append(log, 0)
+	}
+	f()
+	out = log
+	`, nil, ARR{0, 2, 1})
+
+	// arguments are evaluated when the call is deferred
+	expectRun(t, `
+	log := []
+	add := func(x) { log = append(log, x) }
+	f := func() {
+		x := 1
+		defer add(x)
+		x = 2
+		defer add(x)
+		x = 3
+	}
+	f()
+	out = log
+	`, nil, ARR{2, 1})
+
+	// return value is kept, deferred closures see the final locals
+	expectRun(t, `
+	log := []
+	f := func() {
+		x := 1
+		defer func() { log = append(log, x) }()
+		x = 10
+		return x * 2
+	}
+	out = [f(), log]
+	`, nil, ARR{20, ARR{10}})
+
+	// early returns and loops
+	expectRun(t, `
+	log := []
+	f := func(n) {
+		for i := 0; i < n; i++ {
+			defer func(i) { log = append(log, i) }(i)
+			if i == 2 {
+				return "early"
+			}
+		}
+		return "done"
+	}
+	out = [f(5), log, f(1)]
+	`, nil, ARR{"early", ARR{2, 1, 0}, "done"})
+
+	// builtin and variadic functions
+	expectRun(t, `
+	m := {a: 1, b: 2}
+	log := []
+	v := func(a, ...b) { log = append(log, [a, b]) }
+	f := func() {
+		defer delete(m, "a")
+		defer v(1)
+		defer v(1, [2, 3]...)
+		return len(m)
+	}
+	out = [f(), m, log]
+	`, nil, ARR{2, MAP{"b": 2}, ARR{ARR{1, ARR{2, 3}}, ARR{1, ARR{}}}})
+
+	// nested deferred calls and recursion
+	expectRun(t, `
+	log := []
+	f := func(n) {
+		defer func() {
+			defer func() { log = append(log, "inner" + string(n)) }()
+			log = append(log, n)
+		}()
+		if n > 0 {
+			return f(n - 1)
+		}
+	}
+	f(2)
+	out = log
+	`, nil, ARR{0, "inner0", 1, "inner1", 2, "inner2"})
+
+	// default parameter values
+	expectRun(t, `
+	log := []
+	g := func(a, b = 5) { log = append(log, a + b) }
+	f := func() { defer g(1) }
+	f()
+	out = log
+	`, nil, ARR{6})
+
+	expectError(t, `defer len("")`, nil,
+		"Compile Error: defer not allowed outside function")
+	expectError(t, `f := func() { defer len(x: 1) }`, nil,
+		"Compile Error: keyword arguments not allowed in defer")
+	expectError(t, `func() { defer 1() }()`, nil,
+		"Runtime Error: not callable: int")
+	expectError(t, `func() { defer func(a) {}() }()`, nil,
+		"Runtime Error: wrong number of arguments: want=1, got=0")
+	expectError(t, `func() { defer len(1, 2) }()`, nil,
+		"Runtime Error: wrong number of arguments in call to 'builtin-function:len'")
+}
+
+func TestDeferRuntimeError(t *testing.T) {
+	// deferred calls run when a runtime error unwinds the frames
+	g := &tengo.Map{Value: map[string]tengo.Object{}}
+	log := &tengo.UserFunction{Value: func(args ...tengo.Object) (tengo.Object, error) {
+		g.Value[args[0].String()] = args[1]
+		return nil, nil
+	}}
+	expectError(t, `
+	f := func() {
+		defer log("f", 1)
+		defer func() { log("f-closure", 2) }()
+		x := 1 + "a" - 1
+	}
+	g := func() {
+		defer log("g", 3)
+		f()
+	}
+	g()
+	`, Opts().Symbol("log", log).SkipSecondPass(),
+		"Runtime Error: invalid operation: int + string")
+	require.Equal(t, 3, len(g.Value))
+
+	// errors in deferred calls are reported on normal return
+	expectError(t, `
+	f := func() {
+		defer func() { x := 1 + "a" - 1 }()
+		return 1
+	}
+	f()
+	`, nil, "Runtime Error: invalid operation: int + string")
+}
+
+func TestGenerator(t *testing.T) {
+	expectRun(t, `
+	gen := func(n) { for i := 0; i < n; i++ { yield i * 10 } }
+	out = []
+	for k, v in gen(3) { out = append(out, [k, v]) }
+	`, nil, ARR{ARR{0, 0}, ARR{1, 10}, ARR{2, 20}})
+
+	// the body does not run until the first value is requested
+	expectRun(t, `
+	log := []
+	gen := func() { log = append(log, "start"); yield 1 }
+	g := gen()
+	log = append(log, "created")
+	for x in g { log = append(log, x) }
+	out = log
+	`, nil, ARR{"created", "start", 1})
+
+	// arguments, defaults, keyword arguments and closures
+	expectRun(t, `
+	step := 2
+	gen := func(from, to = 10, mul = 1) {
+		for i := from; i < to; i += step { yield i * mul }
+	}
+	out = []
+	for x in gen(1, to: 6, mul: 3) { out = append(out, x) }
+	`, nil, ARR{3, 9, 15})
+
+	// break keeps the generator suspended, it can be resumed later; keys
+	// keep counting the yielded values
+	expectRun(t, `
+	fib := func() {
+		a := 0
+		b := 1
+		for { yield a; t := a + b; a = b; b = t }
+	}
+	g := fib()
+	out = []
+	for x in g { if x > 3 { break }; out = append(out, x) }
+	for i, x in g { if i > 7 { break }; out = append(out, x) }
+	`, nil, ARR{0, 1, 1, 2, 3, 8, 13})
+
+	// exhausted generators produce no more values
+	expectRun(t, `
+	gen := func() { yield 1; yield 2 }
+	g := gen()
+	out = []
+	for x in g { out = append(out, x) }
+	for x in g { out = append(out, x) }
+	`, nil, ARR{1, 2})
+
+	// nested and recursive generators
+	expectRun(t, `
+	gen := func(n) { for i := 0; i < n; i++ { yield i } }
+	evens := func(it) { for x in it { if x % 2 == 0 { yield x } } }
+	tree := func(n) {
+		if n == 0 { return }
+		yield n
+		for x in tree(n - 1) { yield x }
+	}
+	out = []
+	for x in evens(gen(7)) { out = append(out, x) }
+	for x in tree(3) { out = append(out, x) }
+	`, nil, ARR{0, 2, 4, 6, 3, 2, 1})
+
+	// deferred calls run once the generator returns
+	expectRun(t, `
+	log := []
+	gen := func() {
+		defer func() { log = append(log, "done") }()
+		yield 1
+		yield 2
+	}
+	for x in gen() { log = append(log, x) }
+	out = log
+	`, nil, ARR{1, 2, "done"})
+
+	expectRun(t, `
+	gen := func() { yield 1 }
+	out = [type_name(gen()), is_iterable(gen()), gen() == gen()]
+	`, nil, ARR{"generator", true, false})
+
+	expectError(t, `
+	gen := func() { yield 1; yield 1 + "a" }
+	for x in gen() {}
+	`, nil, "Runtime Error: invalid operation: int + string")
+}
+
+func TestGeneratorYield(t *testing.T) {
+	// yield in any statement makes the function a generator
+	expectRun(t, `
+	gen := func(xs) {
+		a: for x in xs {
+			if x < 0 {
+				break a
+			} else if x % 2 == 0 {
+				for i := 0; i < 2; i++ { yield x }
+			} else {
+				if true { yield -x }
+			}
+		}
+	}
+	out = []
+	for v in gen([1, 2, 3, -1, 5]) { out = append(out, v) }
+	`, nil, ARR{-1, 2, 2, -3})
+
+	// yield in a function that is not a generator is a runtime error
+	fn := &tengo.CompiledFunction{
+		Instructions: concatInsts(
+			tengo.MakeInstruction(parser.OpNull),
+			tengo.MakeInstruction(parser.OpYield),
+			tengo.MakeInstruction(parser.OpReturn, 0),
+		),
+	}
+	bytecode := &tengo.Bytecode{
+		FileSet: parser.NewFileSet(),
+		MainFunction: &tengo.CompiledFunction{
+			Instructions: concatInsts(
+				tengo.MakeInstruction(parser.OpConstant, 0),
+				tengo.MakeInstruction(parser.OpCall, 0, 0),
+				tengo.MakeInstruction(parser.OpPop),
+				tengo.MakeInstruction(parser.OpSuspend),
+			),
+		},
+		Constants: []tengo.Object{fn},
+	}
+	v := tengo.NewVM(bytecode, nil, -1)
+	err := v.Run()
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "yield outside generator"),
+		"unexpected error: %v", err)
+}
+
+func TestGeneratorGo(t *testing.T) {
+	v := &tengo.Map{Value: map[string]tengo.Object{}}
+	expectRun(t, `
+	gen := func(n) { for i := 0; i < n; i++ { yield i * i } }
+	v.gen = gen(4)
+	out = 0
+	`, Opts().Symbol("v", v).SkipSecondPass(), 0)
+
+	g := v.Value["gen"].(*tengo.Generator)
+	var values []interface{}
+	for g.Next() {
+		require.Equal(t, int64(len(values)), g.Key().(*tengo.Int).Value)
+		values = append(values, tengo.ToInterface(g.Value()))
+	}
+	require.Equal(t, []interface{}{int64(0), int64(1), int64(4), int64(9)},
+		values)
+	require.False(t, g.Next())
+	require.NoError(t, g.Err())
+}
+
+func TestSpreadLiteral(t *testing.T) {
+	expectRun(t, `
+	a := [1, 2]
+	b := immutable([4, 5])
+	out = [...a, 3, ...b, ...[], 6]
+	`, nil, ARR{1, 2, 3, 4, 5, 6})
+
+	// spreading copies the elements into a new mutable array
+	expectRun(t, `
+	a := immutable([1, 2])
+	c := [...a]
+	c[0] = 9
+	out = [c, a]
+	`, nil, ARR{ARR{9, 2}, IARR{1, 2}})
+
+	// later keys override earlier ones
+	expectRun(t, `
+	defaults := immutable({host: "localhost", port: 80, debug: false})
+	overrides := {port: 8080}
+	out = {...defaults, ...overrides, debug: true}
+	`, nil, MAP{"host": "localhost", "port": 8080, "debug": true})
+
+	expectRun(t, `
+	m := {a: 1}
+	out = {a: 0, b: 2, ...m}
+	`, nil, MAP{"a": 1, "b": 2})
+
+	// elements are evaluated in order
+	expectRun(t, `
+	log := []
+	f := func(x) { log = append(log, x); return [x] }
+	g := [f(1), ...f(2), f(3)]
+	out = log
+	`, nil, ARR{1, 2, 3})
+
+	expectError(t, `x := [...1]`, nil, "Runtime Error: not an array: int")
+	expectError(t, `x := [1, ...{}]`, nil, "Runtime Error: not an array: map")
+	expectError(t, `x := {...[]}`, nil, "Runtime Error: not a map: array")
+	expectError(t, `x := {a: 1, ...undefined}`, nil,
+		"Runtime Error: not a map: undefined")
+}
+
+func TestBigInt(t *testing.T) {
+	bigInt := func(s string) *tengo.BigInt {
+		v, _ := new(big.Int).SetString(s, 0)
+		return &tengo.BigInt{Value: v}
+	}
+
+	expectRun(t, `out = bigint(9223372036854775807) + 1`,
+		nil, bigInt("9223372036854775808"))
+	expectRun(t, `out = 2 * bigint("123456789012345678901234567890")`,
+		nil, bigInt("246913578024691357802469135780"))
+	expectRun(t, `out = bigint("0xff") - 5`, nil, bigInt("250"))
+	expectRun(t, `out = -bigint(5)`, nil, bigInt("-5"))
+	expectRun(t, `out = ^bigint(0)`, nil, bigInt("-1"))
+	expectRun(t, `x := bigint(10); x += 5; x *= 2; out = x`,
+		nil, bigInt("30"))
+	expectRun(t, `out = bigint(1) << 100 > 9223372036854775807`, nil, true)
+	expectRun(t, `out = [bigint(3) == 3, 3 == bigint(3), bigint(3) != 4]`,
+		nil, ARR{true, true, true})
+	expectRun(t, `out = [bigint(3.9), bigint('a'), bigint(true)]`,
+		nil, ARR{bigInt("3"), bigInt("97"), bigInt("1")})
+	expectRun(t, `out = [bigint("x"), bigint("x", 0), bigint([])]`,
+		nil, ARR{tengo.UndefinedValue, 0, tengo.UndefinedValue})
+	expectRun(t, `out = [int(bigint(5)), int(bigint(1) << 64), float(bigint(2))]`,
+		nil, ARR{5, tengo.UndefinedValue, 2.0})
+	expectRun(t, `out = [is_bigint(bigint(1)), is_bigint(1), type_name(bigint(1))]`,
+		nil, ARR{true, false, "bigint"})
+	expectRun(t, `out = string(bigint(1) << 64)`, nil, "18446744073709551616")
+	expectRun(t, `out = format("%d %x %05d", bigint(1) << 64, bigint(255), bigint(42))`,
+		nil, "18446744073709551616 ff 00042")
+	expectRun(t, `out = bigint(0) ? "t" : "f"`, nil, "f")
+
+	// float operands make the result a float in both operand orders
+	expectRun(t, `out = [bigint(2) + 1.5, 1.5 + bigint(2), 1.5 * bigint(2), 3.0 / bigint(2), 2.0 ** bigint(3)]`,
+		nil, ARR{3.5, 3.5, 3.0, 1.5, 8.0})
+	expectRun(t, `out = [1.5 < bigint(2), bigint(2) < 1.5, 2.5 >= bigint(2), bigint(2) <= 2.0]`,
+		nil, ARR{true, false, true, true})
+
+	expectError(t, `x := bigint(1) / 0`, nil, "Runtime Error: division by zero")
+
+	// size limit
+	expectError(t, `x := bigint(1) << 100000000`, nil,
+		"exceeding bigint size limit")
+	curMaxBigIntBits := tengo.MaxBigIntBits
+	tengo.MaxBigIntBits = 64
+	expectRun(t, `out = string(bigint(1) << 63)`, nil, "9223372036854775808")
+	expectError(t, `x := bigint(1) << 64`, nil, "exceeding bigint size limit")
+	expectError(t, `x := bigint(1) << 63; x *= 2`, nil,
+		"exceeding bigint size limit")
+	expectError(t, `x := bigint("0x10000000000000000")`, nil,
+		"exceeding bigint size limit")
+	tengo.MaxBigIntBits = curMaxBigIntBits
+	expectError(t, `x := bigint(1) + "a"`, nil,
+		"Runtime Error: invalid operation: bigint + string")
+
+	// Go conversion
+	v := bigInt("123456789012345678901234567890")
+	require.Equal(t, "123456789012345678901234567890",
+		tengo.ToInterface(v).(*big.Int).String())
+	o, err := tengo.FromInterface(v.Value)
+	require.NoError(t, err)
+	require.Equal(t, v, o)
+}
+
+func TestDecimal(t *testing.T) {
+	expectRun(t, `out = string(decimal("0.1") + decimal("0.2"))`, nil, "0.3")
+	expectRun(t, `out = decimal("0.1") + decimal("0.2") == decimal("0.3")`,
+		nil, true)
+	expectRun(t, `out = string(decimal("12.30") * 2)`, nil, "24.60")
+	expectRun(t, `out = string(decimal(10) / 4)`, nil, "2.5")
+	expectRun(t, `out = string(-decimal("1.50"))`, nil, "-1.50")
+	expectRun(t, `x := decimal("19.99"); x += 0.01; out = string(x)`,
+		nil, "20.00")
+	expectRun(t, `out = [decimal("1.5") > 1, 2 < decimal("2.01"), decimal(3) == 3]`,
+		nil, ARR{true, true, true})
+	expectRun(t, `out = [string(decimal(0.1)), string(decimal(bigint(7))), string(decimal("1e2"))]`,
+		nil, ARR{"0.1", "7", "100"})
+	expectRun(t, `out = [decimal("x"), decimal("x", 0), decimal([])]`,
+		nil, ARR{tengo.UndefinedValue, 0, tengo.UndefinedValue})
+	expectRun(t, `out = [int(decimal("-7.9")), float(decimal("2.5")), string(bigint(decimal("12.5")))]`,
+		nil, ARR{-7, 2.5, "12"})
+	expectRun(t, `out = [is_decimal(decimal(1)), is_decimal(1.0), type_name(decimal(1))]`,
+		nil, ARR{true, false, "decimal"})
+	expectRun(t, `out = format("%v %.1f %8.3f %+f %-6.1f| %e %s", decimal("1.20"), decimal("2.25"), decimal("3.14159"), decimal("1"), decimal("0.05"), decimal("1234.5"), decimal("0.10"))`,
+		nil, "1.20 2.2    3.142 +1 0.0   | 1.234500e+03 0.10")
+	expectRun(t, `out = format("%08.2f %x", decimal("-3.14159"), decimal("1"))`,
+		nil, "-0003.14 %!x(1=1)")
+	expectRun(t, `out = decimal("0.00") ? "t" : "f"`, nil, "f")
+
+	expectError(t, `x := decimal(1) / 0`, nil, "Runtime Error: division by zero")
+
+	// size limit
+	expectError(t, `x := decimal("1e200000000") + decimal("1")`, nil,
+		"exceeding decimal size limit")
+	expectError(t, `x := decimal("1e-200000000")`, nil,
+		"exceeding decimal size limit")
+	curMaxDecimalDigits := tengo.MaxDecimalDigits
+	tengo.MaxDecimalDigits = 20
+	expectRun(t, `out = string(decimal("1e19") + decimal("0.5"))`, nil,
+		"10000000000000000000.5")
+	expectError(t, `x := decimal("1e10"); x *= x; x *= x`, nil,
+		"exceeding decimal size limit")
+	expectError(t, `x := decimal("0.0000000001"); x *= x; x *= x`, nil,
+		"exceeding decimal size limit")
+	expectError(t, `x := decimal(bigint(1) << 100)`, nil,
+		"exceeding decimal size limit")
+	expectRun(t, `out = format("%.30f", decimal("1.5"))`, nil,
+		"1.50000000000000000000")
+	tengo.MaxDecimalDigits = curMaxDecimalDigits
+	expectError(t, `x := decimal(1) + "a"`, nil,
+		"Runtime Error: invalid operation: decimal + string")
+
+	// equality is consistent with ordering against floats
+	expectRun(t, `out = decimal("1.5") == 1.5`, nil, true)
+	expectRun(t, `out = 1.5 == decimal("1.5")`, nil, true)
+	expectRun(t, `out = decimal("1.5") != 1.6`, nil, true)
+	expectRun(t, `out = decimal("0.1") == 0.1`, nil, true)
+
+	expectRun(t, `out = string(round(decimal("2.345"), 2, "half_up"))`, nil,
+		"2.35")
+	expectRun(t, `out = string(round(decimal("2.345"), 2))`, nil, "2.34")
+	expectRun(t, `out = string(round(2.5))`, nil, "2")
+	expectRun(t, `out = string(round(-2.5, 0, "floor"))`, nil, "-3")
+	expectError(t, `round(decimal("1.5"), 0, "sideways")`, nil,
+		"unknown rounding mode: sideways")
+	expectError(t, `round("1.5")`, nil, "invalid type for argument 'first'")
+}
+
+func TestSet(t *testing.T) {
+	expectRun(t, `out = string(set(1, 2, 2, bigint(1), "a"))`, nil,
+		`set("a", 1, 2)`)
+	expectRun(t, `out = len(set([1, 2, 3, 3]...))`, nil, 3)
+	expectRun(t, `a := set(1, 2); b := set(2, 3); out = [string(a | b), string(a & b), string(a - b), string(a ^ b)]`,
+		nil, ARR{"set(1, 2, 3)", "set(2)", "set(1)", "set(1, 3)"})
+	expectRun(t, `a := set(1); a |= set(2); a -= set(1); out = string(a)`,
+		nil, "set(2)")
+	expectRun(t, `s := set("x"); out = [s["x"], s["y"], s[[1]]]`,
+		nil, ARR{true, false, false})
+	expectRun(t, `s := set(); s[1] = true; s[2] = 1; s[2] = false; delete(s, 3); out = string(s)`,
+		nil, "set(1)")
+	expectRun(t, `s := set(1, 2, 3); delete(s, 2); out = len(s)`, nil, 2)
+	expectRun(t, `out = 0; for x in set(1, 2, 3) { out += x }`, nil, 6)
+	expectRun(t, `out = len([...set(1, 2), 3])`, nil, 3)
+	expectRun(t, `a := set(1, 2); b := copy(a); b[3] = true; out = [len(a), len(b)]`,
+		nil, ARR{2, 3})
+	expectRun(t, `out = [set(1, 2) == set(2, 1), set(1) == set(2), set() ? 1 : 0, set(1) ? 1 : 0]`,
+		nil, ARR{true, false, 0, 1})
+	expectRun(t, `i := immutable(set(1)); out = [is_immutable_set(i), is_set(i), type_name(i), string(i | set(2)), is_set(copy(i))]`,
+		nil, ARR{true, false, "immutable-set", "set(1, 2)", true})
+
+	expectError(t, `s := set([1])`, nil, "Runtime Error: not hashable: array")
+	expectError(t, `s := set(); s[{}] = true`, nil,
+		"Runtime Error: not hashable: map")
+	expectError(t, `s := immutable(set(1)); s[2] = true`, nil,
+		"Runtime Error: not index-assignable: immutable-set")
+	expectError(t, `s := set(1) + set(2)`, nil,
+		"Runtime Error: invalid operation: set + set")
+
+	// Go conversion
+	o, err := tengo.FromInterface(map[string]struct{}{"a": {}, "b": {}})
+	require.NoError(t, err)
+	require.Equal(t, `set("a", "b")`, o.String())
+	o, err = tengo.FromInterface(map[any]struct{}{int64(1): {}, "1": {}})
+	require.NoError(t, err)
+	goSet := tengo.ToInterface(o).(map[any]struct{})
+	require.Equal(t, 2, len(goSet))
+	_, ok := goSet[int64(1)]
+	require.True(t, ok)
+	_, ok = goSet["1"]
+	require.True(t, ok)
+	s, err := tengo.NewSet(&tengo.Bytes{Value: []byte("b")})
+	require.NoError(t, err)
+	goSet = tengo.ToInterface(&tengo.ImmutableSet{Value: s.Value}).(map[any]struct{})
+	_, ok = goSet["b"]
+	require.True(t, ok)
+}
+
+func TestIn(t *testing.T) {
+	expectRun(t, `out = [2 in [1, 2], 3 in [1, 2], 3 not in [1, 2]]`,
+		nil, ARR{true, false, true})
+	expectRun(t, `out = [bigint(2) in immutable([1, 2]), "a" in [[1], "a"], [1] in [[1]]]`,
+		nil, ARR{true, true, true})
+	expectRun(t, `m := {a: 1}; out = ["a" in m, "b" in m, "b" not in immutable(m), 1 in {"1": 0}]`,
+		nil, ARR{true, false, true, true})
+	expectRun(t, `out = ["ell" in "hello", 'h' in "hello", "x" not in "hello", "" in ""]`,
+		nil, ARR{true, true, true, true})
+	expectRun(t, `out = [bytes("b") in bytes("abc"), 99 in bytes("abc"), 300 in bytes("abc")]`,
+		nil, ARR{true, true, false})
+	expectRun(t, `s := set(1, "x"); out = ["x" in s, 2 in s, 2 not in immutable(s)]`,
+		nil, ARR{true, false, true})
+	expectRun(t, `out = 1 + 1 in [2] && "a" not in "b"`, nil, true)
+	expectRun(t, `out = 0; for x in [1, 2, 3] { if x in [1, 3] { out += x } }`,
+		nil, 4)
+	expectRun(t, `out = 1; for (out in [1]) { out = 5; break }`, nil, 5)
+	expectRun(t, `not := 1; out = not in [1]`, nil, true)
+	expectRun(t, `out = "b" in x`,
+		Opts().Symbol("x", &StringSet{Value: map[string]bool{"b": true}}).
+			SkipSecondPass(), true)
+
+	expectError(t, `1 in 2`, nil, "Runtime Error: invalid operation: int in int")
+	expectError(t, `1 in "abc"`, nil,
+		"Runtime Error: invalid operation: int in string")
+	expectError(t, `"a" in bytes("a")`, nil,
+		"Runtime Error: invalid operation: string in bytes")
+	expectError(t, `1 in x`,
+		Opts().Symbol("x", &StringSet{Value: map[string]bool{}}).
+			SkipSecondPass(), "Runtime Error: invalid operation: int in string-set")
+}
+
+type StringSet struct {
+	tengo.ObjectImpl
+	Value map[string]bool
+}
+
+func (o *StringSet) TypeName() string {
+	return "string-set"
+}
+
+func (o *StringSet) String() string {
+	return ""
+}
+
+func (o *StringSet) Contains(x tengo.Object) (bool, error) {
+	s, ok := x.(*tengo.String)
+	if !ok {
+		return false, tengo.ErrInvalidOperator
+	}
+	return o.Value[s.Value], nil
+}
+
+func BenchmarkFib(b *testing.B) {
+	benchmarkRun(b, `
+	fib := func(x) {
+		if x < 2 { return x }
+		return fib(x-1) + fib(x-2)
+	}
+	fib(20)`)
+}
+
+func BenchmarkLoop(b *testing.B) {
+	benchmarkRun(b, `
+	sum := 0
+	for i := 0; i < 100000; i++ { sum += i * 2 - 1 }`)
+}
+
+func BenchmarkFloatDiv(b *testing.B) {
+	benchmarkRun(b, `
+	x := 1.0
+	for i := 1; i < 100000; i++ { x = x / 1.0001 }`)
+}
+
+func benchmarkRun(b *testing.B, input string) {
+	fileSet := parser.NewFileSet()
+	file := fileSet.AddFile("bench", -1, len(input))
+	p := parser.NewParser(file, []byte(input), nil)
+	parsed, err := p.ParseFile()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	c := tengo.NewCompiler(file, nil, nil, nil, nil)
+	if err := c.Compile(parsed); err != nil {
+		b.Fatal(err)
+	}
+	bytecode := c.Bytecode()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := tengo.NewVM(bytecode, nil, -1)
+		if err := v.Run(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package tengo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	objectType  = reflect.TypeOf((*Object)(nil)).Elem()
+)
+
+// objectTypeNames are the type names of the objects of the package, by
+// their Go types.
+var objectTypeNames = map[reflect.Type]string{
+	reflect.TypeOf((*Array)(nil)):            "array",
+	reflect.TypeOf((*BigInt)(nil)):           "bigint",
+	reflect.TypeOf((*Bool)(nil)):             "bool",
+	reflect.TypeOf((*BuiltinFunction)(nil)):  "builtin-function",
+	reflect.TypeOf((*Bytes)(nil)):            "bytes",
+	reflect.TypeOf((*Char)(nil)):             "char",
+	reflect.TypeOf((*CompiledFunction)(nil)): "compiled-function",
+	reflect.TypeOf((*Decimal)(nil)):          "decimal",
+	reflect.TypeOf((*Error)(nil)):            "error",
+	reflect.TypeOf((*Float)(nil)):            "float",
+	reflect.TypeOf((*Generator)(nil)):        "generator",
+	reflect.TypeOf((*ImmutableArray)(nil)):   "immutable-array",
+	reflect.TypeOf((*ImmutableMap)(nil)):     "immutable-map",
+	reflect.TypeOf((*ImmutableSet)(nil)):     "immutable-set",
+	reflect.TypeOf((*Int)(nil)):              "int",
+	reflect.TypeOf((*Map)(nil)):              "map",
+	reflect.TypeOf((*Set)(nil)):              "set",
+	reflect.TypeOf((*String)(nil)):           "string",
+	reflect.TypeOf((*Undefined)(nil)):        "undefined",
+	reflect.TypeOf((*UserFunction)(nil)):     "user-function",
+}
+
+// WrapFunc returns a UserFunction that calls a Go function fn, so that any
+// Go function can be added to a script without writing an adapter for its
+// signature.
+//
+// The arguments are converted to the parameter types of fn as ToValue does;
+// an argument that cannot be converted is an ErrInvalidArgumentType error
+// that names it ("first", "second", ...). A variadic fn accepts any number
+// of trailing arguments. If the first parameter of fn is a context.Context,
+// fn receives the context the VM runs with (see Script.CompileRunContext),
+// or context.Background() if the function is called outside of a VM.
+//
+// fn can return nothing, a single value, or a value and an error. Values
+// are converted as FromValue does, and nothing converts to undefined. Like
+// the functions of the standard library, fn reports errors to the script as
+// error values rather than runtime errors: a non-nil error is returned as an
+// error object, and a nil error alone as true.
+//
+// WrapFunc returns an error if fn is not a function or returns values that
+// it cannot convert.
+func WrapFunc(fn any) (*UserFunction, error) {
+	return wrapFunc(fn, fromValue)
+}
+
+// wrapFunc returns a UserFunction that calls fn and converts its result
+// with convert.
+func wrapFunc(
+	fn any,
+	convert func(reflect.Value) (Object, error),
+) (*UserFunction, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("cannot wrap %T: not a function", fn)
+	}
+	t := v.Type()
+	switch {
+	case t.NumOut() > 2:
+		return nil, fmt.Errorf("cannot wrap %s: too many results", t)
+	case t.NumOut() == 2 && t.Out(1) != errorType:
+		return nil, fmt.Errorf("cannot wrap %s: second result is not error", t)
+	}
+
+	w := &funcWrapper{fn: v, convert: convert}
+	for i := 0; i < t.NumIn(); i++ {
+		if i == 0 && t.In(i) == contextType {
+			w.withContext = true
+			continue
+		}
+		w.params = append(w.params, t.In(i))
+	}
+	if t.IsVariadic() {
+		w.variadic = w.params[len(w.params)-1].Elem()
+		w.params = w.params[:len(w.params)-1]
+	}
+
+	res := &UserFunction{
+		Value: func(args ...Object) (Object, error) {
+			return w.call(context.Background(), args)
+		},
+	}
+	if w.withContext {
+		res.ContextValue = func(
+			ctx context.Context,
+			args ...Object,
+		) (Object, error) {
+			return w.call(ctx, args)
+		}
+	}
+	return res, nil
+}
+
+// funcWrapper calls a Go function wrapped by WrapFunc.
+type funcWrapper struct {
+	fn          reflect.Value
+	withContext bool
+	params      []reflect.Type
+	variadic    reflect.Type // element type of the variadic parameter, if any
+	convert     func(reflect.Value) (Object, error)
+}
+
+func (w *funcWrapper) call(ctx context.Context, args []Object) (Object, error) {
+	if len(args) < len(w.params) ||
+		w.variadic == nil && len(args) > len(w.params) {
+		return nil, ErrWrongNumArguments
+	}
+
+	in := make([]reflect.Value, 0, len(args)+1)
+	if w.withContext {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		in = append(in, reflect.ValueOf(&ctx).Elem())
+	}
+	for i, arg := range args {
+		t := w.variadic
+		if i < len(w.params) {
+			t = w.params[i]
+		}
+		v := reflect.New(t).Elem()
+		if err := toValue(arg, v); err != nil {
+			return nil, argumentError(i, arg, t, err)
+		}
+		in = append(in, v)
+	}
+
+	out := w.fn.Call(in)
+	if n := len(out); n > 0 && out[n-1].Type() == errorType {
+		if !out[n-1].IsNil() {
+			return errorObject(out[n-1].Interface().(error)), nil
+		}
+		if n == 1 {
+			return TrueValue, nil
+		}
+		out = out[:n-1]
+	}
+	if len(out) == 0 {
+		return UndefinedValue, nil
+	}
+	return w.convert(out[0])
+}
+
+// Func0 returns a CallableFunc that calls fn. Unlike WrapFunc, the generic
+// FuncN and FuncNE helpers check the signature of fn at compile time and
+// convert the result without reflection on the function.
+func Func0[R any](fn func() R) CallableFunc {
+	return func(args ...Object) (Object, error) {
+		if len(args) != 0 {
+			return nil, ErrWrongNumArguments
+		}
+		return funcResult(fn(), nil)
+	}
+}
+
+// Func0E returns a CallableFunc that calls fn. A non-nil error is returned
+// as an error object.
+func Func0E[R any](fn func() (R, error)) CallableFunc {
+	return func(args ...Object) (Object, error) {
+		if len(args) != 0 {
+			return nil, ErrWrongNumArguments
+		}
+		return funcResult(fn())
+	}
+}
+
+// Func1 returns a CallableFunc that calls fn with its argument converted
+// to type A.
+func Func1[A, R any](fn func(A) R) CallableFunc {
+	return func(args ...Object) (Object, error) {
+		if len(args) != 1 {
+			return nil, ErrWrongNumArguments
+		}
+		a, err := funcArg[A](args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return funcResult(fn(a), nil)
+	}
+}
+
+// Func1E returns a CallableFunc that calls fn with its argument converted
+// to type A. A non-nil error is returned as an error object.
+func Func1E[A, R any](fn func(A) (R, error)) CallableFunc {
+	return func(args ...Object) (Object, error) {
+		if len(args) != 1 {
+			return nil, ErrWrongNumArguments
+		}
+		a, err := funcArg[A](args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return funcResult(fn(a))
+	}
+}
+
+// Func2 returns a CallableFunc that calls fn with its arguments converted
+// to types A and B.
+func Func2[A, B, R any](fn func(A, B) R) CallableFunc {
+	return func(args ...Object) (Object, error) {
+		if len(args) != 2 {
+			return nil, ErrWrongNumArguments
+		}
+		a, err := funcArg[A](args, 0)
+		if err != nil {
+			return nil, err
+		}
+		b, err := funcArg[B](args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return funcResult(fn(a, b), nil)
+	}
+}
+
+// Func2E returns a CallableFunc that calls fn with its arguments converted
+// to types A and B. A non-nil error is returned as an error object.
+func Func2E[A, B, R any](fn func(A, B) (R, error)) CallableFunc {
+	return func(args ...Object) (Object, error) {
+		if len(args) != 2 {
+			return nil, ErrWrongNumArguments
+		}
+		a, err := funcArg[A](args, 0)
+		if err != nil {
+			return nil, err
+		}
+		b, err := funcArg[B](args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return funcResult(fn(a, b))
+	}
+}
+
+// Func3 returns a CallableFunc that calls fn with its arguments converted
+// to types A, B and C.
+func Func3[A, B, C, R any](fn func(A, B, C) R) CallableFunc {
+	return func(args ...Object) (Object, error) {
+		if len(args) != 3 {
+			return nil, ErrWrongNumArguments
+		}
+		a, err := funcArg[A](args, 0)
+		if err != nil {
+			return nil, err
+		}
+		b, err := funcArg[B](args, 1)
+		if err != nil {
+			return nil, err
+		}
+		c, err := funcArg[C](args, 2)
+		if err != nil {
+			return nil, err
+		}
+		return funcResult(fn(a, b, c), nil)
+	}
+}
+
+// Func3E returns a CallableFunc that calls fn with its arguments converted
+// to types A, B and C. A non-nil error is returned as an error object.
+func Func3E[A, B, C, R any](fn func(A, B, C) (R, error)) CallableFunc {
+	return func(args ...Object) (Object, error) {
+		if len(args) != 3 {
+			return nil, ErrWrongNumArguments
+		}
+		a, err := funcArg[A](args, 0)
+		if err != nil {
+			return nil, err
+		}
+		b, err := funcArg[B](args, 1)
+		if err != nil {
+			return nil, err
+		}
+		c, err := funcArg[C](args, 2)
+		if err != nil {
+			return nil, err
+		}
+		return funcResult(fn(a, b, c))
+	}
+}
+
+func funcArg[T any](args []Object, i int) (res T, err error) {
+	v := reflect.ValueOf(&res).Elem()
+	if err = toValue(args[i], v); err != nil {
+		err = argumentError(i, args[i], v.Type(), err)
+	}
+	return
+}
+
+func funcResult[R any](res R, err error) (Object, error) {
+	if err != nil {
+		return errorObject(err), nil
+	}
+	return fromValue(reflect.ValueOf(&res).Elem())
+}
+
+func errorObject(err error) Object {
+	return &Error{Value: &String{Value: err.Error()}}
+}
+
+// argumentError returns the error for an argument arg at index i that
+// cannot be converted to type t. If arg has the expected type but not the
+// expected value (e.g. an int that overflows t), the conversion error is
+// kept.
+func argumentError(i int, arg Object, t reflect.Type, err error) error {
+	name := ordinal(i + 1)
+	expected := objectTypeName(t)
+	if expected == arg.TypeName() {
+		return fmt.Errorf("invalid value for argument '%s': %w", name, err)
+	}
+	return ErrInvalidArgumentType{
+		Name:     name,
+		Expected: expected,
+		Found:    arg.TypeName(),
+	}
+}
+
+// objectTypeName returns the name of the object type that converts to a Go
+// type t.
+func objectTypeName(t reflect.Type) string {
+	switch t {
+	case bigIntType:
+		return "bigint"
+	case timeType:
+		return "string"
+	}
+	if t.Kind() == reflect.Pointer && t.Implements(objectType) {
+		// TypeName is not called on a zero value, which may not be usable
+		if name, ok := objectTypeNames[t]; ok {
+			return name
+		}
+		if name := t.Elem().Name(); name != "" {
+			return name
+		}
+		return "object"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes"
+		}
+		return "array"
+	case reflect.Map:
+		if t.Elem() == emptyType {
+			return "set"
+		}
+		return "map"
+	case reflect.Struct:
+		return "map"
+	case reflect.Pointer:
+		return objectTypeName(t.Elem())
+	}
+	return "object"
+}
+
+var ordinals = []string{
+	"first", "second", "third", "fourth", "fifth",
+	"sixth", "seventh", "eighth", "ninth", "tenth",
+}
+
+// ordinal returns the name of the n-th argument.
+func ordinal(n int) string {
+	if n <= len(ordinals) {
+		return ordinals[n-1]
+	}
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
@@ -0,0 +1,182 @@
+package tengo_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/shelepuginivan/tengo"
+	"github.com/shelepuginivan/tengo/require"
+)
+
+type wrapCtxKey struct{}
+
+// wrapPoint is an object whose zero value has no type name.
+type wrapPoint struct {
+	tengo.ObjectImpl
+	kind *string
+}
+
+func (p *wrapPoint) TypeName() string {
+	return *p.kind
+}
+
+func TestWrapFunc(t *testing.T) {
+	expectWrapFunc(t, strings.ToUpper, []any{"abc"}, "ABC")
+	expectWrapFunc(t, strings.Repeat, []any{"ab", 2}, "abab")
+	expectWrapFunc(t, func() {}, nil, tengo.UndefinedValue)
+	expectWrapFunc(t, func(a uint8, b float32) float64 {
+		return float64(a) + float64(b)
+	}, []any{2, 0.5}, 2.5)
+	expectWrapFunc(t, func(s []string) int { return len(s) },
+		[]any{ARR{"a", "b"}}, 2)
+	expectWrapFunc(t, func(m map[string]int) []int { return []int{m["a"]} },
+		[]any{MAP{"a": 3}}, ARR{3})
+	expectWrapFunc(t, func(p struct{ X, Y int }) int { return p.X * p.Y },
+		[]any{MAP{"X": 3, "Y": 4}}, 12)
+	expectWrapFunc(t, func(o tengo.Object) string { return o.TypeName() },
+		[]any{ARR{}}, "array")
+
+	// variadic
+	sum := func(base int, xs ...int) int {
+		for _, x := range xs {
+			base += x
+		}
+		return base
+	}
+	expectWrapFunc(t, sum, []any{1}, 1)
+	expectWrapFunc(t, sum, []any{1, 2, 3}, 6)
+
+	// errors are returned as error values
+	expectWrapFunc(t, strconv.Atoi, []any{"12"}, 12)
+	expectWrapFunc(t, strconv.Atoi, []any{"x"}, &tengo.Error{
+		Value: &tengo.String{
+			Value: `strconv.Atoi: parsing "x": invalid syntax`,
+		},
+	})
+	expectWrapFunc(t, func(fail bool) error {
+		if fail {
+			return errors.New("failed")
+		}
+		return nil
+	}, []any{false}, true)
+
+	// context is not an argument
+	expectWrapFunc(t, func(ctx context.Context, s string) string {
+		return s
+	}, []any{"a"}, "a")
+
+	f, err := tengo.WrapFunc(strings.Repeat)
+	require.NoError(t, err)
+	_, err = f.Call(&tengo.String{Value: "a"})
+	require.Equal(t, tengo.ErrWrongNumArguments, err)
+	_, err = f.Call(&tengo.String{Value: "a"}, &tengo.String{Value: "b"})
+	require.Equal(t, tengo.ErrInvalidArgumentType{
+		Name: "second", Expected: "int", Found: "string",
+	}, err)
+	f, err = tengo.WrapFunc(func(uint8) {})
+	require.NoError(t, err)
+	_, err = f.Call(&tengo.Int{Value: 300})
+	require.Equal(t,
+		"invalid value for argument 'first': value 300 overflows uint8",
+		err.Error())
+
+	_, err = tengo.WrapFunc(42)
+	require.Error(t, err)
+	_, err = tengo.WrapFunc(func() (int, int) { return 0, 0 })
+	require.Error(t, err)
+	_, err = tengo.WrapFunc(func() (int, int, error) { return 0, 0, nil })
+	require.Error(t, err)
+}
+
+func TestWrapFunc_Context(t *testing.T) {
+	f, err := tengo.WrapFunc(func(ctx context.Context) string {
+		v, _ := ctx.Value(wrapCtxKey{}).(string)
+		return v
+	})
+	require.NoError(t, err)
+
+	s := tengo.NewScript([]byte(`out := f()`))
+	require.NoError(t, s.Add("f", f))
+	ctx := context.WithValue(context.Background(), wrapCtxKey{}, "value")
+	c, err := s.CompileRunContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "value", c.Get("out").String())
+
+	// deferred calls receive the context as well
+	s = tengo.NewScript([]byte(`
+out := ""
+func() { defer func() { out = f() }() }()`))
+	require.NoError(t, s.Add("f", f))
+	c, err = s.CompileRunContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "value", c.Get("out").String())
+
+	res, err := f.Call()
+	require.NoError(t, err)
+	require.Equal(t, &tengo.String{Value: ""}, res)
+}
+
+func TestFuncN(t *testing.T) {
+	expectCallable(t, tengo.Func0(func() int { return 1 }), nil, 1)
+	expectCallable(t, tengo.Func0E(func() (string, error) {
+		return "", errors.New("e")
+	}), nil, &tengo.Error{Value: &tengo.String{Value: "e"}})
+	expectCallable(t, tengo.Func1(strings.ToLower), []any{"AB"}, "ab")
+	expectCallable(t, tengo.Func1E(strconv.Atoi), []any{"7"}, 7)
+	expectCallable(t, tengo.Func2(strings.Repeat), []any{"a", 3}, "aaa")
+	expectCallable(t, tengo.Func2E(func(a, b int) (int, error) {
+		if b == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return a / b, nil
+	}), []any{7, 2}, 3)
+	expectCallable(t, tengo.Func2(strings.Repeat),
+		[]any{"a"}, tengo.ErrWrongNumArguments)
+	expectCallable(t, tengo.Func3(strings.ReplaceAll),
+		[]any{"aba", "a", "c"}, "cbc")
+	expectCallable(t, tengo.Func3E(strconv.ParseInt),
+		[]any{"ff", 16, 64}, 255)
+	expectCallable(t, tengo.Func1(func(xs []int32) int32 { return xs[0] }),
+		[]any{"a"}, tengo.ErrInvalidArgumentType{
+			Name: "first", Expected: "array", Found: "string",
+		})
+
+	// the expected type of an object parameter is named after its Go type
+	expectCallable(t, tengo.Func1(func(p *wrapPoint) string { return "" }),
+		[]any{"a"}, tengo.ErrInvalidArgumentType{
+			Name: "first", Expected: "wrapPoint", Found: "string",
+		})
+	expectCallable(t,
+		tengo.Func1(func(f *tengo.BuiltinFunction) string { return f.Name }),
+		[]any{1}, tengo.ErrInvalidArgumentType{
+			Name: "first", Expected: "builtin-function", Found: "int",
+		})
+}
+
+func expectWrapFunc(t *testing.T, fn any, args []any, expected any) {
+	f, err := tengo.WrapFunc(fn)
+	require.NoError(t, err)
+	expectCallable(t, f.Value, args, expected)
+}
+
+func expectCallable(
+	t *testing.T,
+	fn tengo.CallableFunc,
+	args []any,
+	expected any,
+) {
+	var objs []tengo.Object
+	for _, arg := range args {
+		objs = append(objs, toObject(arg))
+	}
+	res, err := fn(objs...)
+	if expectedErr, ok := expected.(error); ok {
+		require.Equal(t, expectedErr, err)
+		return
+	}
+	require.NoError(t, err)
+	require.Equal(t, toObject(expected), res)
+}